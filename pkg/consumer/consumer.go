@@ -0,0 +1,329 @@
+// Package consumer processes the events delivered to a service's worker. Each
+// worker has an SQS queue subscribed to the events topic, the queue invokes the
+// worker's Lambda with batches of SNS notifications.
+//
+// A worker registers a handler for every event type its subscription lets
+// through and starts the consumer as its Lambda handler
+//
+//	c := consumer.New(logger, config.EventRetryCount)
+//	c.Handle(events.TypeEndpointResolved, finalizeRegistration(config, logger, dynamoClient, publisher),
+//		consumer.OnExhausted(consumer.PublishFailed(publisher, events.TypeAgencyRegistrationCreateFailed)))
+//	lambda.Start(c.Process)
+//
+// Records that fail are reported back to SQS as batch item failures so only
+// they are redelivered. The queue's event source mapping must enable
+// ReportBatchItemFailures.
+//
+// SQS delivers at least once. A consumer given a Ledger with UseLedger records
+// each event it handles, with the result its handler set, and replays that
+// result for the copies delivered after it rather than handling them again.
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strconv"
+
+	lambdaevents "github.com/aws/aws-lambda-go/events"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+)
+
+// Handler handles one event. Returning an error fails the record so SQS
+// redelivers it.
+type Handler func(ctx context.Context, envelope events.Envelope) error
+
+// ExhaustedFunc is called when a handler fails on the last delivery of an
+// event, before the queue moves it to its dead letter queue. err is the
+// handler's error.
+type ExhaustedFunc func(ctx context.Context, envelope events.Envelope, err error) error
+
+// Option configures the handling of an event type.
+type Option func(*route)
+
+// OnExhausted sets the function called when the handler fails on the last
+// delivery of an event.
+func OnExhausted(fn ExhaustedFunc) Option {
+	return func(r *route) {
+		r.onExhausted = fn
+	}
+}
+
+// ReplayFunc is called instead of the handler when an event the ledger
+// recorded as handled is delivered again. entry holds the result the handler
+// recorded the first time, see SetResult.
+type ReplayFunc func(ctx context.Context, envelope events.Envelope, entry Entry) error
+
+// OnReplay sets the function that replays the recorded result of an event
+// delivered again after it was handled. Without one the delivery succeeds, as
+// the first one did, without calling the handler.
+func OnReplay(fn ReplayFunc) Option {
+	return func(r *route) {
+		r.onReplay = fn
+	}
+}
+
+// Publisher publishes events, it is satisfied by *events.Publisher.
+type Publisher interface {
+	Publish(ctx context.Context, eventType events.Type, payload any) error
+}
+
+// PublishFailed returns an ExhaustedFunc that publishes the failed event's
+// payload as an event of eventType, letting the service that published the
+// event react to the failure.
+func PublishFailed(publisher Publisher, eventType events.Type) ExhaustedFunc {
+	return func(ctx context.Context, envelope events.Envelope, err error) error {
+		return publisher.Publish(ctx, eventType, envelope.Payload)
+	}
+}
+
+type route struct {
+	handler     Handler
+	onExhausted ExhaustedFunc
+	onReplay    ReplayFunc
+}
+
+type contextKey string
+
+// contextKeyResult is the key used to store the result of the event being
+// handled in a context.
+const contextKeyResult contextKey = "result"
+
+// result holds the result a handler records with SetResult.
+type result struct {
+	value json.RawMessage
+}
+
+// SetResult records the result of handling the current event, replacing any
+// result set before. It is marshalled to JSON and, when the consumer has a
+// ledger, stored with the event and passed to the route's ReplayFunc if the
+// event is delivered again.
+func SetResult(ctx context.Context, v any) error {
+	r, ok := ctx.Value(contextKeyResult).(*result)
+	if !ok {
+		return errors.New("no event is being handled")
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	r.value = value
+	return nil
+}
+
+// Consumer dispatches the records of an SQS batch to the handler registered
+// for their event type.
+type Consumer struct {
+	logger      *slog.Logger
+	maxReceives int
+	routes      map[events.Type]route
+	ledger      Ledger
+}
+
+// New returns a consumer with no handlers. maxReceives is the queue's
+// maxReceiveCount, an event that fails on its maxReceives-th delivery is
+// exhausted.
+func New(logger *slog.Logger, maxReceives int) *Consumer {
+	return &Consumer{
+		logger:      logger,
+		maxReceives: maxReceives,
+		routes:      make(map[events.Type]route),
+	}
+}
+
+// Handle registers the handler for an event type, replacing any handler
+// registered before.
+func (c *Consumer) Handle(eventType events.Type, handler Handler, opts ...Option) {
+	r := route{handler: handler}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	c.routes[eventType] = r
+}
+
+// UseLedger makes the consumer handle each event at most once. Events the
+// ledger has recorded as processed aren't handled again, their recorded
+// result is replayed instead, see OnReplay.
+func (c *Consumer) UseLedger(ledger Ledger) {
+	c.ledger = ledger
+}
+
+// Process handles every record in the batch, returning the records that failed.
+// It never returns an error, so one bad record doesn't fail the whole batch.
+func (c *Consumer) Process(ctx context.Context, event lambdaevents.SQSEvent) (lambdaevents.SQSEventResponse, error) {
+	var batchItemFailures []lambdaevents.SQSBatchItemFailure
+	for _, record := range event.Records {
+		if err := c.processRecord(ctx, record); err != nil {
+			c.logger.ErrorContext(
+				ctx,
+				"failed to process record",
+				slog.String("messageId", record.MessageId),
+				slog.Any("error", err))
+
+			batchItemFailures = append(batchItemFailures, lambdaevents.SQSBatchItemFailure{
+				ItemIdentifier: record.MessageId,
+			})
+		}
+	}
+
+	return lambdaevents.SQSEventResponse{
+		BatchItemFailures: batchItemFailures,
+	}, nil
+}
+
+// processRecord handles a single record. A panic while handling the record
+// fails it rather than the batch.
+func (c *Consumer) processRecord(ctx context.Context, record lambdaevents.SQSMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic processing record: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	var snsRecord lambdaevents.SNSEntity
+	if err := json.Unmarshal([]byte(record.Body), &snsRecord); err != nil {
+		return fmt.Errorf("failed to unmarshal record body: %w", err)
+	}
+
+	eventType, err := messageType(snsRecord)
+	if err != nil {
+		return err
+	}
+
+	route, ok := c.routes[eventType]
+	if !ok {
+		return fmt.Errorf("unknown event type: %s", eventType)
+	}
+
+	receiveCount, err := strconv.Atoi(record.Attributes["ApproximateReceiveCount"])
+	if err != nil {
+		return fmt.Errorf("failed to read receive count: %w", err)
+	}
+
+	envelope, err := events.Decode(snsRecord.Message, eventType)
+	if err != nil {
+		return err
+	}
+
+	ctx = events.WithCorrelationID(ctx, envelope.CorrelationID)
+
+	key := eventKey(envelope, snsRecord, record)
+	if c.ledger != nil {
+		entry, claimed, err := c.ledger.Claim(ctx, key, record.MessageId)
+		if err != nil {
+			return err
+		}
+
+		if !claimed {
+			if entry.Status != StatusSucceeded {
+				return fmt.Errorf("event %s is already being processed", key)
+			}
+
+			c.logger.InfoContext(
+				ctx,
+				"replaying processed event",
+				slog.String("messageId", record.MessageId),
+				slog.String("eventKey", key),
+				slog.String("processedMessageId", entry.MessageID))
+
+			if route.onReplay == nil {
+				return nil
+			}
+
+			if err := route.onReplay(ctx, envelope, entry); err != nil {
+				return fmt.Errorf("failed to replay %s event: %w", eventType, err)
+			}
+
+			return nil
+		}
+	}
+
+	res := new(result)
+	ctx = context.WithValue(ctx, contextKeyResult, res)
+
+	err = handle(ctx, route.handler, envelope)
+	if err == nil {
+		if c.ledger != nil {
+			// The event was handled, failing the record now would only handle it
+			// again. If the entry is never completed its lease expires and a
+			// redelivery is handled as if it were the first.
+			if completeErr := c.ledger.Complete(ctx, key, res.value); completeErr != nil {
+				c.logger.ErrorContext(
+					ctx,
+					"failed to record processed event",
+					slog.String("messageId", record.MessageId),
+					slog.Any("error", completeErr))
+			}
+		}
+		return nil
+	}
+
+	if c.ledger != nil {
+		if releaseErr := c.ledger.Release(ctx, key); releaseErr != nil {
+			c.logger.ErrorContext(
+				ctx,
+				"failed to release event",
+				slog.String("messageId", record.MessageId),
+				slog.Any("error", releaseErr))
+		}
+	}
+
+	if receiveCount >= c.maxReceives && route.onExhausted != nil {
+		if exhaustedErr := route.onExhausted(ctx, envelope, err); exhaustedErr != nil {
+			c.logger.ErrorContext(
+				ctx,
+				"failed to handle exhausted event",
+				slog.String("messageId", record.MessageId),
+				slog.Any("error", exhaustedErr))
+		}
+	}
+
+	return fmt.Errorf("failed to handle %s event: %w", eventType, err)
+}
+
+// handle calls the handler, returning a panic as an error so a handler that
+// always panics is still exhausted.
+func handle(ctx context.Context, handler Handler, envelope events.Envelope) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	return handler(ctx, envelope)
+}
+
+// messageType reads the event type from the notification's type message
+// attribute.
+func messageType(snsRecord lambdaevents.SNSEntity) (events.Type, error) {
+	attribute, ok := snsRecord.MessageAttributes["type"].(map[string]any)
+	if !ok {
+		return "", errors.New("message has no type attribute")
+	}
+
+	value, ok := attribute["Value"].(string)
+	if !ok || value == "" {
+		return "", errors.New("message type attribute has no value")
+	}
+
+	return events.Type(value), nil
+}
+
+// eventKey identifies the event in a record for the ledger. Enveloped events
+// carry their own ID. Bare events fall back to the SNS message ID, which is
+// shared by every copy SNS delivers, and then to the SQS message ID.
+func eventKey(envelope events.Envelope, snsRecord lambdaevents.SNSEntity, record lambdaevents.SQSMessage) string {
+	switch {
+	case envelope.ID != "":
+		return envelope.ID
+	case snsRecord.MessageID != "":
+		return snsRecord.MessageID
+	default:
+		return record.MessageId
+	}
+}
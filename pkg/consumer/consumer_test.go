@@ -0,0 +1,433 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+
+	lambdaevents "github.com/aws/aws-lambda-go/events"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePublisher records the events published to it.
+type fakePublisher struct {
+	types    []events.Type
+	payloads []any
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, eventType events.Type, payload any) error {
+	f.types = append(f.types, eventType)
+	f.payloads = append(f.payloads, payload)
+	return nil
+}
+
+func newTestConsumer() *Consumer {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), 3)
+}
+
+// newRecord builds an SQS record holding an SNS notification with the given
+// type attribute and message.
+func newRecord(t *testing.T, id string, eventType string, message string, receiveCount int) lambdaevents.SQSMessage {
+	t.Helper()
+
+	attributes := map[string]any{}
+	if eventType != "" {
+		attributes["type"] = map[string]any{
+			"Type":  "String",
+			"Value": eventType,
+		}
+	}
+
+	return newNotificationRecord(t, id, lambdaevents.SNSEntity{
+		Message:           message,
+		MessageAttributes: attributes,
+	}, receiveCount)
+}
+
+// newNotificationRecord builds an SQS record holding the SNS notification.
+func newNotificationRecord(t *testing.T, id string, notification lambdaevents.SNSEntity, receiveCount int) lambdaevents.SQSMessage {
+	t.Helper()
+
+	body, err := json.Marshal(notification)
+	require.NoError(t, err)
+
+	return lambdaevents.SQSMessage{
+		MessageId: id,
+		Body:      string(body),
+		Attributes: map[string]string{
+			"ApproximateReceiveCount": strconv.Itoa(receiveCount),
+		},
+	}
+}
+
+func failedIDs(response lambdaevents.SQSEventResponse) []string {
+	var ids []string
+	for _, failure := range response.BatchItemFailures {
+		ids = append(ids, failure.ItemIdentifier)
+	}
+	return ids
+}
+
+func TestProcess(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		consumer = newTestConsumer()
+		handled  []events.AgencyMembershipDeleted
+		corrIDs  []string
+	)
+
+	consumer.Handle(events.TypeAgencyMembershipDeleted, func(ctx context.Context, envelope events.Envelope) error {
+		payload, err := events.DecodePayload[events.AgencyMembershipDeleted](envelope)
+		if err != nil {
+			return err
+		}
+		handled = append(handled, payload)
+		correlationID, _ := events.CorrelationIDFrom(ctx)
+		corrIDs = append(corrIDs, correlationID)
+		return nil
+	})
+
+	response, err := consumer.Process(ctx, lambdaevents.SQSEvent{
+		Records: []lambdaevents.SQSMessage{
+			newRecord(t, "enveloped", "agency.membership.deleted", `{
+				"id": "event-1",
+				"type": "agency.membership.deleted",
+				"version": 1,
+				"correlationId": "correlation-1",
+				"payload": {"userId": "user-1", "agencyId": "agency-1"}
+			}`, 1),
+			newRecord(t, "bare", "agency.membership.deleted", `{"userId": "user-2", "agencyId": "agency-2"}`, 1),
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Empty(t, response.BatchItemFailures)
+	assert.Equal(t, []events.AgencyMembershipDeleted{
+		{UserID: "user-1", AgencyID: "agency-1"},
+		{UserID: "user-2", AgencyID: "agency-2"},
+	}, handled)
+	assert.Equal(t, []string{"correlation-1", ""}, corrIDs)
+}
+
+func TestProcessPartialFailure(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		consumer = newTestConsumer()
+	)
+
+	consumer.Handle(events.TypeAgencyMembershipDeleted, func(ctx context.Context, envelope events.Envelope) error {
+		payload, err := events.DecodePayload[events.AgencyMembershipDeleted](envelope)
+		if err != nil {
+			return err
+		}
+		if payload.UserID == "fail" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	consumer.Handle(events.TypeAgencyMembershipCreated, func(ctx context.Context, envelope events.Envelope) error {
+		panic("boom")
+	})
+
+	response, err := consumer.Process(ctx, lambdaevents.SQSEvent{
+		Records: []lambdaevents.SQSMessage{
+			newRecord(t, "ok", "agency.membership.deleted", `{"userId": "user-1"}`, 1),
+			newRecord(t, "handler-error", "agency.membership.deleted", `{"userId": "fail"}`, 1),
+			newRecord(t, "panic", "agency.membership.created", `{"userId": "user-1"}`, 1),
+			newRecord(t, "no-type", "", `{"userId": "user-1"}`, 1),
+			newRecord(t, "unknown-type", "agency.unknown", `{"userId": "user-1"}`, 1),
+			newRecord(t, "invalid-message", "agency.membership.deleted", `not json`, 1),
+			{MessageId: "invalid-body", Body: "not json"},
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"handler-error",
+		"panic",
+		"no-type",
+		"unknown-type",
+		"invalid-message",
+		"invalid-body",
+	}, failedIDs(response))
+}
+
+func TestProcessExhausted(t *testing.T) {
+	tests := []struct {
+		name         string
+		receiveCount int
+		handler      Handler
+		exhausted    bool
+	}{
+		{
+			name:         "first delivery",
+			receiveCount: 1,
+			handler: func(ctx context.Context, envelope events.Envelope) error {
+				return errors.New("failed")
+			},
+		},
+		{
+			name:         "last delivery",
+			receiveCount: 3,
+			handler: func(ctx context.Context, envelope events.Envelope) error {
+				return errors.New("failed")
+			},
+			exhausted: true,
+		},
+		{
+			name:         "last delivery panics",
+			receiveCount: 3,
+			handler: func(ctx context.Context, envelope events.Envelope) error {
+				panic("boom")
+			},
+			exhausted: true,
+		},
+		{
+			name:         "last delivery succeeds",
+			receiveCount: 3,
+			handler: func(ctx context.Context, envelope events.Envelope) error {
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				ctx       = context.Background()
+				consumer  = newTestConsumer()
+				publisher = new(fakePublisher)
+			)
+
+			consumer.Handle(
+				events.TypeEndpointResolved,
+				tt.handler,
+				OnExhausted(PublishFailed(publisher, events.TypeAgencyRegistrationCreateFailed)))
+
+			_, err := consumer.Process(ctx, lambdaevents.SQSEvent{
+				Records: []lambdaevents.SQSMessage{
+					newRecord(t, "record", "endpoint.resolved", `{"registrationCode": "code-1", "agencyId": "agency-1", "endpointId": "endpoint-1"}`, tt.receiveCount),
+				},
+			})
+			require.NoError(t, err)
+
+			if !tt.exhausted {
+				assert.Empty(t, publisher.types)
+				return
+			}
+
+			require.Equal(t, []events.Type{events.TypeAgencyRegistrationCreateFailed}, publisher.types)
+
+			payload, err := json.Marshal(publisher.payloads[0])
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"registrationCode": "code-1", "agencyId": "agency-1", "endpointId": "endpoint-1"}`, string(payload))
+		})
+	}
+}
+
+// memoryLedger is a Ledger held in memory.
+type memoryLedger struct {
+	entries map[string]Entry
+}
+
+func newMemoryLedger() *memoryLedger {
+	return &memoryLedger{entries: make(map[string]Entry)}
+}
+
+func (l *memoryLedger) Claim(ctx context.Context, key string, messageID string) (Entry, bool, error) {
+	if entry, ok := l.entries[key]; ok {
+		return entry, false, nil
+	}
+	l.entries[key] = Entry{Status: StatusProcessing, MessageID: messageID}
+	return Entry{}, true, nil
+}
+
+func (l *memoryLedger) Complete(ctx context.Context, key string, result json.RawMessage) error {
+	entry := l.entries[key]
+	entry.Status = StatusSucceeded
+	entry.Result = result
+	l.entries[key] = entry
+	return nil
+}
+
+func (l *memoryLedger) Release(ctx context.Context, key string) error {
+	if l.entries[key].Status == StatusProcessing {
+		delete(l.entries, key)
+	}
+	return nil
+}
+
+func TestProcessRedelivered(t *testing.T) {
+	envelope := func(id string, userID string) string {
+		return `{
+			"id": "` + id + `",
+			"type": "agency.membership.deleted",
+			"version": 1,
+			"payload": {"userId": "` + userID + `", "agencyId": "agency-1"}
+		}`
+	}
+
+	var (
+		ctx      = context.Background()
+		consumer = newTestConsumer()
+		ledger   = newMemoryLedger()
+		handled  []string
+		replayed []Entry
+	)
+
+	consumer.UseLedger(ledger)
+	consumer.Handle(
+		events.TypeAgencyMembershipDeleted,
+		func(ctx context.Context, envelope events.Envelope) error {
+			payload, err := events.DecodePayload[events.AgencyMembershipDeleted](envelope)
+			if err != nil {
+				return err
+			}
+			handled = append(handled, payload.UserID)
+			if payload.UserID == "fail" {
+				return errors.New("failed")
+			}
+			return SetResult(ctx, map[string]string{"deleted": payload.UserID})
+		},
+		OnReplay(func(ctx context.Context, envelope events.Envelope, entry Entry) error {
+			replayed = append(replayed, entry)
+			return nil
+		}))
+
+	bare := lambdaevents.SNSEntity{
+		MessageID: "notification-1",
+		Message:   `{"userId": "user-3", "agencyId": "agency-1"}`,
+		MessageAttributes: map[string]any{
+			"type": map[string]any{"Type": "String", "Value": "agency.membership.deleted"},
+		},
+	}
+
+	batch := lambdaevents.SQSEvent{
+		Records: []lambdaevents.SQSMessage{
+			newRecord(t, "message-1", "agency.membership.deleted", envelope("event-1", "user-1"), 1),
+			// SNS delivered event-1 to the queue twice.
+			newRecord(t, "message-2", "agency.membership.deleted", envelope("event-1", "user-1"), 1),
+			newRecord(t, "message-3", "agency.membership.deleted", envelope("event-2", "user-2"), 1),
+			newNotificationRecord(t, "message-4", bare, 1),
+			newRecord(t, "message-5", "agency.membership.deleted", envelope("event-3", "fail"), 1),
+		},
+	}
+
+	entry := func(messageID string, userID string) Entry {
+		return Entry{
+			Status:    StatusSucceeded,
+			MessageID: messageID,
+			Result:    json.RawMessage(`{"deleted":"` + userID + `"}`),
+		}
+	}
+
+	response, err := consumer.Process(ctx, batch)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"message-5"}, failedIDs(response))
+	assert.Equal(t, []string{"user-1", "user-2", "user-3", "fail"}, handled)
+
+	// The second copy of event-1 replays the result of the first.
+	assert.Equal(t, []Entry{entry("message-1", "user-1")}, replayed)
+
+	// The whole batch is redelivered. Only the failed event is handled again,
+	// the others replay the results recorded the first time.
+	handled, replayed = nil, nil
+	response, err = consumer.Process(ctx, batch)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"message-5"}, failedIDs(response))
+	assert.Equal(t, []string{"fail"}, handled)
+	assert.Equal(t, []Entry{
+		entry("message-1", "user-1"),
+		entry("message-1", "user-1"),
+		entry("message-3", "user-2"),
+		entry("message-4", "user-3"),
+	}, replayed)
+
+	assert.NotContains(t, ledger.entries, "event-3")
+}
+
+func TestProcessClaimed(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		consumer  = newTestConsumer()
+		ledger    = newMemoryLedger()
+		publisher = new(fakePublisher)
+		handled   bool
+	)
+
+	// Another invocation is handling the event.
+	ledger.entries["event-1"] = Entry{Status: StatusProcessing, MessageID: "message-1"}
+
+	consumer.UseLedger(ledger)
+	consumer.Handle(
+		events.TypeAgencyMembershipDeleted,
+		func(ctx context.Context, envelope events.Envelope) error {
+			handled = true
+			return nil
+		},
+		OnExhausted(PublishFailed(publisher, events.TypeUserMembershipDeleteFailed)))
+
+	response, err := consumer.Process(ctx, lambdaevents.SQSEvent{
+		Records: []lambdaevents.SQSMessage{
+			newRecord(t, "message-2", "agency.membership.deleted", `{
+				"id": "event-1",
+				"type": "agency.membership.deleted",
+				"version": 1,
+				"payload": {"userId": "user-1", "agencyId": "agency-1"}
+			}`, 3),
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"message-2"}, failedIDs(response))
+	assert.False(t, handled)
+	assert.Empty(t, publisher.types)
+}
+
+func TestProcessReplayFailure(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		consumer = newTestConsumer()
+		ledger   = newMemoryLedger()
+		handled  bool
+	)
+
+	ledger.entries["event-1"] = Entry{Status: StatusSucceeded, MessageID: "message-1"}
+
+	consumer.UseLedger(ledger)
+	consumer.Handle(
+		events.TypeAgencyMembershipDeleted,
+		func(ctx context.Context, envelope events.Envelope) error {
+			handled = true
+			return nil
+		},
+		OnReplay(func(ctx context.Context, envelope events.Envelope, entry Entry) error {
+			return errors.New("failed")
+		}))
+
+	response, err := consumer.Process(ctx, lambdaevents.SQSEvent{
+		Records: []lambdaevents.SQSMessage{
+			newRecord(t, "message-2", "agency.membership.deleted", `{
+				"id": "event-1",
+				"type": "agency.membership.deleted",
+				"version": 1,
+				"payload": {"userId": "user-1", "agencyId": "agency-1"}
+			}`, 1),
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"message-2"}, failedIDs(response))
+	assert.False(t, handled)
+}
+
+func TestSetResultOutsideHandler(t *testing.T) {
+	assert.Error(t, SetResult(context.Background(), "result"))
+}
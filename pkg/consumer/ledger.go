@@ -0,0 +1,232 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Status is the state of an event in a ledger.
+type Status string
+
+const (
+	// StatusProcessing means a consumer has claimed the event and is handling
+	// it.
+	StatusProcessing Status = "processing"
+	// StatusSucceeded means the event was handled successfully.
+	StatusSucceeded Status = "succeeded"
+)
+
+// Entry is a ledger's record of an event.
+type Entry struct {
+	Status Status
+	// MessageID is the SQS message the event was claimed from.
+	MessageID string
+	// Result is the result the handler recorded with SetResult, as JSON. It is
+	// empty if the handler didn't record one.
+	Result json.RawMessage
+	// Updated is when the entry last changed.
+	Updated time.Time
+}
+
+// Ledger records the events a consumer has processed so redelivered events are
+// not handled twice. SQS delivers at least once, and SNS can deliver the same
+// notification to a queue more than once.
+//
+// Only successes are recorded, with the handler's result. A failed event is
+// released so its redelivery, or a redrive from the dead letter queue, is
+// handled again.
+type Ledger interface {
+	// Claim records that the event identified by key is being processed. If the
+	// event was claimed before, Claim returns the recorded entry and false.
+	Claim(ctx context.Context, key string, messageID string) (Entry, bool, error)
+	// Complete records that the event was handled successfully, with the
+	// handler's result if it recorded one.
+	Complete(ctx context.Context, key string, result json.RawMessage) error
+	// Release removes the claim on the event so it can be handled again.
+	Release(ctx context.Context, key string) error
+}
+
+// DynamoDBAPI is the subset of the DynamoDB client used by DynamoLedger.
+type DynamoDBAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoLedger is a Ledger stored in a service's DynamoDB table. Each event is
+// an item keyed by event#<key>, written with a conditional put so concurrent
+// deliveries of the same event can't both claim it. Items expire through the
+// table's ttl attribute.
+type DynamoLedger struct {
+	client    DynamoDBAPI
+	tableName string
+	lease     time.Duration
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewDynamoLedger returns a ledger in the given table.
+//
+// lease is how long a claim holds an event. A consumer that crashes mid-event
+// leaves its claim behind, once the lease expires the event can be claimed
+// again. It must be at least the consumer's function timeout, or a slow but
+// live handler can lose its claim to a redelivery, and is best set to the
+// queue's visibility timeout so the redelivery of a crashed event can claim it.
+//
+// retention is how long a processed event is remembered, it should comfortably
+// exceed the queue's message retention period.
+func NewDynamoLedger(client DynamoDBAPI, tableName string, lease, retention time.Duration) *DynamoLedger {
+	return &DynamoLedger{
+		client:    client,
+		tableName: tableName,
+		lease:     lease,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+func (l *DynamoLedger) key(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("event#%s", key)},
+		"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("event#%s", key)},
+	}
+}
+
+// Claim writes a processing entry for the event unless one exists whose lease
+// hasn't expired, or the event already succeeded.
+func (l *DynamoLedger) Claim(ctx context.Context, key string, messageID string) (Entry, bool, error) {
+	now := l.now()
+
+	item := l.key(key)
+	item["type"] = &types.AttributeValueMemberS{Value: "event"}
+	item["status"] = &types.AttributeValueMemberS{Value: string(StatusProcessing)}
+	item["messageId"] = &types.AttributeValueMemberS{Value: messageID}
+	item["leaseExpires"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(l.lease).Unix(), 10)}
+	item["updated"] = &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)}
+	item["ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(l.retention).Unix(), 10)}
+
+	_, err := l.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(l.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(#pk) OR (#status = :processing AND #leaseExpires < :now)"),
+		ExpressionAttributeNames: map[string]string{
+			"#pk":           "pk",
+			"#status":       "status",
+			"#leaseExpires": "leaseExpires",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":processing": &types.AttributeValueMemberS{Value: string(StatusProcessing)},
+			":now":        &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+	if err == nil {
+		return Entry{}, true, nil
+	}
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditionFailed) {
+		return Entry{}, false, fmt.Errorf("failed to claim event: %w", err)
+	}
+
+	result, err := l.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(l.tableName),
+		Key:            l.key(key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("failed to read event: %w", err)
+	}
+
+	// The claim was released between the put and the get, the next delivery
+	// will claim it.
+	if result.Item == nil {
+		return Entry{Status: StatusProcessing}, false, nil
+	}
+
+	return entryFromItem(result.Item), false, nil
+}
+
+// Complete marks the event as succeeded and stores its result.
+func (l *DynamoLedger) Complete(ctx context.Context, key string, result json.RawMessage) error {
+	now := l.now()
+
+	updateExpression := "SET #status = :succeeded, #updated = :updated, #ttl = :ttl"
+	names := map[string]string{
+		"#status":       "status",
+		"#updated":      "updated",
+		"#ttl":          "ttl",
+		"#leaseExpires": "leaseExpires",
+	}
+	values := map[string]types.AttributeValue{
+		":succeeded": &types.AttributeValueMemberS{Value: string(StatusSucceeded)},
+		":updated":   &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+		":ttl":       &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(l.retention).Unix(), 10)},
+	}
+
+	if len(result) > 0 {
+		updateExpression += ", #result = :result"
+		names["#result"] = "result"
+		values[":result"] = &types.AttributeValueMemberS{Value: string(result)}
+	}
+
+	if _, err := l.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(l.tableName),
+		Key:                       l.key(key),
+		UpdateExpression:          aws.String(updateExpression + " REMOVE #leaseExpires"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}); err != nil {
+		return fmt.Errorf("failed to complete event: %w", err)
+	}
+
+	return nil
+}
+
+// Release deletes the event's processing entry. An entry that already
+// succeeded is left in place.
+func (l *DynamoLedger) Release(ctx context.Context, key string) error {
+	_, err := l.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(l.tableName),
+		Key:                 l.key(key),
+		ConditionExpression: aws.String("#status = :processing"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":processing": &types.AttributeValueMemberS{Value: string(StatusProcessing)},
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &conditionFailed) {
+		return fmt.Errorf("failed to release event: %w", err)
+	}
+
+	return nil
+}
+
+func entryFromItem(item map[string]types.AttributeValue) Entry {
+	var entry Entry
+	if v, ok := item["status"].(*types.AttributeValueMemberS); ok {
+		entry.Status = Status(v.Value)
+	}
+	if v, ok := item["messageId"].(*types.AttributeValueMemberS); ok {
+		entry.MessageID = v.Value
+	}
+	if v, ok := item["result"].(*types.AttributeValueMemberS); ok {
+		entry.Result = json.RawMessage(v.Value)
+	}
+	if v, ok := item["updated"].(*types.AttributeValueMemberS); ok {
+		entry.Updated, _ = time.Parse(time.RFC3339Nano, v.Value)
+	}
+	return entry
+}
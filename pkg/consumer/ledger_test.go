@@ -0,0 +1,159 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDynamoDB returns the configured errors and item, recording the requests
+// made to it.
+type fakeDynamoDB struct {
+	putErr    error
+	deleteErr error
+	item      map[string]types.AttributeValue
+
+	puts    []*dynamodb.PutItemInput
+	updates []*dynamodb.UpdateItemInput
+	deletes []*dynamodb.DeleteItemInput
+}
+
+func (f *fakeDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.puts = append(f.puts, params)
+	return &dynamodb.PutItemOutput{}, f.putErr
+}
+
+func (f *fakeDynamoDB) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f.item}, nil
+}
+
+func (f *fakeDynamoDB) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.updates = append(f.updates, params)
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.deletes = append(f.deletes, params)
+	return &dynamodb.DeleteItemOutput{}, f.deleteErr
+}
+
+func newTestLedger(client DynamoDBAPI) *DynamoLedger {
+	ledger := NewDynamoLedger(client, "table", 30*time.Second, time.Hour)
+	ledger.now = func() time.Time {
+		return time.Unix(1000, 0).UTC()
+	}
+	return ledger
+}
+
+func TestDynamoLedgerClaim(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("claimed", func(t *testing.T) {
+		client := new(fakeDynamoDB)
+
+		_, claimed, err := newTestLedger(client).Claim(ctx, "event-1", "message-1")
+
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		require.Len(t, client.puts, 1)
+
+		item := client.puts[0].Item
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "event#event-1"}, item["pk"])
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "event#event-1"}, item["sk"])
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "processing"}, item["status"])
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "message-1"}, item["messageId"])
+		assert.Equal(t, &types.AttributeValueMemberN{Value: "1030"}, item["leaseExpires"])
+		assert.Equal(t, &types.AttributeValueMemberN{Value: "4600"}, item["ttl"])
+		assert.NotNil(t, client.puts[0].ConditionExpression)
+	})
+
+	t.Run("already processed", func(t *testing.T) {
+		client := &fakeDynamoDB{
+			putErr: &types.ConditionalCheckFailedException{},
+			item: map[string]types.AttributeValue{
+				"status":    &types.AttributeValueMemberS{Value: "succeeded"},
+				"messageId": &types.AttributeValueMemberS{Value: "message-1"},
+				"result":    &types.AttributeValueMemberS{Value: `{"userId":"user-1"}`},
+				"updated":   &types.AttributeValueMemberS{Value: "1970-01-01T00:16:40Z"},
+			},
+		}
+
+		entry, claimed, err := newTestLedger(client).Claim(ctx, "event-1", "message-2")
+
+		require.NoError(t, err)
+		assert.False(t, claimed)
+		assert.Equal(t, Entry{
+			Status:    StatusSucceeded,
+			MessageID: "message-1",
+			Result:    json.RawMessage(`{"userId":"user-1"}`),
+			Updated:   time.Unix(1000, 0).UTC(),
+		}, entry)
+	})
+
+	t.Run("released before read", func(t *testing.T) {
+		client := &fakeDynamoDB{putErr: &types.ConditionalCheckFailedException{}}
+
+		entry, claimed, err := newTestLedger(client).Claim(ctx, "event-1", "message-2")
+
+		require.NoError(t, err)
+		assert.False(t, claimed)
+		assert.Equal(t, StatusProcessing, entry.Status)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		client := &fakeDynamoDB{putErr: errors.New("unavailable")}
+
+		_, claimed, err := newTestLedger(client).Claim(ctx, "event-1", "message-1")
+
+		assert.Error(t, err)
+		assert.False(t, claimed)
+	})
+}
+
+func TestDynamoLedgerComplete(t *testing.T) {
+	client := new(fakeDynamoDB)
+	ledger := newTestLedger(client)
+
+	require.NoError(t, ledger.Complete(context.Background(), "event-1", nil))
+	require.NoError(t, ledger.Complete(context.Background(), "event-2", json.RawMessage(`{"userId":"user-1"}`)))
+	require.Len(t, client.updates, 2)
+
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "succeeded"}, client.updates[0].ExpressionAttributeValues[":succeeded"])
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "4600"}, client.updates[0].ExpressionAttributeValues[":ttl"])
+	assert.NotContains(t, client.updates[0].ExpressionAttributeValues, ":result")
+	assert.Equal(t, &types.AttributeValueMemberS{Value: `{"userId":"user-1"}`}, client.updates[1].ExpressionAttributeValues[":result"])
+}
+
+func TestDynamoLedgerRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "released"},
+		{name: "already succeeded", err: &types.ConditionalCheckFailedException{}},
+		{name: "error", err: errors.New("unavailable"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeDynamoDB{deleteErr: tt.err}
+
+			err := newTestLedger(client).Release(context.Background(), "event-1")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, client.deletes, 1)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package events
+
+// Type is the type of an event. It is sent as the type message attribute,
+// which service subscriptions filter on, and in the event's envelope.
+type Type string
+
+// Every event published by pager. Events ending in .failed are published by a
+// worker that gave up processing an event and carry the payload of the event
+// that failed.
+const (
+	TypeAgencyDeactivated                Type = "agency.deactivated"
+	TypeAgencyReactivated                Type = "agency.reactivated"
+	TypeAgencyMembershipCreated          Type = "agency.membership.created"
+	TypeAgencyMembershipUpdated          Type = "agency.membership.updated"
+	TypeAgencyMembershipDeleted          Type = "agency.membership.deleted"
+	TypeAgencyMembershipStatusSyncFailed Type = "agency.membership.status.sync.failed"
+	TypeAgencyInvitationUpdateFailed     Type = "agency.invitation.update.failed"
+	TypeAgencyRegistrationCreated        Type = "agency.registration.created"
+	TypeAgencyRegistrationCreateFailed   Type = "agency.registration.create.failed"
+	TypeAgencyRegistrationSyncFailed     Type = "agency.registration.sync.failed"
+	TypeAgencyRegistrationDeleted        Type = "agency.registration.deleted"
+	TypeAgencyRegistrationDeleteFailed   Type = "agency.registration.delete.failed"
+
+	TypeUserDeactivated            Type = "user.deactivated"
+	TypeUserReactivated            Type = "user.reactivated"
+	TypeUserEnsureInvite           Type = "user.ensure-invite"
+	TypeUserEnsureInviteFailed     Type = "user.ensure-invite.failed"
+	TypeUserInviteTargetEnsured    Type = "user.invite-target.ensured"
+	TypeUserMembershipUpserted     Type = "user.membership.upserted"
+	TypeUserMembershipUpsertFailed Type = "user.membership.upsert.failed"
+	TypeUserMembershipDeleteFailed Type = "user.membership.delete.failed"
+	TypeUserEndpointUpsertFailed   Type = "user.endpoint.upsert.failed"
+	TypeUserEndpointDeleteFailed   Type = "user.endpoint.delete.failed"
+
+	TypeEndpointCreated                  Type = "endpoint.created"
+	TypeEndpointUpdated                  Type = "endpoint.updated"
+	TypeEndpointDeleted                  Type = "endpoint.deleted"
+	TypeEndpointResolve                  Type = "endpoint.resolve"
+	TypeEndpointResolved                 Type = "endpoint.resolved"
+	TypeEndpointResolutionFailed         Type = "endpoint.resolution.failed"
+	TypeEndpointRegistrationDeclined     Type = "endpoint.registration.declined"
+	TypeEndpointRegistrationUpserted     Type = "endpoint.registration.upserted"
+	TypeEndpointRegistrationUpsertFailed Type = "endpoint.registration.upsert.failed"
+	TypeEndpointRegistrationDeleted      Type = "endpoint.registration.deleted"
+	TypeEndpointRegistrationDeleteFailed Type = "endpoint.registration.delete.failed"
+	TypeEndpointAgencyStatusSyncFailed   Type = "endpoint.agency.status.sync.failed"
+	TypeEndpointUserSuspendFailed        Type = "endpoint.user.suspend.failed"
+	TypeEndpointDeliver                  Type = "endpoint.deliver"
+	TypeEndpointDeliverFailed            Type = "endpoint.deliver.failed"
+	TypeEndpointDeliverySucceeded        Type = "endpoint.delivery.succeeded"
+	TypeEndpointDeliveryFailed           Type = "endpoint.delivery.failed"
+
+	TypePageResponseRecorded Type = "page.response.recorded"
+)
+
+// catalog is the registry of event types, mapping each to the version of its
+// payload that is published. A type's version is bumped when its payload
+// changes in a way older consumers can't read.
+var catalog = map[Type]int{
+	TypeAgencyDeactivated:                1,
+	TypeAgencyReactivated:                1,
+	TypeAgencyMembershipCreated:          1,
+	TypeAgencyMembershipUpdated:          1,
+	TypeAgencyMembershipDeleted:          1,
+	TypeAgencyMembershipStatusSyncFailed: 1,
+	TypeAgencyInvitationUpdateFailed:     1,
+	TypeAgencyRegistrationCreated:        1,
+	TypeAgencyRegistrationCreateFailed:   1,
+	TypeAgencyRegistrationSyncFailed:     1,
+	TypeAgencyRegistrationDeleted:        1,
+	TypeAgencyRegistrationDeleteFailed:   1,
+
+	TypeUserDeactivated:            1,
+	TypeUserReactivated:            1,
+	TypeUserEnsureInvite:           1,
+	TypeUserEnsureInviteFailed:     1,
+	TypeUserInviteTargetEnsured:    1,
+	TypeUserMembershipUpserted:     1,
+	TypeUserMembershipUpsertFailed: 1,
+	TypeUserMembershipDeleteFailed: 1,
+	TypeUserEndpointUpsertFailed:   1,
+	TypeUserEndpointDeleteFailed:   1,
+
+	TypeEndpointCreated:                  1,
+	TypeEndpointUpdated:                  1,
+	TypeEndpointDeleted:                  1,
+	TypeEndpointResolve:                  1,
+	TypeEndpointResolved:                 1,
+	TypeEndpointResolutionFailed:         1,
+	TypeEndpointRegistrationDeclined:     1,
+	TypeEndpointRegistrationUpserted:     1,
+	TypeEndpointRegistrationUpsertFailed: 1,
+	TypeEndpointRegistrationDeleted:      1,
+	TypeEndpointRegistrationDeleteFailed: 1,
+	TypeEndpointAgencyStatusSyncFailed:   1,
+	TypeEndpointUserSuspendFailed:        1,
+	TypeEndpointDeliver:                  1,
+	TypeEndpointDeliverFailed:            1,
+	TypeEndpointDeliverySucceeded:        1,
+	TypeEndpointDeliveryFailed:           1,
+
+	TypePageResponseRecorded: 1,
+}
+
+// Version returns the version of the type's payload that is published, or 0 if
+// the type isn't in the catalog.
+func (t Type) Version() int {
+	return catalog[t]
+}
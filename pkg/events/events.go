@@ -0,0 +1,104 @@
+// Package events defines the events pager's services publish to each other
+// over SNS: a catalog of event types, a typed payload for each and the
+// envelope every event is published in.
+//
+// An event is published with
+//
+//	publisher := events.NewPublisher(snsClient, config.EventsTopicARN, "agency")
+//	err := publisher.Publish(ctx, events.TypeAgencyMembershipDeleted, events.AgencyMembershipDeleted{
+//		UserID:   userID,
+//		AgencyID: agencyID,
+//	})
+//
+// and consumed with
+//
+//	envelope, err := events.Decode(snsRecord.Message, eventType)
+//	payload, err := events.DecodePayload[events.AgencyMembershipDeleted](envelope)
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Envelope wraps the payload of every event.
+type Envelope struct {
+	// ID uniquely identifies the event.
+	ID string `json:"id"`
+	// Type is the type of the event, it determines the payload.
+	Type Type `json:"type"`
+	// Version is the version of the payload.
+	Version int `json:"version"`
+	// Time is when the event was published.
+	Time time.Time `json:"time"`
+	// Source is the service that published the event.
+	Source string `json:"source"`
+	// CorrelationID is shared by every event published as a result of the same
+	// request, see WithCorrelationID.
+	CorrelationID string `json:"correlationId"`
+	// Payload is the event's payload, one of the structs in this package.
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Decode parses the message of an event of the given type, as read from the
+// SNS type message attribute.
+//
+// Messages published before events were enveloped are bare payloads, they are
+// returned in an envelope of the given type at version 0 so consumers can
+// handle messages that were in flight when publishers were upgraded.
+func Decode(message string, eventType Type) (Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal([]byte(message), &envelope); err != nil {
+		return Envelope{}, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	if envelope.Version == 0 || envelope.Payload == nil {
+		return Envelope{
+			Type:    eventType,
+			Payload: json.RawMessage(message),
+		}, nil
+	}
+
+	if envelope.Type != eventType {
+		return Envelope{}, fmt.Errorf("event type %s does not match %s", envelope.Type, eventType)
+	}
+
+	return envelope, nil
+}
+
+// DecodePayload unmarshals the envelope's payload.
+func DecodePayload[T any](envelope Envelope) (T, error) {
+	var payload T
+	if len(envelope.Payload) == 0 {
+		return payload, errors.New("event has no payload")
+	}
+
+	if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+		return payload, fmt.Errorf("failed to decode %s payload: %w", envelope.Type, err)
+	}
+
+	return payload, nil
+}
+
+type contextKey string
+
+// contextKeyCorrelationID is the key used to store the correlation ID in a
+// context.
+const contextKeyCorrelationID contextKey = "correlationId"
+
+// WithCorrelationID returns a new context with the correlation ID stored in it.
+// Events published with the context carry the ID, a worker handling an event
+// should pass on the event's correlation ID so the events it publishes can be
+// traced back to the same request.
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, contextKeyCorrelationID, correlationID)
+}
+
+// CorrelationIDFrom extracts the correlation ID from the context, if present.
+func CorrelationIDFrom(ctx context.Context) (string, bool) {
+	correlationID, ok := ctx.Value(contextKeyCorrelationID).(string)
+	return correlationID, ok && correlationID != ""
+}
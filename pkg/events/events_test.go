@@ -0,0 +1,170 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSNS records the messages published to it.
+type fakeSNS struct {
+	inputs []*sns.PublishInput
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.inputs = append(f.inputs, params)
+	return &sns.PublishOutput{}, nil
+}
+
+func newTestPublisher(client SNSAPI) *Publisher {
+	publisher := NewPublisher(client, "arn:aws:sns:us-east-1:123456789012:events", "agency")
+	publisher.now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+	publisher.newID = func() string { return "event-1" }
+	return publisher
+}
+
+func TestPublish(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		client    = new(fakeSNS)
+		publisher = newTestPublisher(client)
+	)
+
+	err := publisher.Publish(ctx, TypeAgencyMembershipDeleted, AgencyMembershipDeleted{
+		UserID:   "user-1",
+		AgencyID: "agency-1",
+	})
+	require.NoError(t, err)
+	require.Len(t, client.inputs, 1)
+
+	input := client.inputs[0]
+	assert.Equal(t, "arn:aws:sns:us-east-1:123456789012:events", aws.ToString(input.TopicArn))
+	assert.Equal(t, "agency.membership.deleted", aws.ToString(input.MessageAttributes["type"].StringValue))
+	assert.JSONEq(t, `{
+		"id": "event-1",
+		"type": "agency.membership.deleted",
+		"version": 1,
+		"time": "2025-01-02T03:04:05Z",
+		"source": "agency",
+		"correlationId": "event-1",
+		"payload": {"userId": "user-1", "agencyId": "agency-1"}
+	}`, aws.ToString(input.Message))
+}
+
+func TestPublishCorrelationID(t *testing.T) {
+	var (
+		ctx       = WithCorrelationID(context.Background(), "request-1")
+		client    = new(fakeSNS)
+		publisher = newTestPublisher(client)
+	)
+
+	require.NoError(t, publisher.Publish(ctx, TypeUserEnsureInvite, UserEnsureInvite{}))
+
+	envelope, err := Decode(aws.ToString(client.inputs[0].Message), TypeUserEnsureInvite)
+	require.NoError(t, err)
+	assert.Equal(t, "event-1", envelope.ID)
+	assert.Equal(t, "request-1", envelope.CorrelationID)
+}
+
+func TestPublishEnvelope(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		client    = new(fakeSNS)
+		publisher = newTestPublisher(client)
+	)
+
+	envelope, err := publisher.Envelope(ctx, TypeEndpointResolve, EndpointResolve{RegistrationCode: "code-1"})
+	require.NoError(t, err)
+	assert.Empty(t, client.inputs)
+
+	// Publishing the envelope again delivers the same event.
+	require.NoError(t, publisher.PublishEnvelope(ctx, envelope))
+	require.NoError(t, publisher.PublishEnvelope(ctx, envelope))
+	require.Len(t, client.inputs, 2)
+	assert.Equal(t, aws.ToString(client.inputs[0].Message), aws.ToString(client.inputs[1].Message))
+	assert.Equal(t, "endpoint.resolve", aws.ToString(client.inputs[1].MessageAttributes["type"].StringValue))
+
+	published, err := Decode(aws.ToString(client.inputs[0].Message), TypeEndpointResolve)
+	require.NoError(t, err)
+	assert.Equal(t, envelope.ID, published.ID)
+}
+
+func TestPublishUnknownType(t *testing.T) {
+	var (
+		client    = new(fakeSNS)
+		publisher = newTestPublisher(client)
+	)
+
+	err := publisher.Publish(context.Background(), Type("agency.unknown"), struct{}{})
+	assert.ErrorContains(t, err, "unknown event type")
+	assert.Empty(t, client.inputs)
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("envelope", func(t *testing.T) {
+		var (
+			client    = new(fakeSNS)
+			publisher = newTestPublisher(client)
+			payload   = EndpointDeliver{
+				Title:    "Missing hiker",
+				PageID:   "page-1",
+				AgencyID: "agency-1",
+				Location: &Location{Latitude: 39.7, Longitude: -105.1, Type: "COORDINATES"},
+			}
+		)
+
+		require.NoError(t, publisher.Publish(context.Background(), TypeEndpointDeliver, payload))
+
+		envelope, err := Decode(aws.ToString(client.inputs[0].Message), TypeEndpointDeliver)
+		require.NoError(t, err)
+		assert.Equal(t, 1, envelope.Version)
+		assert.Equal(t, "agency", envelope.Source)
+
+		decoded, err := DecodePayload[EndpointDeliver](envelope)
+		require.NoError(t, err)
+		assert.Equal(t, payload, decoded)
+	})
+
+	t.Run("bare payload", func(t *testing.T) {
+		envelope, err := Decode(`{"userId": "user-1", "agencyId": "agency-1"}`, TypeAgencyMembershipDeleted)
+		require.NoError(t, err)
+		assert.Equal(t, TypeAgencyMembershipDeleted, envelope.Type)
+		assert.Zero(t, envelope.Version)
+
+		decoded, err := DecodePayload[AgencyMembershipDeleted](envelope)
+		require.NoError(t, err)
+		assert.Equal(t, AgencyMembershipDeleted{UserID: "user-1", AgencyID: "agency-1"}, decoded)
+	})
+
+	t.Run("mismatched type", func(t *testing.T) {
+		message, err := json.Marshal(Envelope{
+			ID:      "event-1",
+			Type:    TypeUserDeactivated,
+			Version: 1,
+			Payload: json.RawMessage(`{}`),
+		})
+		require.NoError(t, err)
+
+		_, err = Decode(string(message), TypeUserReactivated)
+		assert.ErrorContains(t, err, "does not match")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := Decode("not json", TypeUserDeactivated)
+		assert.Error(t, err)
+	})
+}
+
+func TestCatalog(t *testing.T) {
+	for eventType, version := range catalog {
+		assert.Positive(t, version, eventType)
+	}
+
+	assert.Zero(t, Type("agency.unknown").Version())
+}
@@ -0,0 +1,221 @@
+package events
+
+import "time"
+
+//-----------------------------------------------------------------------------
+// AGENCY
+//-----------------------------------------------------------------------------
+
+// AgencyDeactivated is the payload of agency.deactivated. It is also the
+// payload of agency.reactivated and endpoint.agency.status.sync.failed.
+type AgencyDeactivated struct {
+	AgencyID   string    `json:"agencyId"`
+	Status     string    `json:"status"`
+	Modified   time.Time `json:"modified"`
+	ModifiedBy string    `json:"modifiedBy"`
+}
+
+// AgencyReactivated is the payload of agency.reactivated.
+type AgencyReactivated = AgencyDeactivated
+
+// AgencyMembershipCreated is the payload of agency.membership.created. It is
+// also the payload of agency.membership.updated and
+// user.membership.upsert.failed.
+type AgencyMembershipCreated struct {
+	UserID   string `json:"userId"`
+	AgencyID string `json:"agencyId"`
+	Role     string `json:"role"`
+}
+
+// AgencyMembershipUpdated is the payload of agency.membership.updated.
+type AgencyMembershipUpdated = AgencyMembershipCreated
+
+// AgencyMembershipDeleted is the payload of agency.membership.deleted and
+// user.membership.delete.failed.
+type AgencyMembershipDeleted struct {
+	UserID   string `json:"userId"`
+	AgencyID string `json:"agencyId"`
+}
+
+// AgencyRegistrationCreated is the payload of agency.registration.created and
+// endpoint.registration.upsert.failed.
+type AgencyRegistrationCreated struct {
+	EndpointID string `json:"endpointId"`
+	AgencyID   string `json:"agencyId"`
+}
+
+// AgencyRegistrationDeleted is the payload of agency.registration.deleted and
+// endpoint.registration.delete.failed.
+type AgencyRegistrationDeleted struct {
+	AgencyID   string `json:"agencyId"`
+	EndpointID string `json:"endpointId"`
+}
+
+//-----------------------------------------------------------------------------
+// USER
+//-----------------------------------------------------------------------------
+
+// UserDeactivated is the payload of user.deactivated. It is also the payload
+// of user.reactivated, agency.membership.status.sync.failed and
+// endpoint.user.suspend.failed.
+type UserDeactivated struct {
+	UserID     string    `json:"userId"`
+	Status     string    `json:"status"`
+	Modified   time.Time `json:"modified"`
+	ModifiedBy string    `json:"modifiedBy"`
+}
+
+// UserReactivated is the payload of user.reactivated.
+type UserReactivated = UserDeactivated
+
+// UserEnsureInvite asks the user service to ensure a user exists for an
+// invitee. It is the payload of user.ensure-invite and
+// user.ensure-invite.failed.
+type UserEnsureInvite struct {
+	Email    string `json:"email"`
+	AgencyID string `json:"agencyId"`
+}
+
+// UserInviteTargetEnsured is the payload of user.invite-target.ensured and
+// agency.invitation.update.failed.
+type UserInviteTargetEnsured struct {
+	Email    string `json:"email"`
+	AgencyID string `json:"agencyId"`
+	UserID   string `json:"userId"`
+}
+
+// UserMembershipUpserted is the payload of user.membership.upserted.
+type UserMembershipUpserted struct {
+	UserID   string `json:"userId"`
+	AgencyID string `json:"agencyId"`
+}
+
+//-----------------------------------------------------------------------------
+// ENDPOINT
+//-----------------------------------------------------------------------------
+
+// EndpointCreated is the payload of endpoint.created and
+// user.endpoint.upsert.failed.
+type EndpointCreated struct {
+	EndpointID   string `json:"endpointId"`
+	UserID       string `json:"userId"`
+	Name         string `json:"name"`
+	EndpointType string `json:"endpointType"`
+	Enabled      bool   `json:"enabled"`
+}
+
+// EndpointUpdated is the payload of endpoint.updated and
+// agency.registration.sync.failed. Agencies are the agencies the endpoint is
+// registered to.
+type EndpointUpdated struct {
+	EndpointID   string   `json:"endpointId"`
+	UserID       string   `json:"userId"`
+	Name         string   `json:"name"`
+	EndpointType string   `json:"endpointType"`
+	Enabled      bool     `json:"enabled"`
+	Agencies     []string `json:"agencies"`
+}
+
+// EndpointDeleted is the payload of endpoint.deleted,
+// agency.registration.delete.failed and user.endpoint.delete.failed.
+type EndpointDeleted struct {
+	EndpointID string   `json:"endpointId"`
+	UserID     string   `json:"userId"`
+	Agencies   []string `json:"agencies"`
+}
+
+// EndpointResolve asks the endpoint service to resolve the endpoint a
+// registration code belongs to. It is the payload of endpoint.resolve.
+type EndpointResolve struct {
+	AgencyID         string `json:"agencyId"`
+	RegistrationCode string `json:"registrationCode"`
+	RequestedBy      string `json:"requestedBy"`
+}
+
+// EndpointResolved is the payload of endpoint.resolved and
+// agency.registration.create.failed.
+type EndpointResolved struct {
+	RegistrationCode string `json:"registrationCode"`
+	AgencyID         string `json:"agencyId"`
+	EndpointID       string `json:"endpointId"`
+}
+
+// EndpointResolutionFailed is the payload of endpoint.resolution.failed.
+// Reason is empty when resolution failed after retries rather than for a
+// known reason.
+type EndpointResolutionFailed struct {
+	RegistrationCode string `json:"registrationCode"`
+	AgencyID         string `json:"agencyId"`
+	Reason           string `json:"reason,omitempty"`
+}
+
+// EndpointRegistrationDeclined is the payload of
+// endpoint.registration.declined.
+type EndpointRegistrationDeclined struct {
+	RegistrationCode string `json:"registrationCode"`
+	AgencyID         string `json:"agencyId"`
+	EndpointID       string `json:"endpointId"`
+	DeclinedBy       string `json:"declinedBy"`
+}
+
+// EndpointRegistrationUpserted is the payload of
+// endpoint.registration.upserted.
+type EndpointRegistrationUpserted struct {
+	AgencyID   string `json:"agencyId"`
+	EndpointID string `json:"endpointId"`
+}
+
+// EndpointRegistrationDeleted is the payload of endpoint.registration.deleted.
+type EndpointRegistrationDeleted struct {
+	AgencyID   string `json:"agencyId"`
+	EndpointID string `json:"endpointId"`
+}
+
+// Location is where a page is for.
+type Location struct {
+	Description string  `json:"description"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Type        string  `json:"type"`
+}
+
+// EndpointDeliver asks the endpoint service to deliver a page to an agency's
+// endpoints. It is the payload of endpoint.deliver and
+// endpoint.deliver.failed.
+type EndpointDeliver struct {
+	Title    string    `json:"title"`
+	Notes    string    `json:"notes"`
+	Location *Location `json:"location"`
+	PageID   string    `json:"pageId"`
+	AgencyID string    `json:"agencyId"`
+}
+
+// EndpointDelivery is the payload of endpoint.delivery.succeeded and
+// endpoint.delivery.failed, the outcome of delivering a page to one endpoint.
+type EndpointDelivery struct {
+	PageID             string    `json:"pageId"`
+	AgencyID           string    `json:"agencyId"`
+	EndpointID         string    `json:"endpointId"`
+	EndpointType       string    `json:"endpointType"`
+	EndpointName       string    `json:"endpointName"`
+	UserID             string    `json:"userId"`
+	StatusCode         int       `json:"statusCode"`
+	LatencyMs          int64     `json:"latencyMs"`
+	Attempts           int       `json:"attempts"`
+	ProviderMessageIDs []string  `json:"providerMessageIds,omitempty"`
+	Error              string    `json:"error"`
+	AttemptedAt        time.Time `json:"attemptedAt"`
+}
+
+//-----------------------------------------------------------------------------
+// PAGE
+//-----------------------------------------------------------------------------
+
+// PageResponseRecorded is the payload of page.response.recorded.
+type PageResponseRecorded struct {
+	PageID   string     `json:"pageId"`
+	UserID   string     `json:"userId"`
+	Agencies []string   `json:"agencies"`
+	Status   string     `json:"status"`
+	ETA      *time.Time `json:"eta,omitempty"`
+}
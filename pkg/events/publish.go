@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/google/uuid"
+)
+
+// SNSAPI is the part of the SNS client used to publish events.
+type SNSAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// Publisher publishes events to an SNS topic on behalf of a service.
+type Publisher struct {
+	client   SNSAPI
+	topicARN string
+	source   string
+	now      func() time.Time
+	newID    func() string
+}
+
+// NewPublisher returns a publisher for the topic. Source names the publishing
+// service in each event's envelope.
+func NewPublisher(client SNSAPI, topicARN, source string) *Publisher {
+	return &Publisher{
+		client:   client,
+		topicARN: topicARN,
+		source:   source,
+		now:      time.Now,
+		newID:    uuid.NewString,
+	}
+}
+
+// Publish publishes an event with the given payload, which should be the
+// payload struct for the event's type. The type is also set as the message's
+// type attribute for subscriptions to filter on.
+//
+// The event's correlation ID is read from ctx, an event published without one
+// starts a new correlation with its own ID.
+func (p *Publisher) Publish(ctx context.Context, eventType Type, payload any) error {
+	envelope, err := p.Envelope(ctx, eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	return p.PublishEnvelope(ctx, envelope)
+}
+
+// PublishEnvelope publishes an envelope built by Envelope. The envelope's ID is
+// kept, so publishing the same envelope twice delivers the same event twice.
+func (p *Publisher) PublishEnvelope(ctx context.Context, envelope Envelope) error {
+	message, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", envelope.Type, err)
+	}
+
+	if _, err := p.client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(p.topicARN),
+		Message:  aws.String(string(message)),
+		MessageAttributes: map[string]snstypes.MessageAttributeValue{
+			"type": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(string(envelope.Type)),
+			},
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", envelope.Type, err)
+	}
+
+	return nil
+}
+
+// Envelope wraps the payload in a new envelope without publishing it, for
+// events that are stored and published later with PublishEnvelope.
+func (p *Publisher) Envelope(ctx context.Context, eventType Type, payload any) (Envelope, error) {
+	version := eventType.Version()
+	if version == 0 {
+		return Envelope{}, fmt.Errorf("unknown event type: %s", eventType)
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+
+	id := p.newID()
+
+	correlationID, ok := CorrelationIDFrom(ctx)
+	if !ok {
+		correlationID = id
+	}
+
+	return Envelope{
+		ID:            id,
+		Type:          eventType,
+		Version:       version,
+		Time:          p.now().UTC(),
+		Source:        p.source,
+		CorrelationID: correlationID,
+		Payload:       payloadJSON,
+	}, nil
+}
@@ -0,0 +1,82 @@
+// Package outbox publishes events written in the same transaction as the
+// change that caused them. Publishing an event after a write can fail and
+// leave the write without its event, for example a pending invitation that
+// never progresses. Instead a handler stages the event as an outbox item in its
+// write transaction
+//
+//	envelope, err := publisher.Envelope(ctx, events.TypeUserEnsureInvite, payload)
+//	item, err := outbox.Put(config.AgencyTableName, envelope, now)
+//	dynamoClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+//		TransactItems: []types.TransactWriteItem{invitationItem, item},
+//	})
+//
+// and a Relay, subscribed to the table's stream, publishes the outbox items and
+// marks them sent.
+//
+// Lambda drops a stream record once its retries run out, leaving its item
+// pending. Pending items are kept in the table's PendingIndex, and the relay's
+// Sweep, run on a schedule, publishes the ones that have been pending too long.
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+)
+
+// Status is the state of an outbox item.
+type Status string
+
+const (
+	// StatusPending means the event hasn't been published.
+	StatusPending Status = "pending"
+	// StatusSent means the event was published.
+	StatusSent Status = "sent"
+)
+
+// KeyPrefix prefixes the partition key of every outbox item. A table's stream
+// subscription filters on it so the relay only sees outbox items.
+const KeyPrefix = "outbox#"
+
+// PendingIndex is the name of the sparse global secondary index of pending
+// outbox items. Its partition key is outboxStatus and its sort key is
+// pendingSince, the unix time the item was staged. Both are removed when the
+// item is sent, which drops it from the index.
+const PendingIndex = "outbox-pending"
+
+// Put returns a transaction item that stages the event in the table's outbox.
+func Put(tableName string, envelope events.Envelope, now time.Time) (types.TransactWriteItem, error) {
+	if envelope.ID == "" {
+		return types.TransactWriteItem{}, fmt.Errorf("%s event has no ID", envelope.Type)
+	}
+
+	message, err := json.Marshal(envelope)
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("failed to marshal %s event: %w", envelope.Type, err)
+	}
+
+	key := fmt.Sprintf("%s%s", KeyPrefix, envelope.ID)
+
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName: aws.String(tableName),
+			Item: map[string]types.AttributeValue{
+				"pk":        &types.AttributeValueMemberS{Value: key},
+				"sk":        &types.AttributeValueMemberS{Value: key},
+				"type":      &types.AttributeValueMemberS{Value: "outbox"},
+				"eventType": &types.AttributeValueMemberS{Value: string(envelope.Type)},
+				"status":    &types.AttributeValueMemberS{Value: string(StatusPending)},
+				"envelope":  &types.AttributeValueMemberS{Value: string(message)},
+				"created":   &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+				// Keys of the PendingIndex.
+				"outboxStatus": &types.AttributeValueMemberS{Value: string(StatusPending)},
+				"pendingSince": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			},
+		},
+	}, nil
+}
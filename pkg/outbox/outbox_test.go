@@ -0,0 +1,229 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	lambdaevents "github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePublisher records the envelopes published to it, failing for the IDs in
+// fail.
+type fakePublisher struct {
+	fail      map[string]bool
+	envelopes []events.Envelope
+}
+
+func (f *fakePublisher) PublishEnvelope(ctx context.Context, envelope events.Envelope) error {
+	if f.fail[envelope.ID] {
+		return errors.New("unavailable")
+	}
+	f.envelopes = append(f.envelopes, envelope)
+	return nil
+}
+
+// fakeDynamoDB records the updates and queries made through it. Queries return
+// the pending items that haven't been updated since.
+type fakeDynamoDB struct {
+	pending []map[string]types.AttributeValue
+	updates []*dynamodb.UpdateItemInput
+	queries []*dynamodb.QueryInput
+}
+
+func (f *fakeDynamoDB) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.updates = append(f.updates, params)
+	f.pending = slices.DeleteFunc(f.pending, func(item map[string]types.AttributeValue) bool {
+		return item["pk"].(*types.AttributeValueMemberS).Value == params.Key["pk"].(*types.AttributeValueMemberS).Value
+	})
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	f.queries = append(f.queries, params)
+	return &dynamodb.QueryOutput{Items: slices.Clone(f.pending)}, nil
+}
+
+var testNow = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newEnvelope(id string) events.Envelope {
+	return events.Envelope{
+		ID:            id,
+		Type:          events.TypeUserEnsureInvite,
+		Version:       1,
+		Time:          testNow,
+		Source:        "agency",
+		CorrelationID: "correlation-1",
+		Payload:       []byte(`{"email":"user@example.com","agencyId":"agency-1"}`),
+	}
+}
+
+// newStreamRecord builds the stream record for the insertion of the item Put
+// stages for the envelope.
+func newStreamRecord(t *testing.T, envelope events.Envelope, sequenceNumber string) lambdaevents.DynamoDBEventRecord {
+	t.Helper()
+
+	item, err := Put("table", envelope, testNow)
+	require.NoError(t, err)
+
+	image := make(map[string]lambdaevents.DynamoDBAttributeValue)
+	for name, value := range item.Put.Item {
+		switch value := value.(type) {
+		case *types.AttributeValueMemberS:
+			image[name] = lambdaevents.NewStringAttribute(value.Value)
+		case *types.AttributeValueMemberN:
+			image[name] = lambdaevents.NewNumberAttribute(value.Value)
+		}
+	}
+
+	return lambdaevents.DynamoDBEventRecord{
+		EventID:   sequenceNumber,
+		EventName: string(lambdaevents.DynamoDBOperationTypeInsert),
+		Change: lambdaevents.DynamoDBStreamRecord{
+			SequenceNumber: sequenceNumber,
+			NewImage:       image,
+		},
+	}
+}
+
+func newTestRelay(client DynamoDBAPI, publisher Publisher) *Relay {
+	relay := NewRelay(slog.New(slog.NewTextHandler(io.Discard, nil)), client, publisher, "table", time.Hour)
+	relay.now = func() time.Time { return testNow }
+	return relay
+}
+
+func TestPut(t *testing.T) {
+	item, err := Put("table", newEnvelope("event-1"), testNow)
+	require.NoError(t, err)
+
+	assert.Equal(t, "table", aws.ToString(item.Put.TableName))
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "outbox#event-1"}, item.Put.Item["pk"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "pending"}, item.Put.Item["status"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "user.ensure-invite"}, item.Put.Item["eventType"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "pending"}, item.Put.Item["outboxStatus"])
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "1735787045"}, item.Put.Item["pendingSince"])
+
+	envelope, err := events.Decode(item.Put.Item["envelope"].(*types.AttributeValueMemberS).Value, events.TypeUserEnsureInvite)
+	require.NoError(t, err)
+	assert.Equal(t, newEnvelope("event-1"), envelope)
+
+	_, err = Put("table", events.Envelope{Type: events.TypeUserEnsureInvite}, testNow)
+	assert.Error(t, err)
+}
+
+func TestRelay(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		client    = new(fakeDynamoDB)
+		publisher = new(fakePublisher)
+	)
+
+	sent := newStreamRecord(t, newEnvelope("event-3"), "3")
+	sent.EventName = string(lambdaevents.DynamoDBOperationTypeModify)
+
+	response, err := newTestRelay(client, publisher).Process(ctx, lambdaevents.DynamoDBEvent{
+		Records: []lambdaevents.DynamoDBEventRecord{
+			newStreamRecord(t, newEnvelope("event-1"), "1"),
+			newStreamRecord(t, newEnvelope("event-2"), "2"),
+			sent,
+			{
+				EventName: string(lambdaevents.DynamoDBOperationTypeInsert),
+				Change: lambdaevents.DynamoDBStreamRecord{
+					SequenceNumber: "4",
+					NewImage: map[string]lambdaevents.DynamoDBAttributeValue{
+						"pk": lambdaevents.NewStringAttribute("invite#user@example.com"),
+					},
+				},
+			},
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Empty(t, response.BatchItemFailures)
+	assert.Equal(t, []events.Envelope{newEnvelope("event-1"), newEnvelope("event-2")}, publisher.envelopes)
+
+	require.Len(t, client.updates, 2)
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "outbox#event-1"}, client.updates[0].Key["pk"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "sent"}, client.updates[0].ExpressionAttributeValues[":sent"])
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "1735790645"}, client.updates[0].ExpressionAttributeValues[":ttl"])
+	assert.Contains(t, aws.ToString(client.updates[0].UpdateExpression), "REMOVE #outboxStatus, #pendingSince")
+}
+
+func TestRelayFailure(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		client    = new(fakeDynamoDB)
+		publisher = &fakePublisher{fail: map[string]bool{"event-2": true}}
+	)
+
+	response, err := newTestRelay(client, publisher).Process(ctx, lambdaevents.DynamoDBEvent{
+		Records: []lambdaevents.DynamoDBEventRecord{
+			newStreamRecord(t, newEnvelope("event-1"), "1"),
+			newStreamRecord(t, newEnvelope("event-2"), "2"),
+			newStreamRecord(t, newEnvelope("event-3"), "3"),
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []lambdaevents.DynamoDBBatchItemFailure{{ItemIdentifier: "2"}}, response.BatchItemFailures)
+	assert.Equal(t, []events.Envelope{newEnvelope("event-1")}, publisher.envelopes)
+	assert.Len(t, client.updates, 1)
+}
+
+func TestSweep(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		client    = new(fakeDynamoDB)
+		publisher = &fakePublisher{fail: map[string]bool{"event-1": true}}
+		relay     = newTestRelay(client, publisher)
+		record    = newStreamRecord(t, newEnvelope("event-1"), "1")
+	)
+
+	item, err := Put("table", newEnvelope("event-1"), testNow)
+	require.NoError(t, err)
+	client.pending = append(client.pending, item.Put.Item)
+
+	// The publish fails on every attempt the stream makes, after which Lambda
+	// drops the record and the item is left pending.
+	for range 3 {
+		response, err := relay.Process(ctx, lambdaevents.DynamoDBEvent{
+			Records: []lambdaevents.DynamoDBEventRecord{record},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []lambdaevents.DynamoDBBatchItemFailure{{ItemIdentifier: "1"}}, response.BatchItemFailures)
+	}
+	assert.Empty(t, client.updates)
+
+	// While publishing still fails the sweep fails, leaving the item pending.
+	assert.Error(t, relay.Sweep(ctx, 15*time.Minute))
+	assert.Len(t, client.pending, 1)
+
+	// Once publishing recovers the next sweep publishes the item and marks it
+	// sent.
+	publisher.fail = nil
+	require.NoError(t, relay.Sweep(ctx, 15*time.Minute))
+
+	assert.Equal(t, []events.Envelope{newEnvelope("event-1")}, publisher.envelopes)
+	require.Len(t, client.updates, 1)
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "outbox#event-1"}, client.updates[0].Key["pk"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "sent"}, client.updates[0].ExpressionAttributeValues[":sent"])
+	assert.Empty(t, client.pending)
+
+	require.Len(t, client.queries, 2)
+	assert.Equal(t, PendingIndex, aws.ToString(client.queries[0].IndexName))
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "1735786145"}, client.queries[0].ExpressionAttributeValues[":before"])
+
+	// Nothing is left to sweep.
+	require.NoError(t, relay.Sweep(ctx, 15*time.Minute))
+	assert.Len(t, publisher.envelopes, 1)
+}
@@ -0,0 +1,217 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	lambdaevents "github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+)
+
+// Publisher publishes staged envelopes, it is satisfied by *events.Publisher.
+type Publisher interface {
+	PublishEnvelope(ctx context.Context, envelope events.Envelope) error
+}
+
+// DynamoDBAPI is the part of the DynamoDB client used by the relay.
+type DynamoDBAPI interface {
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+}
+
+// Relay publishes the outbox items inserted into a table, as read from the
+// table's stream, and marks them sent.
+//
+// An item is published at least once. If marking it sent fails the stream
+// delivers it again and it is published again with the same envelope ID, which
+// consumers with a ledger skip. The same goes for an item published by both
+// the stream and Sweep.
+type Relay struct {
+	logger    *slog.Logger
+	client    DynamoDBAPI
+	publisher Publisher
+	tableName string
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewRelay returns a relay for the table's outbox. retention is how long a sent
+// item is kept before the table's ttl expires it, pending items never expire.
+func NewRelay(logger *slog.Logger, client DynamoDBAPI, publisher Publisher, tableName string, retention time.Duration) *Relay {
+	return &Relay{
+		logger:    logger,
+		client:    client,
+		publisher: publisher,
+		tableName: tableName,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// Process publishes the batch's outbox items in order. Lambda retries a stream
+// batch from its first failed record, so processing stops there and the
+// records after it are retried with it.
+func (r *Relay) Process(ctx context.Context, event lambdaevents.DynamoDBEvent) (lambdaevents.DynamoDBEventResponse, error) {
+	for _, record := range event.Records {
+		if err := r.relay(ctx, record); err != nil {
+			r.logger.ErrorContext(
+				ctx,
+				"failed to relay outbox item",
+				slog.String("eventId", record.EventID),
+				slog.Any("error", err))
+
+			return lambdaevents.DynamoDBEventResponse{
+				BatchItemFailures: []lambdaevents.DynamoDBBatchItemFailure{
+					{ItemIdentifier: record.Change.SequenceNumber},
+				},
+			}, nil
+		}
+	}
+
+	return lambdaevents.DynamoDBEventResponse{}, nil
+}
+
+// relay publishes a single outbox item. Records that aren't new outbox items,
+// including the update marking an item sent, are ignored.
+func (r *Relay) relay(ctx context.Context, record lambdaevents.DynamoDBEventRecord) error {
+	if record.EventName != string(lambdaevents.DynamoDBOperationTypeInsert) {
+		return nil
+	}
+
+	image := record.Change.NewImage
+	pk := image["pk"]
+	if pk.DataType() != lambdaevents.DataTypeString || !strings.HasPrefix(pk.String(), KeyPrefix) {
+		return nil
+	}
+
+	message := image["envelope"]
+	if message.DataType() != lambdaevents.DataTypeString {
+		return fmt.Errorf("outbox item %s has no envelope", pk.String())
+	}
+
+	return r.send(ctx, pk.String(), image["sk"].String(), message.String())
+}
+
+// Sweep publishes the outbox items that have been pending for longer than age
+// and marks them sent. It recovers the items whose stream records were dropped
+// after their retries ran out, so age should comfortably exceed the time the
+// stream takes to retry a record.
+//
+// Every item is attempted, the errors of those that fail are joined and
+// returned so the invocation fails and the items are swept again next time.
+func (r *Relay) Sweep(ctx context.Context, age time.Duration) error {
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		IndexName:              aws.String(PendingIndex),
+		KeyConditionExpression: aws.String("#outboxStatus = :pending AND #pendingSince <= :before"),
+		ExpressionAttributeNames: map[string]string{
+			"#outboxStatus": "outboxStatus",
+			"#pendingSince": "pendingSince",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending": &types.AttributeValueMemberS{Value: string(StatusPending)},
+			":before":  &types.AttributeValueMemberN{Value: strconv.FormatInt(r.now().Add(-age).Unix(), 10)},
+		},
+	})
+
+	var errs []error
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return errors.Join(append(errs, fmt.Errorf("failed to query pending outbox items: %w", err))...)
+		}
+
+		for _, item := range page.Items {
+			if err := r.sweep(ctx, item); err != nil {
+				r.logger.ErrorContext(
+					ctx,
+					"failed to sweep outbox item",
+					slog.Any("error", err))
+
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sweep publishes a single pending outbox item read from the PendingIndex.
+func (r *Relay) sweep(ctx context.Context, item map[string]types.AttributeValue) error {
+	pk, _ := item["pk"].(*types.AttributeValueMemberS)
+	sk, _ := item["sk"].(*types.AttributeValueMemberS)
+	if pk == nil || sk == nil {
+		return errors.New("pending outbox item has no key")
+	}
+
+	message, ok := item["envelope"].(*types.AttributeValueMemberS)
+	if !ok {
+		return fmt.Errorf("outbox item %s has no envelope", pk.Value)
+	}
+
+	r.logger.WarnContext(
+		ctx,
+		"sweeping pending outbox item",
+		slog.String("pk", pk.Value))
+
+	return r.send(ctx, pk.Value, sk.Value, message.Value)
+}
+
+// send publishes the envelope of the outbox item keyed by pk and sk and marks
+// the item sent, removing it from the PendingIndex.
+func (r *Relay) send(ctx context.Context, pk string, sk string, message string) error {
+	var envelope events.Envelope
+	if err := json.Unmarshal([]byte(message), &envelope); err != nil {
+		return fmt.Errorf("failed to unmarshal outbox item %s: %w", pk, err)
+	}
+
+	ctx = events.WithCorrelationID(ctx, envelope.CorrelationID)
+
+	if err := r.publisher.PublishEnvelope(ctx, envelope); err != nil {
+		return err
+	}
+
+	now := r.now()
+
+	if _, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		},
+		UpdateExpression:    aws.String("SET #status = :sent, #sent = :now, #ttl = :ttl REMOVE #outboxStatus, #pendingSince"),
+		ConditionExpression: aws.String("attribute_exists(#pk)"),
+		ExpressionAttributeNames: map[string]string{
+			"#pk":           "pk",
+			"#status":       "status",
+			"#sent":         "sent",
+			"#ttl":          "ttl",
+			"#outboxStatus": "outboxStatus",
+			"#pendingSince": "pendingSince",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":sent": &types.AttributeValueMemberS{Value: string(StatusSent)},
+			":now":  &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+			":ttl":  &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(r.retention).Unix(), 10)},
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to mark outbox item %s sent: %w", pk, err)
+	}
+
+	r.logger.DebugContext(
+		ctx,
+		"relayed outbox item",
+		slog.String("type", string(envelope.Type)),
+		slog.String("id", envelope.ID))
+
+	return nil
+}
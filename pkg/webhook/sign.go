@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sign returns the hex encoded HMAC-SHA256 of the payload at the given
+// timestamp using secret.
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeaderValue builds the value of the SignatureHeader for a payload,
+// including a signature for each of the secrets.
+func SignatureHeaderValue(timestamp time.Time, payload []byte, secrets ...string) string {
+	parts := []string{"t=" + strconv.FormatInt(timestamp.Unix(), 10)}
+	for _, secret := range secrets {
+		parts = append(parts, "v1="+Sign(secret, timestamp, payload))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is how far a delivery's timestamp may drift from the
+// receiver's clock before it is rejected as a possible replay.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrMissingSignature is returned when a delivery has no signature header.
+	ErrMissingSignature = errors.New("webhook: missing signature")
+	// ErrInvalidHeader is returned when the signature header can't be parsed.
+	ErrInvalidHeader = errors.New("webhook: invalid signature header")
+	// ErrExpiredTimestamp is returned when the timestamp is outside the
+	// tolerance.
+	ErrExpiredTimestamp = errors.New("webhook: timestamp outside tolerance")
+	// ErrNoValidSignature is returned when none of the signatures match any of
+	// the secrets.
+	ErrNoValidSignature = errors.New("webhook: no valid signature")
+)
+
+// Verify checks the signature header against the payload. The delivery is
+// accepted if any signature in the header matches any of the secrets and the
+// timestamp is within tolerance of now. A tolerance of 0 uses
+// DefaultTolerance.
+func Verify(payload []byte, header string, tolerance time.Duration, now time.Time, secrets ...string) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+
+	timestamp, signatures, err := parseHeader(header)
+	if err != nil {
+		return err
+	}
+
+	if drift := now.Sub(timestamp).Abs(); drift > tolerance {
+		return ErrExpiredTimestamp
+	}
+
+	for _, secret := range secrets {
+		expected, _ := hex.DecodeString(Sign(secret, timestamp, payload))
+		for _, signature := range signatures {
+			if hmac.Equal(expected, signature) {
+				return nil
+			}
+		}
+	}
+
+	return ErrNoValidSignature
+}
+
+// VerifyRequest reads and verifies the body of an incoming delivery. The body
+// is returned and also restored on the request so it can be read again.
+func VerifyRequest(r *http.Request, tolerance time.Duration, secrets ...string) ([]byte, error) {
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: failed to read body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(payload))
+
+	if err := Verify(payload, r.Header.Get(SignatureHeader), tolerance, time.Now(), secrets...); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// parseHeader splits a signature header into its timestamp and v1 signatures.
+// Unknown keys are ignored so new schemes can be added without breaking
+// receivers.
+func parseHeader(header string) (time.Time, [][]byte, error) {
+	var (
+		timestamp  time.Time
+		signatures [][]byte
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return time.Time{}, nil, ErrInvalidHeader
+		}
+
+		switch key {
+		case "t":
+			unix, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return time.Time{}, nil, ErrInvalidHeader
+			}
+			timestamp = time.Unix(unix, 0)
+		case "v1":
+			signature, err := hex.DecodeString(value)
+			if err != nil {
+				return time.Time{}, nil, ErrInvalidHeader
+			}
+			signatures = append(signatures, signature)
+		}
+	}
+
+	if timestamp.IsZero() || len(signatures) == 0 {
+		return time.Time{}, nil, ErrInvalidHeader
+	}
+
+	return timestamp, signatures, nil
+}
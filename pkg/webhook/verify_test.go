@@ -0,0 +1,108 @@
+package webhook_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSecret(t *testing.T) {
+	a, err := webhook.NewSecret()
+	require.NoError(t, err)
+	b, err := webhook.NewSecret()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(a, "whsec_"))
+	assert.NotEqual(t, a, b)
+}
+
+func TestVerify(t *testing.T) {
+	var (
+		now     = time.Unix(1700000000, 0)
+		payload = []byte(`{"pageId":"123"}`)
+	)
+
+	tests := map[string]struct {
+		header  string
+		secrets []string
+		err     error
+	}{
+		"valid signature": {
+			header:  webhook.SignatureHeaderValue(now, payload, "secret"),
+			secrets: []string{"secret"},
+		},
+		"old secret during rotation": {
+			header:  webhook.SignatureHeaderValue(now, payload, "new", "old"),
+			secrets: []string{"old"},
+		},
+		"new secret during rotation": {
+			header:  webhook.SignatureHeaderValue(now, payload, "new", "old"),
+			secrets: []string{"new"},
+		},
+		"wrong secret": {
+			header:  webhook.SignatureHeaderValue(now, payload, "secret"),
+			secrets: []string{"other"},
+			err:     webhook.ErrNoValidSignature,
+		},
+		"tampered payload": {
+			header:  webhook.SignatureHeaderValue(now, []byte(`{"pageId":"456"}`), "secret"),
+			secrets: []string{"secret"},
+			err:     webhook.ErrNoValidSignature,
+		},
+		"stale timestamp": {
+			header:  webhook.SignatureHeaderValue(now.Add(-10*time.Minute), payload, "secret"),
+			secrets: []string{"secret"},
+			err:     webhook.ErrExpiredTimestamp,
+		},
+		"missing header": {
+			secrets: []string{"secret"},
+			err:     webhook.ErrMissingSignature,
+		},
+		"missing timestamp": {
+			header:  "v1=abcd",
+			secrets: []string{"secret"},
+			err:     webhook.ErrInvalidHeader,
+		},
+		"missing signature": {
+			header:  "t=1700000000",
+			secrets: []string{"secret"},
+			err:     webhook.ErrInvalidHeader,
+		},
+		"malformed signature": {
+			header:  "t=1700000000,v1=zz",
+			secrets: []string{"secret"},
+			err:     webhook.ErrInvalidHeader,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := webhook.Verify(payload, tc.header, 0, now, tc.secrets...)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	payload := []byte(`{"pageId":"123"}`)
+
+	r, err := http.NewRequest(http.MethodPost, "https://example.com/hook", bytes.NewReader(payload))
+	require.NoError(t, err)
+	r.Header.Set(webhook.SignatureHeader, webhook.SignatureHeaderValue(time.Now(), payload, "secret"))
+
+	body, err := webhook.VerifyRequest(r, 0, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, payload, body)
+
+	// The body is restored for later handlers.
+	restored, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, payload, restored)
+}
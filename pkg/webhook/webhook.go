@@ -0,0 +1,34 @@
+// Package webhook signs and verifies the webhook deliveries made by pager.
+//
+// Every delivery carries a Pager-Signature header of the form
+//
+//	t=<unix timestamp>,v1=<hex hmac-sha256>[,v1=<hex hmac-sha256>]
+//
+// The signature is an HMAC-SHA256 of "<timestamp>.<body>" keyed with the
+// endpoint's signing secret. While a secret is being rotated a delivery is
+// signed with both the old and new secrets, so receivers can switch secrets at
+// any point during the overlap window.
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	// SignatureHeader is the header a delivery's signature is sent in.
+	SignatureHeader = "Pager-Signature"
+	// secretPrefix is prepended to generated secrets so they are easy to
+	// recognise.
+	secretPrefix = "whsec_"
+)
+
+// NewSecret generates a random signing secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/caarlos0/env/v11"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/services/agency/internal/worker"
 )
 
@@ -39,7 +40,9 @@ func run(ctx context.Context) error {
 	dynamoClient := dynamodb.NewFromConfig(awsconf)
 	snsClient := sns.NewFromConfig(awsconf)
 
-	lambda.Start(worker.ProcessEvents(conf, logger, dynamoClient, snsClient))
+	publisher := events.NewPublisher(snsClient, conf.EventsTopicARN, "agency")
+
+	lambda.Start(worker.ProcessEvents(conf, logger, dynamoClient, publisher))
 
 	return nil
 }
@@ -5,13 +5,13 @@ import (
 	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 )
 
-func NewServer(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) http.Handler {
+func NewServer(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) http.Handler {
 	mux := http.NewServeMux()
 
-	addRoutes(mux, config, logger, dynamoClient, snsClient)
+	addRoutes(mux, config, logger, dynamoClient, publisher)
 
 	return mux
 }
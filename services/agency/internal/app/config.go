@@ -1,10 +1,14 @@
 package app
 
-import "log/slog"
+import (
+	"log/slog"
+	"time"
+)
 
 type Config struct {
-	LogLevel        slog.Level `env:"LOG_LEVEL"`
-	Environment     string     `env:"ENVIRONMENT"`
-	AgencyTableName string     `env:"AGENCY_TABLE_NAME"`
-	EventsTopicARN  string     `env:"EVENTS_TOPIC_ARN"`
+	LogLevel        slog.Level    `env:"LOG_LEVEL"`
+	Environment     string        `env:"ENVIRONMENT"`
+	AgencyTableName string        `env:"AGENCY_TABLE_NAME"`
+	EventsTopicARN  string        `env:"EVENTS_TOPIC_ARN"`
+	InvitationTTL   time.Duration `env:"INVITATION_TTL" envDefault:"168h"`
 }
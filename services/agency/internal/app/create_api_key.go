@@ -0,0 +1,147 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// apiKeyPrefix starts every API key so the gateway can tell keys from JWTs.
+const apiKeyPrefix = "pgr_"
+
+// createAPIKey creates an API key for an agency. The key is returned in the
+// response and can't be retrieved again, only its hash is stored.
+// The calling user must be a writer in the agency or a platform admin.
+func createAPIKey(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user     identity.User
+			agencyID = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(r.Header.Get("x-pager-userinfo")), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if role, ok := user.Memberships[agencyID]; !ok || role != identity.RoleWriter {
+			if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		req, problems, err := decodeValid[createAPIKeyRequest](r)
+		if err != nil {
+			if len(problems) > 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				if err := json.NewEncoder(w).Encode(problems); err != nil {
+					logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var (
+			now   = time.Now()
+			keyID = uuid.New().String()
+		)
+
+		key, hash, err := newAPIKey(keyID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to generate api key", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		slices.Sort(req.Scopes)
+
+		apiKey := models.APIKey{
+			PK:        fmt.Sprintf("agency#%s", agencyID),
+			SK:        fmt.Sprintf("apikey#%s", keyID),
+			Type:      models.EntityTypeAPIKey,
+			AgencyID:  agencyID,
+			Name:      req.Name,
+			Scopes:    slices.Compact(req.Scopes),
+			Created:   now,
+			CreatedBy: user.ID,
+		}
+
+		lookup := apiKey
+		lookup.PK = fmt.Sprintf("apikey#%s", keyID)
+		lookup.SK = "meta"
+		lookup.Hash = hash
+
+		var transactItems []types.TransactWriteItem
+		for _, row := range []models.APIKey{apiKey, lookup} {
+			item, err := attributevalue.MarshalMap(row)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to marshal api key", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			transactItems = append(transactItems, types.TransactWriteItem{
+				Put: &types.Put{
+					TableName:           aws.String(config.AgencyTableName),
+					Item:                item,
+					ConditionExpression: aws.String("attribute_not_exists(#pk)"),
+					ExpressionAttributeNames: map[string]string{
+						"#pk": "pk",
+					},
+				},
+			})
+		}
+
+		if _, err := client.TransactWriteItems(r.Context(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: transactItems,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to put api key", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := toAPIKeyResponse(apiKey)
+		response.Key = key
+
+		if err := encode(w, r, http.StatusCreated, response); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// newAPIKey returns a new key with the given ID and the hash the gateway
+// compares presented keys against. Keys are pgr_<keyId>_<secret>, the ID lets
+// the gateway find the key's row without storing the key.
+func newAPIKey(keyID string) (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	key := fmt.Sprintf("%s%s_%s", apiKeyPrefix, keyID, hex.EncodeToString(secret))
+	hash := sha256.Sum256([]byte(key))
+
+	return key, hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+)
+
+// deleteEndpointRegistration removes an endpoint from an agency. The removal is
+// published so the endpoint service stops delivering the agency's pages to it.
+// The calling user must be a writer in the agency or a platform admin.
+func deleteEndpointRegistration(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user       identity.User
+			agencyID   = r.PathValue("id")
+			endpointID = r.PathValue("endpointId")
+		)
+
+		if err := json.Unmarshal([]byte(r.Header.Get("x-pager-userinfo")), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if role, ok := user.Memberships[agencyID]; !ok || role != identity.RoleWriter {
+			if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		_, err := dynamoClient.DeleteItem(r.Context(), &dynamodb.DeleteItemInput{
+			TableName: aws.String(config.AgencyTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyID),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("endpoint#%s", endpointID),
+				},
+			},
+			ConditionExpression: aws.String("attribute_exists(#pk)"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk": "pk",
+			},
+		})
+
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to delete registration", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := publisher.Publish(r.Context(), events.TypeAgencyRegistrationDeleted, events.AgencyRegistrationDeleted{
+			AgencyID:   agencyID,
+			EndpointID: endpointID,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
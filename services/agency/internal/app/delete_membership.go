@@ -0,0 +1,148 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// deleteMembership removes a member from an agency. Both rows of the
+// membership are deleted together, and the removal is published so the
+// memberships cached on the user are updated.
+// An agency always keeps an active writer, removing the last of them fails with
+// a conflict.
+// The calling user must be a writer in the agency or a platform admin.
+func deleteMembership(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user     identity.User
+			agencyID = r.PathValue("id")
+			userID   = r.PathValue("userId")
+		)
+
+		if err := json.Unmarshal([]byte(r.Header.Get("x-pager-userinfo")), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if role, ok := user.Memberships[agencyID]; !ok || role != identity.RoleWriter {
+			if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		result, err := dynamoClient.GetItem(r.Context(), &dynamodb.GetItemInput{
+			TableName: aws.String(config.AgencyTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyID),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("user#%s", userID),
+				},
+			},
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get membership", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var membership models.Membership
+		if err := attributevalue.UnmarshalMap(result.Item, &membership); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal membership record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var items []types.TransactWriteItem
+		for _, key := range membershipKeys(agencyID, userID) {
+			items = append(items, types.TransactWriteItem{
+				Delete: &types.Delete{
+					TableName: aws.String(config.AgencyTableName),
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: key[0]},
+						"sk": &types.AttributeValueMemberS{Value: key[1]},
+					},
+					// The membership is only removed with the role read, so a
+					// concurrent promotion can't skip the writer check below.
+					ConditionExpression: aws.String("attribute_exists(#pk) AND #role = :role"),
+					ExpressionAttributeNames: map[string]string{
+						"#pk":   "pk",
+						"#role": "role",
+					},
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":role": &types.AttributeValueMemberS{Value: membership.Role},
+					},
+					ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+				},
+			})
+		}
+
+		if membership.Role == identity.RoleWriter {
+			check, err := otherWriterCheck(r.Context(), config, dynamoClient, agencyID, userID)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to query agency writers", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if check == nil {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			items = append(items, *check)
+		}
+
+		_, err = dynamoClient.TransactWriteItems(r.Context(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+
+		if membershipMissing(err) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// The membership's role changed, or the other writer was demoted,
+		// between our read and write.
+		if membershipChanged(err) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to delete membership", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := publisher.Publish(r.Context(), events.TypeAgencyMembershipDeleted, events.AgencyMembershipDeleted{
+			UserID:   userID,
+			AgencyID: agencyID,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func removeMember(t *testing.T, client *fakeDynamoDB, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serve(
+		t,
+		deleteMembership(Config{}, discardLogger, client, events.NewPublisher(new(fakeSNS), "topic", "agency")),
+		agencyWriter,
+		httptest.NewRequest(http.MethodDelete, "/agencies/agency-1/members/"+userID, nil),
+		map[string]string{"id": "agency-1", "userId": userID})
+}
+
+func TestDeleteMembership(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []member
+		userID  string
+		want    int
+		items   int
+	}{
+		{
+			name: "reader",
+			members: []member{
+				{"writer", identity.RoleWriter, models.MembershipStatusActive},
+				{"reader", identity.RoleReader, models.MembershipStatusActive},
+			},
+			userID: "reader",
+			want:   http.StatusNoContent,
+			items:  2,
+		},
+		{
+			name: "writer",
+			members: []member{
+				{"writer", identity.RoleWriter, models.MembershipStatusActive},
+				{"writer-2", identity.RoleWriter, models.MembershipStatusActive},
+			},
+			userID: "writer",
+			want:   http.StatusNoContent,
+			items:  3,
+		},
+		{
+			name: "last writer",
+			members: []member{
+				{"writer", identity.RoleWriter, models.MembershipStatusActive},
+				{"pending", identity.RoleWriter, models.MembershipStatusPending},
+			},
+			userID: "writer",
+			want:   http.StatusConflict,
+		},
+		{
+			name: "not found",
+			members: []member{
+				{"writer", identity.RoleWriter, models.MembershipStatusActive},
+			},
+			userID: "reader",
+			want:   http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMembershipTable(t, tt.members...)
+
+			require.Equal(t, tt.want, removeMember(t, client, tt.userID).Code)
+
+			if tt.items == 0 {
+				assert.Empty(t, client.transacts)
+				return
+			}
+			require.Len(t, client.transacts, 1)
+			assert.Len(t, client.transacts[0].TransactItems, tt.items)
+		})
+	}
+}
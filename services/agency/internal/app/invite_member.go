@@ -8,16 +8,19 @@ import (
 	"slices"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/pkg/outbox"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/invitations"
 	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
 )
 
-func inviteMember(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) http.Handler {
+// inviteMember invites a user to join an agency by email. The invitee is
+// created if they don't have a user yet, and becomes a member once they accept
+// the invitation. Invitations expire after the configured invitation TTL.
+// The calling user must be a writer in the agency or a platform admin.
+func inviteMember(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var (
 			user     identity.User
@@ -54,74 +57,45 @@ func inviteMember(config Config, logger *slog.Logger, dynamoClient *dynamodb.Cli
 
 		now := time.Now()
 
-		invitationAV, err := attributevalue.MarshalMap(models.Invitation{
+		invitation := models.Invitation{
 			PK:         fmt.Sprintf("invite#%s", req.Email),
 			SK:         fmt.Sprintf("agency#%s", agencyID),
 			Type:       models.EntityTypeInvitation,
 			Role:       req.Role,
 			Status:     models.InvitationStatusPending,
+			Expires:    now.Add(config.InvitationTTL),
 			Created:    now,
 			Modified:   now,
 			CreatedBy:  user.ID,
 			ModifiedBy: user.ID,
-		})
-
-		if err != nil {
-			logger.ErrorContext(r.Context(), "failed to marshal user agency membership", slog.Any("error", err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
 		}
 
-		_, err = dynamoClient.PutItem(r.Context(), &dynamodb.PutItemInput{
-			TableName: aws.String(config.AgencyTableName),
-			Item:      invitationAV,
+		// The event is staged in the outbox with the invitation so an invitation
+		// is never left pending without it.
+		envelope, err := publisher.Envelope(r.Context(), events.TypeUserEnsureInvite, events.UserEnsureInvite{
+			Email:    req.Email,
+			AgencyID: agencyID,
 		})
-
 		if err != nil {
-			logger.ErrorContext(r.Context(), "failed to write invitation", slog.Any("error", err))
+			logger.ErrorContext(r.Context(), "failed to create event", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		messageBody, err := json.Marshal(struct {
-			Email    string `json:"email"`
-			AgencyID string `json:"agencyId"`
-		}{
-			Email:    req.Email,
-			AgencyID: agencyID,
-		})
-
+		outboxItem, err := outbox.Put(config.AgencyTableName, envelope, now)
 		if err != nil {
-			logger.ErrorContext(r.Context(), "failed to marshal SNS message", slog.Any("error", err))
+			logger.ErrorContext(r.Context(), "failed to create event", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		if _, err = snsClient.Publish(r.Context(), &sns.PublishInput{
-			TopicArn: aws.String(config.EventsTopicARN),
-			Message:  aws.String(string(messageBody)),
-			MessageAttributes: map[string]snstypes.MessageAttributeValue{
-				"type": {
-					DataType:    aws.String("String"),
-					StringValue: aws.String("user.ensure-invite"),
-				},
-			},
-		}); err != nil {
-			logger.ErrorContext(r.Context(), "failed publish to SNS", slog.Any("error", err))
+		if err := invitations.Put(r.Context(), dynamoClient, config.AgencyTableName, invitation, nil, outboxItem); err != nil {
+			logger.ErrorContext(r.Context(), "failed to write invitation", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		if err = encode(w, r, http.StatusCreated, createInvitationResponse{
-			AgencyID:   agencyID,
-			Email:      req.Email,
-			Role:       req.Role,
-			Status:     models.InvitationStatusPending,
-			Created:    now,
-			Modified:   now,
-			CreatedBy:  user.ID,
-			ModifiedBy: user.ID,
-		}); err != nil {
+		if err = encode(w, r, http.StatusCreated, toInvitationResponse(invitation, now)); err != nil {
 			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -17,6 +18,9 @@ import (
 	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
 )
 
+// maxBatchGetItems is the most keys DynamoDB accepts in a single BatchGetItem.
+const maxBatchGetItems = 100
+
 // listAgencies returns a list of agencies the calling user is a member of.
 func listAgencies(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -158,13 +162,28 @@ func listAgencies(config Config, logger *slog.Logger, client *dynamodb.Client) h
 			}
 		}
 
+		agencyIDs := make([]string, 0, len(memberships))
+		for _, membership := range memberships {
+			agencyIDs = append(agencyIDs, strings.Split(membership.SK, "#")[1])
+		}
+
+		statuses, err := agencyStatuses(r.Context(), config, client, agencyIDs)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get agency statuses", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		response := new(listResponse[membershipResponse])
 
 		for _, membership := range memberships {
+			agencyID := strings.Split(membership.SK, "#")[1]
 			response.Results = append(response.Results, membershipResponse{
-				AgencyID: strings.Split(membership.SK, "#")[1],
-				UserID:   strings.Split(membership.PK, "#")[1],
-				Role:     membership.Role,
+				AgencyID:     agencyID,
+				UserID:       strings.Split(membership.PK, "#")[1],
+				Role:         membership.Role,
+				Status:       membership.Status,
+				AgencyStatus: statuses[agencyID],
 			})
 		}
 
@@ -180,3 +199,55 @@ func listAgencies(config Config, logger *slog.Logger, client *dynamodb.Client) h
 		}
 	})
 }
+
+// agencyStatuses returns the status of each of the agencies, keyed by agency
+// ID. Agencies that don't exist are left out.
+func agencyStatuses(ctx context.Context, config Config, client *dynamodb.Client, agencyIDs []string) (map[string]models.AgencyStatus, error) {
+	statuses := make(map[string]models.AgencyStatus, len(agencyIDs))
+
+	for chunk := range slices.Chunk(agencyIDs, maxBatchGetItems) {
+		keys := make([]map[string]types.AttributeValue, 0, len(chunk))
+		for _, agencyID := range chunk {
+			keys = append(keys, map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyID),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			})
+		}
+
+		requestItems := map[string]types.KeysAndAttributes{
+			config.AgencyTableName: {
+				Keys:                     keys,
+				ProjectionExpression:     aws.String("#pk, #status"),
+				ExpressionAttributeNames: map[string]string{"#pk": "pk", "#status": "status"},
+			},
+		}
+
+		// Keys DynamoDB couldn't get this time are returned to be requested
+		// again.
+		for len(requestItems) > 0 {
+			result, err := client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			var agencies []models.Agency
+			if err := attributevalue.UnmarshalListOfMaps(result.Responses[config.AgencyTableName], &agencies); err != nil {
+				return nil, err
+			}
+
+			for _, agency := range agencies {
+				statuses[strings.TrimPrefix(agency.PK, "agency#")] = agency.Status
+			}
+
+			requestItems = result.UnprocessedKeys
+		}
+	}
+
+	return statuses, nil
+}
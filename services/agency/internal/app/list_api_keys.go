@@ -0,0 +1,113 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// listAPIKeys returns the API keys an agency has created.
+// The calling user must be a writer in the agency or a platform admin.
+func listAPIKeys(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			err         error
+			user        identity.User
+			first       = 10
+			firstStr    = r.URL.Query().Get("first")
+			cursor      = r.URL.Query().Get("cursor")
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			agencyid    = r.PathValue("id")
+		)
+
+		if firstStr != "" {
+			first, err = strconv.Atoi(firstStr)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if role, ok := user.Memberships[agencyid]; !ok || role != identity.RoleWriter {
+			if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		queryInput := &dynamodb.QueryInput{
+			TableName:              aws.String(config.AgencyTableName),
+			Limit:                  aws.Int32(int32(first)),
+			KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk": "pk",
+				"#sk": "sk",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyid),
+				},
+				":sk": &types.AttributeValueMemberS{Value: "apikey#"},
+			},
+		}
+
+		if cursor != "" {
+			queryInput.ExclusiveStartKey = map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("apikey#%s", cursor),
+				},
+			}
+		}
+
+		result, err := client.Query(r.Context(), queryInput)
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to query api keys", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var keys []models.APIKey
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &keys); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal api key records", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := new(listResponse[apiKeyResponse])
+		for _, key := range keys {
+			response.Results = append(response.Results, toAPIKeyResponse(key))
+		}
+
+		if result.LastEvaluatedKey != nil {
+			response.NextCursor = strings.TrimPrefix(result.LastEvaluatedKey["sk"].(*types.AttributeValueMemberS).Value, "apikey#")
+			response.HasNextPage = true
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
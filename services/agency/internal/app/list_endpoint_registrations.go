@@ -0,0 +1,173 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// listEndpointRegistrations returns the endpoints registered to an agency,
+// followed by the registrations that haven't completed, whether they are
+// pending, declined, expired or failed.
+// The calling user must have a membership in the agency or be a platform admin.
+func listEndpointRegistrations(config Config, logger *slog.Logger, client dynamoDBAPI) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			err         error
+			user        identity.User
+			first       = 10
+			firstStr    = r.URL.Query().Get("first")
+			cursor      = r.URL.Query().Get("cursor")
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			agencyid    = r.PathValue("id")
+		)
+
+		if firstStr != "" {
+			first, err = strconv.Atoi(firstStr)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if _, ok := user.Memberships[agencyid]; !ok {
+			if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		response := new(listResponse[endpointRegistrationResponse])
+
+		prefix, startKey := parseRegistrationCursor(cursor)
+		for i := slices.Index(registrationPrefixes, prefix); i < len(registrationPrefixes) && first > 0; i++ {
+			prefix := registrationPrefixes[i]
+
+			queryInput := &dynamodb.QueryInput{
+				TableName:              aws.String(config.AgencyTableName),
+				Limit:                  aws.Int32(int32(first)),
+				KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+				ExpressionAttributeNames: map[string]string{
+					"#pk": "pk",
+					"#sk": "sk",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":pk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("agency#%s", agencyid),
+					},
+					":sk": &types.AttributeValueMemberS{Value: prefix},
+				},
+			}
+
+			if startKey != "" {
+				queryInput.ExclusiveStartKey = map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("agency#%s", agencyid),
+					},
+					"sk": &types.AttributeValueMemberS{
+						Value: startKey,
+					},
+				}
+				startKey = ""
+			}
+
+			result, err := client.Query(r.Context(), queryInput)
+
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to query registrations", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			var registrations []models.EndpointRegistration
+			if err := attributevalue.UnmarshalListOfMaps(result.Items, &registrations); err != nil {
+				logger.ErrorContext(r.Context(), "failed to unmarshal registration records", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			for _, registration := range registrations {
+				response.Results = append(response.Results, toEndpointRegistrationResponse(agencyid, registration))
+			}
+			first -= len(registrations)
+
+			if result.LastEvaluatedKey != nil {
+				response.NextCursor = result.LastEvaluatedKey["sk"].(*types.AttributeValueMemberS).Value
+				response.HasNextPage = true
+				break
+			}
+
+			// The page is full but the registrations under the next prefix
+			// haven't been read.
+			if first == 0 && i+1 < len(registrationPrefixes) && len(registrations) > 0 {
+				response.NextCursor = registrations[len(registrations)-1].SK
+				response.HasNextPage = true
+			}
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// registrationPrefixes are the sort key prefixes of an agency's registrations,
+// in the order they are listed. A completed registration is keyed by its
+// endpoint, any other by its registration code.
+var registrationPrefixes = []string{"endpoint#", "registration#"}
+
+// parseRegistrationCursor returns the prefix to continue listing from and the
+// sort key to start after. Cursors are the sort key of the last registration
+// listed, cursors holding only an endpoint ID continue after that endpoint.
+func parseRegistrationCursor(cursor string) (string, string) {
+	if cursor == "" {
+		return registrationPrefixes[0], ""
+	}
+
+	for _, prefix := range registrationPrefixes {
+		if strings.HasPrefix(cursor, prefix) {
+			return prefix, cursor
+		}
+	}
+
+	return registrationPrefixes[0], fmt.Sprintf("endpoint#%s", cursor)
+}
+
+func toEndpointRegistrationResponse(agencyID string, registration models.EndpointRegistration) endpointRegistrationResponse {
+	response := endpointRegistrationResponse{
+		AgencyID:   agencyID,
+		Status:     registration.Status,
+		Disabled:   registration.Disabled,
+		Created:    registration.Created,
+		Modified:   registration.Modified,
+		CreatedBy:  registration.CreatedBy,
+		ModifiedBy: registration.ModifiedBy,
+	}
+
+	if code, ok := strings.CutPrefix(registration.SK, "registration#"); ok {
+		response.RegistrationCode = code
+	} else {
+		response.EndpointID = strings.TrimPrefix(registration.SK, "endpoint#")
+	}
+
+	return response
+}
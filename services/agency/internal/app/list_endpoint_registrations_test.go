@@ -0,0 +1,97 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListEndpointRegistrations(t *testing.T) {
+	client := new(fakeDynamoDB)
+	for _, registration := range []models.EndpointRegistration{
+		{SK: "endpoint#endpoint-1", Status: models.RegistrationStatusComplete},
+		{SK: "endpoint#endpoint-2", Status: models.RegistrationStatusComplete, Disabled: true},
+		{SK: "registration#AAAA1111", Status: models.RegistrationStatusPending},
+		{SK: "registration#BBBB2222", Status: models.RegistrationStatusDeclined},
+		{SK: "registration#CCCC3333", Status: models.RegistrationStatusExpired},
+		{SK: "registration#DDDD4444", Status: "FAILED"},
+	} {
+		registration.PK = "agency#agency-1"
+		registration.Type = models.EntityTypeRegistration
+		client.put(t, registration)
+	}
+
+	// Another agency's registration.
+	client.put(t, models.EndpointRegistration{PK: "agency#agency-2", SK: "endpoint#endpoint-3"})
+
+	list := func(t *testing.T, query string) listResponse[endpointRegistrationResponse] {
+		t.Helper()
+
+		w := serve(
+			t,
+			listEndpointRegistrations(Config{}, discardLogger, client),
+			identity.User{ID: "user-1", Memberships: map[string]identity.Role{"agency-1": identity.RoleReader}},
+			httptest.NewRequest(http.MethodGet, "/agency-1/endpoints"+query, nil),
+			map[string]string{"id": "agency-1"})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response listResponse[endpointRegistrationResponse]
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return response
+	}
+
+	type listed struct {
+		endpointID, registrationCode, status string
+	}
+	listedOf := func(response listResponse[endpointRegistrationResponse]) []listed {
+		var result []listed
+		for _, registration := range response.Results {
+			result = append(result, listed{registration.EndpointID, registration.RegistrationCode, registration.Status})
+		}
+		return result
+	}
+
+	all := []listed{
+		{"endpoint-1", "", models.RegistrationStatusComplete},
+		{"endpoint-2", "", models.RegistrationStatusComplete},
+		{"", "AAAA1111", models.RegistrationStatusPending},
+		{"", "BBBB2222", models.RegistrationStatusDeclined},
+		{"", "CCCC3333", models.RegistrationStatusExpired},
+		{"", "DDDD4444", "FAILED"},
+	}
+
+	t.Run("single page", func(t *testing.T) {
+		response := list(t, "?first=10")
+		assert.Equal(t, all, listedOf(response))
+		assert.False(t, response.HasNextPage)
+	})
+
+	for _, first := range []string{"1", "2", "3", "4"} {
+		t.Run("pages of "+first, func(t *testing.T) {
+			var (
+				result []listed
+				cursor string
+			)
+			for range len(all) + 1 {
+				response := list(t, "?first="+first+"&cursor="+cursor)
+				result = append(result, listedOf(response)...)
+				if !response.HasNextPage {
+					break
+				}
+				cursor = response.NextCursor
+			}
+			assert.Equal(t, all, result)
+		})
+	}
+
+	t.Run("endpoint cursor", func(t *testing.T) {
+		// Cursors issued before registrations were listed hold an endpoint ID.
+		assert.Equal(t, all[1:], listedOf(list(t, "?cursor=endpoint-1")))
+	})
+}
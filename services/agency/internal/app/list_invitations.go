@@ -0,0 +1,190 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// listInvitations returns the invitations an agency has sent, whatever their
+// status. Invitations sent before they were listed by agency only have their
+// invite#<email> row and aren't listed until they are next written, for
+// example when they are resent or responded to.
+// The calling user must be a writer in the agency or a platform admin.
+func listInvitations(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			err         error
+			user        identity.User
+			first       = 10
+			firstStr    = r.URL.Query().Get("first")
+			cursor      = r.URL.Query().Get("cursor")
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			agencyid    = r.PathValue("id")
+		)
+
+		if firstStr != "" {
+			first, err = strconv.Atoi(firstStr)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if role, ok := user.Memberships[agencyid]; !ok || role != identity.RoleWriter {
+			if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		queryInput := &dynamodb.QueryInput{
+			TableName:              aws.String(config.AgencyTableName),
+			Limit:                  aws.Int32(int32(first)),
+			KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk": "pk",
+				"#sk": "sk",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyid),
+				},
+				":sk": &types.AttributeValueMemberS{Value: "invite#"},
+			},
+		}
+
+		if cursor != "" {
+			queryInput.ExclusiveStartKey = map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("invite#%s", cursor),
+				},
+			}
+		}
+
+		result, err := client.Query(r.Context(), queryInput)
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to query invitations", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var invitations []models.Invitation
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &invitations); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal invitation records", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var (
+			now      = time.Now()
+			response = new(listResponse[invitationResponse])
+		)
+
+		for _, invitation := range invitations {
+			response.Results = append(response.Results, toInvitationResponse(invitation, now))
+		}
+
+		if result.LastEvaluatedKey != nil {
+			response.NextCursor = strings.TrimPrefix(result.LastEvaluatedKey["sk"].(*types.AttributeValueMemberS).Value, "invite#")
+			response.HasNextPage = true
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// listUserInvitations returns the invitations sent to the calling user's email
+// address by any agency.
+func listUserInvitations(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var invitations []models.Invitation
+
+		paginator := dynamodb.NewQueryPaginator(client, &dynamodb.QueryInput{
+			TableName:              aws.String(config.AgencyTableName),
+			KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk": "pk",
+				"#sk": "sk",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("invite#%s", user.Email),
+				},
+				":sk": &types.AttributeValueMemberS{Value: "agency#"},
+			},
+		})
+
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(r.Context())
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to query invitations", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			var results []models.Invitation
+			if err := attributevalue.UnmarshalListOfMaps(page.Items, &results); err != nil {
+				logger.ErrorContext(r.Context(), "failed to unmarshal invitation records", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			invitations = append(invitations, results...)
+		}
+
+		var (
+			now     = time.Now()
+			results = make([]invitationResponse, 0, len(invitations))
+		)
+
+		for _, invitation := range invitations {
+			results = append(results, toInvitationResponse(invitation, now))
+		}
+
+		if err := json.NewEncoder(w).Encode(listResponse[invitationResponse]{
+			Results: results,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -97,13 +97,22 @@ func listMemberships(config Config, logger *slog.Logger, client *dynamodb.Client
 			}
 		}
 
+		statuses, err := agencyStatuses(r.Context(), config, client, []string{agencyid})
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get agency status", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		response := new(listResponse[membershipResponse])
 
 		for _, membership := range memberships {
 			response.Results = append(response.Results, membershipResponse{
-				AgencyID: strings.Split(membership.PK, "#")[1],
-				UserID:   strings.Split(membership.SK, "#")[1],
-				Role:     membership.Role,
+				AgencyID:     agencyid,
+				UserID:       strings.Split(membership.SK, "#")[1],
+				Role:         membership.Role,
+				Status:       membership.Status,
+				AgencyStatus: statuses[agencyid],
 			})
 		}
 
@@ -37,6 +37,23 @@ type createAgencyResponse struct {
 	ID string `json:"id"`
 }
 
+// updateAgencyRequest represents a request to update an agency. Fields that
+// are nil are left unchanged.
+type updateAgencyRequest struct {
+	Name *string `json:"name"`
+}
+
+// valid returns a map of validation problems for the request.
+func (r updateAgencyRequest) valid(ctx context.Context) map[string]string {
+	problems := make(map[string]string)
+
+	if r.Name != nil && *r.Name == "" {
+		problems["name"] = "name cannot be empty"
+	}
+
+	return problems
+}
+
 // agencyResponse represents a single agency by ID.
 type agencyResponse struct {
 	ID         string    `json:"id"`
@@ -65,16 +82,36 @@ func toAgencyResponse(agency models.Agency) agencyResponse {
 // MEMBERSHIP
 //-----------------------------------------------------------------------------
 
-// membershipResponse represents a single membership by ID.
+// membershipResponse represents a single membership by ID. AgencyStatus is the
+// status of the agency itself, members of an inactive agency can't page it.
 type membershipResponse struct {
-	AgencyID   string        `json:"agencyId"`
-	UserID     string        `json:"userId"`
-	Role       identity.Role `json:"role"`
-	Status     string        `json:"status"`
-	Created    time.Time     `json:"created"`
-	Modified   time.Time     `json:"modified"`
-	CreatedBy  string        `json:"createdBy"`
-	ModifiedBy string        `json:"modifiedBy"`
+	AgencyID     string        `json:"agencyId"`
+	UserID       string        `json:"userId"`
+	Role         identity.Role `json:"role"`
+	Status       string        `json:"status"`
+	AgencyStatus string        `json:"agencyStatus"`
+	Created      time.Time     `json:"created"`
+	Modified     time.Time     `json:"modified"`
+	CreatedBy    string        `json:"createdBy"`
+	ModifiedBy   string        `json:"modifiedBy"`
+}
+
+// updateMembershipRequest represents a request to change the role of a member.
+type updateMembershipRequest struct {
+	Role identity.Role `json:"role"`
+}
+
+// valid returns a map of validation problems for the request.
+func (r updateMembershipRequest) valid(ctx context.Context) map[string]string {
+	problems := make(map[string]string)
+
+	validRoles := []identity.Role{identity.RoleReader, identity.RoleWriter}
+
+	if !slices.Contains(validRoles, r.Role) {
+		problems["role"] = fmt.Sprintf("role must be one of %s", strings.Join(validRoles, ", "))
+	}
+
+	return problems
 }
 
 //-----------------------------------------------------------------------------
@@ -108,19 +145,58 @@ func (r createInvitationRequest) valid(ctx context.Context) map[string]string {
 	return problems
 }
 
-// createInvitationResponse represents a response to a request to create a new
-// invitation.
-type createInvitationResponse struct {
+// invitationResponse represents a single invitation.
+type invitationResponse struct {
 	AgencyID   string        `json:"agencyId"`
 	Email      string        `json:"email"`
 	Role       identity.Role `json:"role"`
 	Status     string        `json:"status"`
+	UserID     string        `json:"userId,omitempty"`
+	Expires    *time.Time    `json:"expires,omitempty"`
 	Created    time.Time     `json:"created"`
 	Modified   time.Time     `json:"modified"`
 	CreatedBy  string        `json:"createdBy"`
 	ModifiedBy string        `json:"modifiedBy"`
 }
 
+// toInvitationResponse converts either row of an invitation to a response. A
+// pending invitation that has expired is reported as expired even before it is
+// marked as such.
+func toInvitationResponse(invitation models.Invitation, now time.Time) invitationResponse {
+	var email, agencyID string
+	for _, key := range []string{invitation.PK, invitation.SK} {
+		if value, ok := strings.CutPrefix(key, "invite#"); ok {
+			email = value
+		}
+		if value, ok := strings.CutPrefix(key, "agency#"); ok {
+			agencyID = value
+		}
+	}
+
+	status := invitation.Status
+	if status == models.InvitationStatusPending && invitation.Expired(now) {
+		status = models.InvitationStatusExpired
+	}
+
+	response := invitationResponse{
+		AgencyID:   agencyID,
+		Email:      email,
+		Role:       invitation.Role,
+		Status:     status,
+		UserID:     invitation.UserID,
+		Created:    invitation.Created,
+		Modified:   invitation.Modified,
+		CreatedBy:  invitation.CreatedBy,
+		ModifiedBy: invitation.ModifiedBy,
+	}
+
+	if !invitation.Expires.IsZero() {
+		response.Expires = &invitation.Expires
+	}
+
+	return response
+}
+
 //-----------------------------------------------------------------------------
 // ENDPOINT REGISTRATION
 //-----------------------------------------------------------------------------
@@ -153,6 +229,80 @@ type registerEndpointResponse struct {
 	ModifiedBy       string    `json:"modifiedBy"`
 }
 
+// endpointRegistrationResponse represents an endpoint registered to an agency.
+type endpointRegistrationResponse struct {
+	AgencyID   string `json:"agencyId"`
+	EndpointID string `json:"endpointId,omitempty"`
+	// RegistrationCode is set until the registration completes and the
+	// endpoint is known.
+	RegistrationCode string    `json:"registrationCode,omitempty"`
+	Status           string    `json:"status"`
+	Disabled         bool      `json:"disabled"`
+	Created          time.Time `json:"created"`
+	Modified         time.Time `json:"modified"`
+	CreatedBy        string    `json:"createdBy"`
+	ModifiedBy       string    `json:"modifiedBy"`
+}
+
+//-----------------------------------------------------------------------------
+// API KEY
+//-----------------------------------------------------------------------------
+
+// createAPIKeyRequest represents a request to create a new API key.
+type createAPIKeyRequest struct {
+	Name   string               `json:"name"`
+	Scopes []models.APIKeyScope `json:"scopes"`
+}
+
+// valid returns a map of validation problems for the request.
+func (r createAPIKeyRequest) valid(ctx context.Context) map[string]string {
+	problems := make(map[string]string)
+
+	if r.Name == "" {
+		problems["name"] = "name is required"
+	}
+
+	validScopes := []models.APIKeyScope{models.APIKeyScopePagesCreate, models.APIKeyScopePagesRead}
+
+	if len(r.Scopes) == 0 {
+		problems["scopes"] = "at least one scope is required"
+	}
+
+	for _, scope := range r.Scopes {
+		if !slices.Contains(validScopes, scope) {
+			problems["scopes"] = fmt.Sprintf("scopes must be one of %s", strings.Join(validScopes, ", "))
+		}
+	}
+
+	return problems
+}
+
+// apiKeyResponse represents an API key. The key itself is only returned when
+// it is created.
+type apiKeyResponse struct {
+	ID        string               `json:"id"`
+	AgencyID  string               `json:"agencyId"`
+	Name      string               `json:"name"`
+	Scopes    []models.APIKeyScope `json:"scopes"`
+	Key       string               `json:"key,omitempty"`
+	LastUsed  *time.Time           `json:"lastUsed,omitempty"`
+	Created   time.Time            `json:"created"`
+	CreatedBy string               `json:"createdBy"`
+}
+
+// toAPIKeyResponse converts the agency row of an API key to a response.
+func toAPIKeyResponse(key models.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:        strings.TrimPrefix(key.SK, "apikey#"),
+		AgencyID:  key.AgencyID,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+		LastUsed:  key.LastUsed,
+		Created:   key.Created,
+		CreatedBy: key.CreatedBy,
+	}
+}
+
 //-----------------------------------------------------------------------------
 // LIST RESPONSE
 //-----------------------------------------------------------------------------
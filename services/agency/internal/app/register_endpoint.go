@@ -10,13 +10,18 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/pkg/outbox"
 	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
 )
 
-func registerEndpoint(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) http.Handler {
+// registerEndpoint starts registering an endpoint to the agency using the
+// registration code its owner shared. The registration stays pending until the
+// owner of the endpoint approves it, unless the owner registered it themselves.
+// The calling user must be a writer in the agency.
+func registerEndpoint(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var (
 			user     identity.User
@@ -68,42 +73,40 @@ func registerEndpoint(config Config, logger *slog.Logger, dynamoClient *dynamodb
 			return
 		}
 
-		_, err = dynamoClient.PutItem(r.Context(), &dynamodb.PutItemInput{
-			TableName: aws.String(config.AgencyTableName),
-			Item:      registrationAV,
+		// The event is staged in the outbox with the registration so a
+		// registration is never left pending without it.
+		envelope, err := publisher.Envelope(r.Context(), events.TypeEndpointResolve, events.EndpointResolve{
+			AgencyID:         agencyID,
+			RegistrationCode: req.RegistrationCode,
+			RequestedBy:      user.ID,
 		})
-
 		if err != nil {
-			logger.ErrorContext(r.Context(), "failed to write registration", slog.Any("error", err))
+			logger.ErrorContext(r.Context(), "failed to create event", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		messageBody, err := json.Marshal(struct {
-			AgencyID         string `json:"agencyId"`
-			RegistrationCode string `json:"registrationCode"`
-		}{
-			AgencyID:         agencyID,
-			RegistrationCode: req.RegistrationCode,
-		})
-
+		outboxItem, err := outbox.Put(config.AgencyTableName, envelope, now)
 		if err != nil {
-			logger.ErrorContext(r.Context(), "failed to marshal SNS message", slog.Any("error", err))
+			logger.ErrorContext(r.Context(), "failed to create event", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		if _, err = snsClient.Publish(r.Context(), &sns.PublishInput{
-			TopicArn: aws.String(config.EventsTopicARN),
-			Message:  aws.String(string(messageBody)),
-			MessageAttributes: map[string]snstypes.MessageAttributeValue{
-				"type": {
-					DataType:    aws.String("String"),
-					StringValue: aws.String("endpoint.resolve"),
+		_, err = dynamoClient.TransactWriteItems(r.Context(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{
+					Put: &types.Put{
+						TableName: aws.String(config.AgencyTableName),
+						Item:      registrationAV,
+					},
 				},
+				outboxItem,
 			},
-		}); err != nil {
-			logger.ErrorContext(r.Context(), "failed to publish SNS message", slog.Any("error", err))
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to write registration", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
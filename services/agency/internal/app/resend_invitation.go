@@ -0,0 +1,96 @@
+package app
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/invitations"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// resendInvitation sends an invitation again, for example once it has expired
+// or the invitee lost it. The invitation is made pending again with a new
+// expiry and the invitee is ensured again.
+// The calling user must be a writer in the agency or a platform admin.
+func resendInvitation(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user     identity.User
+			agencyID = r.PathValue("id")
+			email    = r.PathValue("email")
+		)
+
+		if err := json.Unmarshal([]byte(r.Header.Get("x-pager-userinfo")), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if role, ok := user.Memberships[agencyID]; !ok || role != identity.RoleWriter {
+			if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		invitation, err := invitations.Get(r.Context(), dynamoClient, config.AgencyTableName, email, agencyID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get invitation", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if invitation == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if !slices.Contains([]models.InvitationStatus{
+			models.InvitationStatusPending,
+			models.InvitationStatusExpired,
+			models.InvitationStatusFailed,
+		}, invitation.Status) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		now := time.Now()
+
+		resent := *invitation
+		resent.Status = models.InvitationStatusPending
+		resent.Expires = now.Add(config.InvitationTTL)
+		resent.Modified = now
+		resent.ModifiedBy = user.ID
+
+		if err := invitations.Put(r.Context(), dynamoClient, config.AgencyTableName, resent, invitation); err != nil {
+			if invitations.Changed(err) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+
+			logger.ErrorContext(r.Context(), "failed to resend invitation", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := publisher.Publish(r.Context(), events.TypeUserEnsureInvite, events.UserEnsureInvite{
+			Email:    email,
+			AgencyID: agencyID,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, toInvitationResponse(resent, now)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resend(t *testing.T, client *fakeDynamoDB, sns *fakeSNS) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serve(
+		t,
+		resendInvitation(Config{InvitationTTL: 24 * time.Hour}, discardLogger, client, events.NewPublisher(sns, "topic", "agency")),
+		agencyWriter,
+		httptest.NewRequest(http.MethodPost, "/agencies/agency-1/invitations/invitee@pager.com/resend", nil),
+		map[string]string{"id": "agency-1", "email": "invitee@pager.com"})
+}
+
+func TestResendInvitation(t *testing.T) {
+	var (
+		client = newInvitationTable(t, models.InvitationStatusExpired, time.Now().Add(-time.Hour))
+		sns    = new(fakeSNS)
+		before = time.Now()
+	)
+
+	require.Equal(t, http.StatusOK, resend(t, client, sns).Code)
+
+	// The invitation is pending again with a new expiry.
+	require.Len(t, client.transacts, 1)
+	resent := transactPut[models.Invitation](t, client.transacts[0], 0)
+	assert.Equal(t, models.InvitationStatusPending, resent.Status)
+	assert.WithinRange(t, resent.Expires, before.Add(24*time.Hour), time.Now().Add(24*time.Hour))
+
+	// The invitee is ensured again.
+	require.Len(t, sns.envelopes, 1)
+	assert.Equal(t, events.TypeUserEnsureInvite, sns.envelopes[0].Type)
+	assert.JSONEq(t, `{"email": "invitee@pager.com", "agencyId": "agency-1"}`, string(sns.envelopes[0].Payload))
+}
+
+func TestResendInvitationSettled(t *testing.T) {
+	var (
+		client = newInvitationTable(t, models.InvitationStatusComplete, time.Now().Add(time.Hour))
+		sns    = new(fakeSNS)
+	)
+
+	assert.Equal(t, http.StatusConflict, resend(t, client, sns).Code)
+	assert.Empty(t, client.transacts)
+	assert.Empty(t, sns.envelopes)
+}
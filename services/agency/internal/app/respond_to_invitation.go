@@ -0,0 +1,164 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/invitations"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// respondToInvitation accepts or declines an invitation on behalf of the
+// invitee. Accepting creates the membership in the agency with the role the
+// invitation was sent with. An invitation that has expired can't be accepted
+// or declined, it is marked as expired and 410 is returned. An invitee who is
+// already a member can't accept, their membership is kept and 409 is returned.
+// The calling user must be the invitee, the invitation is found by their
+// email address.
+func respondToInvitation(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher, decision models.InvitationStatus) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user     identity.User
+			agencyID = r.PathValue("agencyId")
+		)
+
+		if err := json.Unmarshal([]byte(r.Header.Get("x-pager-userinfo")), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		invitation, err := invitations.Get(r.Context(), dynamoClient, config.AgencyTableName, user.Email, agencyID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get invitation", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if invitation == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if invitation.Status != models.InvitationStatusPending {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		now := time.Now()
+
+		updated := *invitation
+		updated.UserID = user.ID
+		updated.Modified = now
+		updated.ModifiedBy = user.ID
+
+		if invitation.Expired(now) {
+			updated.Status = models.InvitationStatusExpired
+			if err := invitations.Put(r.Context(), dynamoClient, config.AgencyTableName, updated, invitation); err != nil && !invitations.Changed(err) {
+				logger.ErrorContext(r.Context(), "failed to expire invitation", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+
+		updated.Status = decision
+
+		var items []types.TransactWriteItem
+		if decision == models.InvitationStatusComplete {
+			membership := models.Membership{
+				PK:         fmt.Sprintf("user#%s", user.ID),
+				SK:         fmt.Sprintf("agency#%s", agencyID),
+				Type:       models.EntityTypeMembership,
+				Role:       invitation.Role,
+				Status:     models.MembershipStatusActive,
+				Created:    now,
+				Modified:   now,
+				CreatedBy:  invitation.CreatedBy,
+				ModifiedBy: user.ID,
+			}
+
+			membershipInverse := membership
+			membershipInverse.PK, membershipInverse.SK = membership.SK, membership.PK
+
+			for _, row := range []models.Membership{membership, membershipInverse} {
+				av, err := attributevalue.MarshalMap(row)
+				if err != nil {
+					logger.ErrorContext(r.Context(), "failed to marshal membership", slog.Any("error", err))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				// A membership the invitee already has is kept, it may have a
+				// different role to the invitation's.
+				items = append(items, types.TransactWriteItem{
+					Put: &types.Put{
+						TableName:           aws.String(config.AgencyTableName),
+						Item:                av,
+						ConditionExpression: aws.String("attribute_not_exists(#pk)"),
+						ExpressionAttributeNames: map[string]string{
+							"#pk": "pk",
+						},
+					},
+				})
+			}
+		}
+
+		if err := invitations.Put(r.Context(), dynamoClient, config.AgencyTableName, updated, invitation, items...); err != nil {
+			// The invitation was revoked, resent or responded to between our
+			// read and write, or the invitee is already a member.
+			if invitations.Changed(err) || membershipExists(err) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+
+			logger.ErrorContext(r.Context(), "failed to respond to invitation", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if decision == models.InvitationStatusComplete {
+			if err := publisher.Publish(r.Context(), events.TypeAgencyMembershipCreated, events.AgencyMembershipCreated{
+				UserID:   user.ID,
+				AgencyID: agencyID,
+				Role:     invitation.Role,
+			}); err != nil {
+				logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := encode(w, r, http.StatusOK, toInvitationResponse(updated, now)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// membershipExists returns true if accepting an invitation failed because the
+// invitee already has a membership in the agency. The membership rows follow
+// the two invitation rows in the transaction.
+func membershipExists(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || len(canceled.CancellationReasons) <= 2 {
+		return false
+	}
+
+	return slices.ContainsFunc(canceled.CancellationReasons[2:], func(reason types.CancellationReason) bool {
+		return aws.ToString(reason.Code) == "ConditionalCheckFailed"
+	})
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var invitee = identity.User{ID: "invitee", Email: "invitee@pager.com"}
+
+// newInvitationTable returns a table holding an invitation of invitee to
+// agency-1 as a reader, with the given status and expiry.
+func newInvitationTable(t *testing.T, status models.InvitationStatus, expires time.Time) *fakeDynamoDB {
+	t.Helper()
+
+	client := new(fakeDynamoDB)
+	client.put(t, models.Invitation{
+		PK:        "invite#invitee@pager.com",
+		SK:        "agency#agency-1",
+		Type:      models.EntityTypeInvitation,
+		Status:    status,
+		Role:      identity.RoleReader,
+		Expires:   expires,
+		Modified:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		CreatedBy: "writer",
+	})
+	return client
+}
+
+// transactPut returns the item put by the transaction at index.
+func transactPut[T any](t *testing.T, input *dynamodb.TransactWriteItemsInput, index int) T {
+	t.Helper()
+
+	require.Greater(t, len(input.TransactItems), index)
+	put := input.TransactItems[index].Put
+	require.NotNil(t, put)
+
+	var item T
+	require.NoError(t, attributevalue.UnmarshalMap(put.Item, &item))
+	return item
+}
+
+func respond(t *testing.T, client *fakeDynamoDB, sns *fakeSNS, decision models.InvitationStatus) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serve(
+		t,
+		respondToInvitation(Config{}, discardLogger, client, events.NewPublisher(sns, "topic", "agency"), decision),
+		invitee,
+		httptest.NewRequest(http.MethodPost, "/invitations/agency-1/accept", nil),
+		map[string]string{"agencyId": "agency-1"})
+}
+
+func TestRespondToInvitationAccept(t *testing.T) {
+	var (
+		client = newInvitationTable(t, models.InvitationStatusPending, time.Now().Add(time.Hour))
+		sns    = new(fakeSNS)
+	)
+
+	w := respond(t, client, sns, models.InvitationStatusComplete)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response invitationResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, models.InvitationStatusComplete, response.Status)
+	assert.Equal(t, "invitee", response.UserID)
+
+	// Both invitation rows and both membership rows are written together.
+	require.Len(t, client.transacts, 1)
+	transact := client.transacts[0]
+	require.Len(t, transact.TransactItems, 4)
+
+	assert.Equal(t, models.InvitationStatusComplete, transactPut[models.Invitation](t, transact, 0).Status)
+	assert.Equal(t, "agency#agency-1", transactPut[models.Invitation](t, transact, 1).PK)
+
+	for i, key := range [][2]string{{"user#invitee", "agency#agency-1"}, {"agency#agency-1", "user#invitee"}} {
+		membership := transactPut[models.Membership](t, transact, i+2)
+		assert.Equal(t, key, [2]string{membership.PK, membership.SK})
+		assert.Equal(t, identity.RoleReader, membership.Role)
+		assert.Equal(t, models.MembershipStatusActive, membership.Status)
+
+		// An existing membership isn't replaced.
+		assert.Equal(t, "attribute_not_exists(#pk)", aws.ToString(transact.TransactItems[i+2].Put.ConditionExpression))
+	}
+
+	require.Len(t, sns.envelopes, 1)
+	assert.Equal(t, events.TypeAgencyMembershipCreated, sns.envelopes[0].Type)
+}
+
+func TestRespondToInvitationAlreadyMember(t *testing.T) {
+	var (
+		client = newInvitationTable(t, models.InvitationStatusPending, time.Now().Add(time.Hour))
+		sns    = new(fakeSNS)
+	)
+
+	client.transactWriteItems = func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+		return nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+				{Code: aws.String("ConditionalCheckFailed")},
+			},
+		}
+	}
+
+	assert.Equal(t, http.StatusConflict, respond(t, client, sns, models.InvitationStatusComplete).Code)
+	assert.Empty(t, sns.envelopes)
+}
+
+func TestRespondToInvitationChanged(t *testing.T) {
+	var (
+		client = newInvitationTable(t, models.InvitationStatusPending, time.Now().Add(time.Hour))
+		sns    = new(fakeSNS)
+	)
+
+	// The invitation was revoked after it was read.
+	client.transactWriteItems = func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+		return nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("ConditionalCheckFailed")},
+				{Code: aws.String("None")},
+				{Code: aws.String("None")},
+				{Code: aws.String("None")},
+			},
+		}
+	}
+
+	assert.Equal(t, http.StatusConflict, respond(t, client, sns, models.InvitationStatusComplete).Code)
+	assert.Empty(t, sns.envelopes)
+}
+
+func TestRespondToInvitationDecline(t *testing.T) {
+	var (
+		client = newInvitationTable(t, models.InvitationStatusPending, time.Now().Add(time.Hour))
+		sns    = new(fakeSNS)
+	)
+
+	w := respond(t, client, sns, models.InvitationStatusDeclined)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Only the invitation rows are written.
+	require.Len(t, client.transacts, 1)
+	require.Len(t, client.transacts[0].TransactItems, 2)
+	assert.Equal(t, models.InvitationStatusDeclined, transactPut[models.Invitation](t, client.transacts[0], 0).Status)
+	assert.Empty(t, sns.envelopes)
+}
+
+func TestRespondToInvitationExpired(t *testing.T) {
+	for _, decision := range []models.InvitationStatus{models.InvitationStatusComplete, models.InvitationStatusDeclined} {
+		t.Run(decision, func(t *testing.T) {
+			var (
+				client = newInvitationTable(t, models.InvitationStatusPending, time.Now().Add(-time.Hour))
+				sns    = new(fakeSNS)
+			)
+
+			assert.Equal(t, http.StatusGone, respond(t, client, sns, decision).Code)
+
+			// The invitation is marked as expired.
+			require.Len(t, client.transacts, 1)
+			require.Len(t, client.transacts[0].TransactItems, 2)
+			assert.Equal(t, models.InvitationStatusExpired, transactPut[models.Invitation](t, client.transacts[0], 0).Status)
+			assert.Empty(t, sns.envelopes)
+		})
+	}
+}
+
+func TestRespondToInvitationNotPending(t *testing.T) {
+	var (
+		client = newInvitationTable(t, models.InvitationStatusRevoked, time.Now().Add(time.Hour))
+		sns    = new(fakeSNS)
+	)
+
+	assert.Equal(t, http.StatusConflict, respond(t, client, sns, models.InvitationStatusComplete).Code)
+	assert.Empty(t, client.transacts)
+}
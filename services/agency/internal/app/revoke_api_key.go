@@ -0,0 +1,117 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+)
+
+// revokeAPIKey deletes an agency's API key, the gateway stops accepting it
+// immediately.
+// The calling user must be a writer in the agency or a platform admin.
+func revokeAPIKey(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user     identity.User
+			agencyID = r.PathValue("id")
+			keyID    = r.PathValue("keyId")
+		)
+
+		if err := json.Unmarshal([]byte(r.Header.Get("x-pager-userinfo")), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if role, ok := user.Memberships[agencyID]; !ok || role != identity.RoleWriter {
+			if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		// Both rows are conditioned on belonging to the agency so a key can't be
+		// revoked through another agency.
+		_, err := client.TransactWriteItems(r.Context(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{
+					Delete: &types.Delete{
+						TableName: aws.String(config.AgencyTableName),
+						Key: map[string]types.AttributeValue{
+							"pk": &types.AttributeValueMemberS{
+								Value: fmt.Sprintf("agency#%s", agencyID),
+							},
+							"sk": &types.AttributeValueMemberS{
+								Value: fmt.Sprintf("apikey#%s", keyID),
+							},
+						},
+						ConditionExpression: aws.String("attribute_exists(#pk)"),
+						ExpressionAttributeNames: map[string]string{
+							"#pk": "pk",
+						},
+					},
+				},
+				{
+					Delete: &types.Delete{
+						TableName: aws.String(config.AgencyTableName),
+						Key: map[string]types.AttributeValue{
+							"pk": &types.AttributeValueMemberS{
+								Value: fmt.Sprintf("apikey#%s", keyID),
+							},
+							"sk": &types.AttributeValueMemberS{
+								Value: "meta",
+							},
+						},
+						ConditionExpression: aws.String("#agencyId = :agencyId"),
+						ExpressionAttributeNames: map[string]string{
+							"#agencyId": "agencyId",
+						},
+						ExpressionAttributeValues: map[string]types.AttributeValue{
+							":agencyId": &types.AttributeValueMemberS{
+								Value: agencyID,
+							},
+						},
+					},
+				},
+			},
+		})
+
+		if apiKeyMissing(err) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to delete api key", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// apiKeyMissing returns true if a transaction on the rows of an API key was
+// canceled because the key doesn't exist in the agency.
+func apiKeyMissing(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return false
+	}
+
+	for _, reason := range canceled.CancellationReasons {
+		if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/invitations"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// revokeInvitation withdraws an invitation that hasn't been accepted so it can
+// no longer be accepted.
+// The calling user must be a writer in the agency or a platform admin.
+func revokeInvitation(config Config, logger *slog.Logger, client dynamoDBAPI) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user     identity.User
+			agencyID = r.PathValue("id")
+			email    = r.PathValue("email")
+		)
+
+		if err := json.Unmarshal([]byte(r.Header.Get("x-pager-userinfo")), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if role, ok := user.Memberships[agencyID]; !ok || role != identity.RoleWriter {
+			if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		invitation, err := invitations.Get(r.Context(), client, config.AgencyTableName, email, agencyID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get invitation", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if invitation == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// Accepted and declined invitations are settled, revoking an accepted
+		// invitation is done by removing the member instead.
+		if !slices.Contains([]models.InvitationStatus{
+			models.InvitationStatusPending,
+			models.InvitationStatusExpired,
+			models.InvitationStatusFailed,
+		}, invitation.Status) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		now := time.Now()
+
+		revoked := *invitation
+		revoked.Status = models.InvitationStatusRevoked
+		revoked.Modified = now
+		revoked.ModifiedBy = user.ID
+
+		if err := invitations.Put(r.Context(), client, config.AgencyTableName, revoked, invitation); err != nil {
+			if invitations.Changed(err) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+
+			logger.ErrorContext(r.Context(), "failed to revoke invitation", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, toInvitationResponse(revoked, now)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var agencyWriter = identity.User{ID: "writer", Memberships: map[string]identity.Role{"agency-1": identity.RoleWriter}}
+
+func revoke(t *testing.T, client *fakeDynamoDB, user identity.User) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serve(
+		t,
+		revokeInvitation(Config{}, discardLogger, client),
+		user,
+		httptest.NewRequest(http.MethodDelete, "/agencies/agency-1/invitations/invitee@pager.com", nil),
+		map[string]string{"id": "agency-1", "email": "invitee@pager.com"})
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	for _, status := range []models.InvitationStatus{
+		models.InvitationStatusPending,
+		models.InvitationStatusExpired,
+		models.InvitationStatusFailed,
+	} {
+		t.Run(status, func(t *testing.T) {
+			client := newInvitationTable(t, status, time.Now().Add(time.Hour))
+
+			require.Equal(t, http.StatusOK, revoke(t, client, agencyWriter).Code)
+
+			// The invitation is only revoked if it hasn't changed since it was
+			// read.
+			require.Len(t, client.transacts, 1)
+			transact := client.transacts[0]
+			require.Len(t, transact.TransactItems, 2)
+			assert.Equal(t, "#modified = :modified", aws.ToString(transact.TransactItems[0].Put.ConditionExpression))
+			assert.Equal(t, models.InvitationStatusRevoked, transactPut[models.Invitation](t, transact, 0).Status)
+			assert.Equal(t, models.InvitationStatusRevoked, transactPut[models.Invitation](t, transact, 1).Status)
+		})
+	}
+}
+
+func TestRevokeInvitationSettled(t *testing.T) {
+	for _, status := range []models.InvitationStatus{
+		models.InvitationStatusComplete,
+		models.InvitationStatusDeclined,
+		models.InvitationStatusRevoked,
+	} {
+		t.Run(status, func(t *testing.T) {
+			client := newInvitationTable(t, status, time.Now().Add(time.Hour))
+
+			assert.Equal(t, http.StatusConflict, revoke(t, client, agencyWriter).Code)
+			assert.Empty(t, client.transacts)
+		})
+	}
+}
+
+func TestRevokeInvitationNotWriter(t *testing.T) {
+	client := newInvitationTable(t, models.InvitationStatusPending, time.Now().Add(time.Hour))
+
+	reader := identity.User{ID: "reader", Memberships: map[string]identity.Role{"agency-1": identity.RoleReader}}
+	assert.Equal(t, http.StatusForbidden, revoke(t, client, reader).Code)
+	assert.Empty(t, client.transacts)
+}
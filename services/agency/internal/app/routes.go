@@ -6,16 +6,37 @@ import (
 	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
 )
 
-func addRoutes(mux *http.ServeMux, config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) {
+func addRoutes(mux *http.ServeMux, config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) {
 	mux.Handle(fmt.Sprintf("GET /%s", config.Environment), listAgencies(config, logger, dynamoClient))
 	mux.Handle(fmt.Sprintf("GET /%s/{id}", config.Environment), readAgency(config, logger, dynamoClient))
 	mux.Handle(fmt.Sprintf("GET /%s/{id}/members", config.Environment), listMemberships(config, logger, dynamoClient))
 
 	mux.Handle(fmt.Sprintf("POST /%s", config.Environment), createAgency(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("PATCH /%s/{id}", config.Environment), updateAgency(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/deactivate", config.Environment), setAgencyStatus(config, logger, dynamoClient, publisher, models.AgencyStatusInactive))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/reactivate", config.Environment), setAgencyStatus(config, logger, dynamoClient, publisher, models.AgencyStatusActive))
 
-	mux.Handle(fmt.Sprintf("POST /%s/{id}/invite-member", config.Environment), inviteMember(config, logger, dynamoClient, snsClient))
-	mux.Handle(fmt.Sprintf("POST /%s/{id}/register-endpoint", config.Environment), registerEndpoint(config, logger, dynamoClient, snsClient))
+	mux.Handle(fmt.Sprintf("PATCH /%s/{id}/members/{userId}", config.Environment), updateMembership(config, logger, dynamoClient, publisher))
+	mux.Handle(fmt.Sprintf("DELETE /%s/{id}/members/{userId}", config.Environment), deleteMembership(config, logger, dynamoClient, publisher))
+
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/invite-member", config.Environment), inviteMember(config, logger, dynamoClient, publisher))
+	mux.Handle(fmt.Sprintf("GET /%s/{id}/invitations", config.Environment), listInvitations(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/invitations/{email}/revoke", config.Environment), revokeInvitation(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/invitations/{email}/resend", config.Environment), resendInvitation(config, logger, dynamoClient, publisher))
+
+	mux.Handle(fmt.Sprintf("GET /%s/invitations", config.Environment), listUserInvitations(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("POST /%s/invitations/{agencyId}/accept", config.Environment), respondToInvitation(config, logger, dynamoClient, publisher, models.InvitationStatusComplete))
+	mux.Handle(fmt.Sprintf("POST /%s/invitations/{agencyId}/decline", config.Environment), respondToInvitation(config, logger, dynamoClient, publisher, models.InvitationStatusDeclined))
+
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/register-endpoint", config.Environment), registerEndpoint(config, logger, dynamoClient, publisher))
+	mux.Handle(fmt.Sprintf("GET /%s/{id}/endpoints", config.Environment), listEndpointRegistrations(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("DELETE /%s/{id}/endpoints/{endpointId}", config.Environment), deleteEndpointRegistration(config, logger, dynamoClient, publisher))
+
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/api-keys", config.Environment), createAPIKey(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("GET /%s/{id}/api-keys", config.Environment), listAPIKeys(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("DELETE /%s/{id}/api-keys/{keyId}", config.Environment), revokeAPIKey(config, logger, dynamoClient))
 }
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// setAgencyStatus deactivates or reactivates an agency. While an agency is
+// inactive it can't be paged and nothing is delivered to its endpoints, its
+// members and registrations are kept so reactivating it restores it as it was.
+// The calling user must be a platform admin.
+func setAgencyStatus(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher, status models.AgencyStatus) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			agencyid    = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		now := time.Now()
+
+		result, err := dynamoClient.UpdateItem(r.Context(), &dynamodb.UpdateItemInput{
+			TableName: aws.String(config.AgencyTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+			ConditionExpression: aws.String("attribute_exists(#pk) AND #status <> :status"),
+			UpdateExpression:    aws.String("SET #status = :status, #modified = :modified, #modifiedBy = :modifiedBy"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk":         "pk",
+				"#status":     "status",
+				"#modified":   "modified",
+				"#modifiedBy": "modifiedBy",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":status":     &types.AttributeValueMemberS{Value: status},
+				":modified":   &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+				":modifiedBy": &types.AttributeValueMemberS{Value: user.ID},
+			},
+			ReturnValues:                        types.ReturnValueAllNew,
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		})
+
+		// Either the agency doesn't exist or it already has the status.
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			if conditionFailed.Item == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to update agency status", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var agency models.Agency
+		if err := attributevalue.UnmarshalMap(result.Attributes, &agency); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal agency record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		eventType := events.TypeAgencyReactivated
+		if status == models.AgencyStatusInactive {
+			eventType = events.TypeAgencyDeactivated
+		}
+
+		if err := publisher.Publish(r.Context(), eventType, events.AgencyDeactivated{
+			AgencyID:   agencyid,
+			Status:     status,
+			Modified:   now,
+			ModifiedBy: user.ID,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, toAgencyResponse(agency)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var platformAdmin = identity.User{ID: "admin", Entitlements: []identity.Entitlement{identity.EntitlementPlatformAdmin}}
+
+func setStatus(t *testing.T, client *fakeDynamoDB, sns *fakeSNS, status models.AgencyStatus, user identity.User) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serve(
+		t,
+		setAgencyStatus(Config{}, discardLogger, client, events.NewPublisher(sns, "topic", "agency"), status),
+		user,
+		httptest.NewRequest(http.MethodPost, "/agencies/agency-1/deactivate", nil),
+		map[string]string{"id": "agency-1"})
+}
+
+func TestSetAgencyStatus(t *testing.T) {
+	tests := []struct {
+		status    models.AgencyStatus
+		eventType events.Type
+	}{
+		{status: models.AgencyStatusInactive, eventType: events.TypeAgencyDeactivated},
+		{status: models.AgencyStatusActive, eventType: events.TypeAgencyReactivated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			var (
+				client = new(fakeDynamoDB)
+				sns    = new(fakeSNS)
+			)
+
+			client.updateItem = func(params *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+				attributes, err := attributevalue.MarshalMap(models.Agency{PK: "agency#agency-1", SK: "meta", Status: tt.status})
+				return &dynamodb.UpdateItemOutput{Attributes: attributes}, err
+			}
+
+			w := setStatus(t, client, sns, tt.status, platformAdmin)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			require.Len(t, client.updates, 1)
+			update := client.updates[0]
+			assert.Equal(t, "attribute_exists(#pk) AND #status <> :status", aws.ToString(update.ConditionExpression))
+			assert.Equal(t, &types.AttributeValueMemberS{Value: tt.status}, update.ExpressionAttributeValues[":status"])
+
+			require.Len(t, sns.envelopes, 1)
+			assert.Equal(t, tt.eventType, sns.envelopes[0].Type)
+
+			message, err := events.DecodePayload[events.AgencyDeactivated](sns.envelopes[0])
+			require.NoError(t, err)
+			assert.Equal(t, "agency-1", message.AgencyID)
+			assert.Equal(t, tt.status, message.Status)
+			assert.Equal(t, "admin", message.ModifiedBy)
+			assert.False(t, message.Modified.IsZero())
+		})
+	}
+}
+
+func TestSetAgencyStatusUnchanged(t *testing.T) {
+	var (
+		client = new(fakeDynamoDB)
+		sns    = new(fakeSNS)
+	)
+
+	// The agency already has the status.
+	client.updateItem = func(params *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+		item, err := attributevalue.MarshalMap(models.Agency{PK: "agency#agency-1", SK: "meta", Status: models.AgencyStatusInactive})
+		require.NoError(t, err)
+		return nil, &types.ConditionalCheckFailedException{Item: item}
+	}
+
+	assert.Equal(t, http.StatusConflict, setStatus(t, client, sns, models.AgencyStatusInactive, platformAdmin).Code)
+	assert.Empty(t, sns.envelopes)
+}
+
+func TestSetAgencyStatusNotFound(t *testing.T) {
+	var (
+		client = new(fakeDynamoDB)
+		sns    = new(fakeSNS)
+	)
+
+	client.updateItem = func(params *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+
+	assert.Equal(t, http.StatusNotFound, setStatus(t, client, sns, models.AgencyStatusInactive, platformAdmin).Code)
+	assert.Empty(t, sns.envelopes)
+}
+
+func TestSetAgencyStatusNotAdmin(t *testing.T) {
+	var (
+		client = new(fakeDynamoDB)
+		sns    = new(fakeSNS)
+	)
+
+	assert.Equal(t, http.StatusForbidden, setStatus(t, client, sns, models.AgencyStatusInactive, identity.User{ID: "user-1"}).Code)
+	assert.Empty(t, client.updates)
+	assert.Empty(t, sns.envelopes)
+}
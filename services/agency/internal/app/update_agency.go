@@ -0,0 +1,120 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// updateAgency changes the settings of an agency. Fields omitted from the
+// request are left as they are.
+// The calling user must be a writer in the agency or a platform admin.
+func updateAgency(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			agencyid    = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		role, ok := user.Memberships[agencyid]
+		if (!ok || role != identity.RoleWriter) && !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		req, problems, err := decodeValid[updateAgencyRequest](r)
+		if err != nil {
+			if len(problems) > 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				if err := json.NewEncoder(w).Encode(problems); err != nil {
+					logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var (
+			set   = []string{"#modified = :modified", "#modifiedBy = :modifiedBy"}
+			names = map[string]string{
+				"#pk":         "pk",
+				"#modified":   "modified",
+				"#modifiedBy": "modifiedBy",
+			}
+			values = map[string]types.AttributeValue{
+				":modified":   &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339Nano)},
+				":modifiedBy": &types.AttributeValueMemberS{Value: user.ID},
+			}
+		)
+
+		if req.Name != nil {
+			set = append(set, "#name = :name")
+			names["#name"] = "name"
+			values[":name"] = &types.AttributeValueMemberS{Value: *req.Name}
+		}
+
+		result, err := client.UpdateItem(r.Context(), &dynamodb.UpdateItemInput{
+			TableName: aws.String(config.AgencyTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+			ConditionExpression:       aws.String("attribute_exists(#pk)"),
+			UpdateExpression:          aws.String("SET " + strings.Join(set, ", ")),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			ReturnValues:              types.ReturnValueAllNew,
+		})
+
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to update agency", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var agency models.Agency
+		if err := attributevalue.UnmarshalMap(result.Attributes, &agency); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal agency record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, toAgencyResponse(agency)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
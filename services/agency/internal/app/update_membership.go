@@ -0,0 +1,300 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// updateMembership changes the role of a member of an agency. Both the
+// agency#<id>/user#<id> row and its user#<id>/agency#<id> inverse are updated
+// together, and the change is published so the memberships cached on the user
+// are updated.
+// An agency always keeps an active writer, demoting the last of them fails with
+// a conflict.
+// The calling user must be a writer in the agency or a platform admin.
+func updateMembership(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user     identity.User
+			agencyID = r.PathValue("id")
+			userID   = r.PathValue("userId")
+		)
+
+		if err := json.Unmarshal([]byte(r.Header.Get("x-pager-userinfo")), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if role, ok := user.Memberships[agencyID]; !ok || role != identity.RoleWriter {
+			if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		req, problems, err := decodeValid[updateMembershipRequest](r)
+		if err != nil {
+			if len(problems) > 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				if err := json.NewEncoder(w).Encode(problems); err != nil {
+					logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		result, err := dynamoClient.GetItem(r.Context(), &dynamodb.GetItemInput{
+			TableName: aws.String(config.AgencyTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyID),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("user#%s", userID),
+				},
+			},
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get membership", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var membership models.Membership
+		if err := attributevalue.UnmarshalMap(result.Item, &membership); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal membership record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now()
+
+		var items []types.TransactWriteItem
+		for _, key := range membershipKeys(agencyID, userID) {
+			items = append(items, types.TransactWriteItem{
+				Update: &types.Update{
+					TableName: aws.String(config.AgencyTableName),
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: key[0]},
+						"sk": &types.AttributeValueMemberS{Value: key[1]},
+					},
+					// The role is only changed from the one read, so a
+					// concurrent promotion can't skip the writer check below.
+					// The rows are returned if it fails, to tell a changed
+					// membership from a removed one.
+					ConditionExpression: aws.String("attribute_exists(#pk) AND #role = :previousRole"),
+					UpdateExpression:    aws.String("SET #role = :role, #modified = :modified, #modifiedBy = :modifiedBy"),
+					ExpressionAttributeNames: map[string]string{
+						"#pk":         "pk",
+						"#role":       "role",
+						"#modified":   "modified",
+						"#modifiedBy": "modifiedBy",
+					},
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":role":         &types.AttributeValueMemberS{Value: req.Role},
+						":previousRole": &types.AttributeValueMemberS{Value: membership.Role},
+						":modified":     &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+						":modifiedBy":   &types.AttributeValueMemberS{Value: user.ID},
+					},
+					ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+				},
+			})
+		}
+
+		if membership.Role == identity.RoleWriter && req.Role != identity.RoleWriter {
+			check, err := otherWriterCheck(r.Context(), config, dynamoClient, agencyID, userID)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to query agency writers", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if check == nil {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			items = append(items, *check)
+		}
+
+		_, err = dynamoClient.TransactWriteItems(r.Context(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+
+		// The membership was removed between our read and write.
+		if membershipMissing(err) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// The membership's role changed, or the other writer was demoted,
+		// between our read and write.
+		if membershipChanged(err) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to update membership", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := publisher.Publish(r.Context(), events.TypeAgencyMembershipUpdated, events.AgencyMembershipUpdated{
+			UserID:   userID,
+			AgencyID: agencyID,
+			Role:     req.Role,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, membershipResponse{
+			AgencyID:   agencyID,
+			UserID:     userID,
+			Role:       req.Role,
+			Status:     membership.Status,
+			Created:    membership.Created,
+			Modified:   now,
+			CreatedBy:  membership.CreatedBy,
+			ModifiedBy: user.ID,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// membershipKeys returns the pk and sk of both rows of a membership.
+func membershipKeys(agencyID, userID string) [][2]string {
+	return [][2]string{
+		{fmt.Sprintf("agency#%s", agencyID), fmt.Sprintf("user#%s", userID)},
+		{fmt.Sprintf("user#%s", userID), fmt.Sprintf("agency#%s", agencyID)},
+	}
+}
+
+// membershipMissing returns true if a transaction on the rows of a membership,
+// written as its first two items, was canceled because the membership doesn't
+// exist.
+func membershipMissing(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || len(canceled.CancellationReasons) < 2 {
+		return false
+	}
+
+	return slices.ContainsFunc(canceled.CancellationReasons[:2], func(reason types.CancellationReason) bool {
+		return conditionCheckFailed(reason) && reason.Item == nil
+	})
+}
+
+// membershipChanged returns true if a transaction on the rows of a membership,
+// written as its first two items, was canceled because the role of the
+// membership changed or the writer checked by otherWriterCheck is no longer an
+// active writer.
+func membershipChanged(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || len(canceled.CancellationReasons) < 2 {
+		return false
+	}
+
+	reasons := canceled.CancellationReasons
+	return slices.ContainsFunc(reasons[:2], func(reason types.CancellationReason) bool {
+		return conditionCheckFailed(reason) && reason.Item != nil
+	}) || slices.ContainsFunc(reasons[2:], conditionCheckFailed)
+}
+
+func conditionCheckFailed(reason types.CancellationReason) bool {
+	return aws.ToString(reason.Code) == "ConditionalCheckFailed"
+}
+
+// otherWriterCheck returns a condition check that an active writer of the
+// agency other than the user is still one, so the user can be demoted or
+// removed without leaving the agency without a writer. It returns nil if the
+// agency has no other active writer.
+func otherWriterCheck(ctx context.Context, config Config, client dynamoDBAPI, agencyID, userID string) (*types.TransactWriteItem, error) {
+	var exclusiveStartKey map[string]types.AttributeValue
+
+	for {
+		result, err := client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(config.AgencyTableName),
+			KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk": "pk",
+				"#sk": "sk",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", agencyID),
+				},
+				":sk": &types.AttributeValueMemberS{Value: "user#"},
+			},
+			ExclusiveStartKey: exclusiveStartKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var memberships []models.Membership
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &memberships); err != nil {
+			return nil, err
+		}
+
+		for _, membership := range memberships {
+			if membership.SK == fmt.Sprintf("user#%s", userID) ||
+				membership.Role != identity.RoleWriter ||
+				membership.Status != models.MembershipStatusActive {
+				continue
+			}
+
+			return &types.TransactWriteItem{
+				ConditionCheck: &types.ConditionCheck{
+					TableName: aws.String(config.AgencyTableName),
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: membership.PK},
+						"sk": &types.AttributeValueMemberS{Value: membership.SK},
+					},
+					ConditionExpression: aws.String("#role = :writer AND #status = :active"),
+					ExpressionAttributeNames: map[string]string{
+						"#role":   "role",
+						"#status": "status",
+					},
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":writer": &types.AttributeValueMemberS{Value: identity.RoleWriter},
+						":active": &types.AttributeValueMemberS{Value: models.MembershipStatusActive},
+					},
+				},
+			}, nil
+		}
+
+		if result.LastEvaluatedKey == nil {
+			return nil, nil
+		}
+		exclusiveStartKey = result.LastEvaluatedKey
+	}
+}
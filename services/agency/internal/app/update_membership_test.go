@@ -0,0 +1,151 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type member struct {
+	userID string
+	role   identity.Role
+	status models.MembershipStatus
+}
+
+// newMembershipTable returns a table holding both rows of each membership in
+// agency-1.
+func newMembershipTable(t *testing.T, members ...member) *fakeDynamoDB {
+	t.Helper()
+
+	client := new(fakeDynamoDB)
+	for _, m := range members {
+		for _, key := range membershipKeys("agency-1", m.userID) {
+			client.put(t, models.Membership{
+				PK:     key[0],
+				SK:     key[1],
+				Type:   models.EntityTypeMembership,
+				Status: m.status,
+				Role:   m.role,
+			})
+		}
+	}
+	return client
+}
+
+// membershipCanceled returns the error of a transaction canceled because the
+// condition of the item at index failed, with the item if it exists.
+func membershipCanceled(n, index int, item map[string]types.AttributeValue) error {
+	reasons := make([]types.CancellationReason, n)
+	for i := range reasons {
+		reasons[i].Code = aws.String("None")
+	}
+	reasons[index] = types.CancellationReason{Code: aws.String("ConditionalCheckFailed"), Item: item}
+	return &types.TransactionCanceledException{CancellationReasons: reasons}
+}
+
+func updateRole(t *testing.T, client *fakeDynamoDB, userID string, role identity.Role) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serve(
+		t,
+		updateMembership(Config{}, discardLogger, client, events.NewPublisher(new(fakeSNS), "topic", "agency")),
+		agencyWriter,
+		httptest.NewRequest(http.MethodPatch, "/agencies/agency-1/members/"+userID, strings.NewReader(`{"role": "`+role+`"}`)),
+		map[string]string{"id": "agency-1", "userId": userID})
+}
+
+func TestUpdateMembershipLastWriter(t *testing.T) {
+	client := newMembershipTable(t,
+		member{"writer", identity.RoleWriter, models.MembershipStatusActive},
+		member{"inactive", identity.RoleWriter, models.MembershipStatusInactive},
+		member{"reader", identity.RoleReader, models.MembershipStatusActive})
+
+	assert.Equal(t, http.StatusConflict, updateRole(t, client, "writer", identity.RoleReader).Code)
+	assert.Empty(t, client.transacts)
+}
+
+func TestUpdateMembershipDemoteWriter(t *testing.T) {
+	client := newMembershipTable(t,
+		member{"writer", identity.RoleWriter, models.MembershipStatusActive},
+		member{"writer-2", identity.RoleWriter, models.MembershipStatusActive})
+
+	require.Equal(t, http.StatusOK, updateRole(t, client, "writer", identity.RoleReader).Code)
+
+	// The other writer is checked to still be one as the writer is demoted.
+	require.Len(t, client.transacts, 1)
+	items := client.transacts[0].TransactItems
+	require.Len(t, items, 3)
+	require.NotNil(t, items[2].ConditionCheck)
+	assert.Equal(t, "agency#agency-1|user#writer-2", itemKey(items[2].ConditionCheck.Key))
+	assert.Equal(t, "#role = :writer AND #status = :active", aws.ToString(items[2].ConditionCheck.ConditionExpression))
+}
+
+func TestUpdateMembershipConcurrent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  func(params *dynamodb.TransactWriteItemsInput) error
+		want int
+	}{
+		{
+			name: "other writer demoted",
+			err: func(params *dynamodb.TransactWriteItemsInput) error {
+				return membershipCanceled(len(params.TransactItems), 2, nil)
+			},
+			want: http.StatusConflict,
+		},
+		{
+			name: "role changed",
+			err: func(params *dynamodb.TransactWriteItemsInput) error {
+				return membershipCanceled(len(params.TransactItems), 0, map[string]types.AttributeValue{
+					"role": &types.AttributeValueMemberS{Value: identity.RoleReader},
+				})
+			},
+			want: http.StatusConflict,
+		},
+		{
+			name: "removed",
+			err: func(params *dynamodb.TransactWriteItemsInput) error {
+				return membershipCanceled(len(params.TransactItems), 0, nil)
+			},
+			want: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMembershipTable(t,
+				member{"writer", identity.RoleWriter, models.MembershipStatusActive},
+				member{"writer-2", identity.RoleWriter, models.MembershipStatusActive})
+			client.transactWriteItems = func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+				return nil, tt.err(params)
+			}
+
+			assert.Equal(t, tt.want, updateRole(t, client, "writer", identity.RoleReader).Code)
+		})
+	}
+}
+
+func TestUpdateMembershipPromoteReader(t *testing.T) {
+	client := newMembershipTable(t,
+		member{"writer", identity.RoleWriter, models.MembershipStatusActive},
+		member{"reader", identity.RoleReader, models.MembershipStatusActive})
+
+	require.Equal(t, http.StatusOK, updateRole(t, client, "reader", identity.RoleWriter).Code)
+
+	// Only a writer being demoted needs another writer.
+	require.Len(t, client.transacts, 1)
+	items := client.transacts[0].TransactItems
+	require.Len(t, items, 2)
+	assert.Equal(t, &types.AttributeValueMemberS{Value: identity.RoleReader}, items[0].Update.ExpressionAttributeValues[":previousRole"])
+	assert.Empty(t, client.queries)
+}
@@ -0,0 +1,109 @@
+// Package invitations reads and writes the invitations in the agency table for
+// both the API and the event handlers.
+package invitations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// DynamoDBAPI is the part of the DynamoDB client used to read and write
+// invitations.
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+// Get reads the invitation of email to an agency, returning nil if there isn't
+// one.
+func Get(ctx context.Context, client DynamoDBAPI, tableName, email, agencyID string) (*models.Invitation, error) {
+	result, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("invite#%s", email),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("agency#%s", agencyID),
+			},
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var invitation models.Invitation
+	if err := attributevalue.UnmarshalMap(result.Item, &invitation); err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// Put writes both rows of an invitation along with any other items that must
+// change with it. When replacing an invitation that was read earlier the write
+// only succeeds if it hasn't been modified since, see Changed.
+func Put(ctx context.Context, client DynamoDBAPI, tableName string, invitation models.Invitation, previous *models.Invitation, items ...types.TransactWriteItem) error {
+	invitationAV, err := attributevalue.MarshalMap(invitation)
+	if err != nil {
+		return err
+	}
+
+	inverseAV, err := attributevalue.MarshalMap(invitation.Inverse())
+	if err != nil {
+		return err
+	}
+
+	put := &types.Put{
+		TableName: aws.String(tableName),
+		Item:      invitationAV,
+	}
+
+	if previous != nil {
+		modifiedAV, err := attributevalue.Marshal(previous.Modified)
+		if err != nil {
+			return err
+		}
+
+		put.ConditionExpression = aws.String("#modified = :modified")
+		put.ExpressionAttributeNames = map[string]string{"#modified": "modified"}
+		put.ExpressionAttributeValues = map[string]types.AttributeValue{":modified": modifiedAV}
+	}
+
+	_, err = client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: append([]types.TransactWriteItem{
+			{Put: put},
+			{
+				Put: &types.Put{
+					TableName: aws.String(tableName),
+					Item:      inverseAV,
+				},
+			},
+		}, items...),
+	})
+
+	return err
+}
+
+// Changed returns true if Put failed because the invitation was modified after
+// it was read.
+func Changed(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || len(canceled.CancellationReasons) == 0 {
+		return false
+	}
+
+	return aws.ToString(canceled.CancellationReasons[0].Code) == "ConditionalCheckFailed"
+}
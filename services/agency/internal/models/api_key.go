@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type APIKeyScope = string
+
+const (
+	// APIKeyScopePagesCreate allows a key to create pages for its agency.
+	APIKeyScopePagesCreate APIKeyScope = "pages:create"
+	// APIKeyScopePagesRead allows a key to read its agency's pages.
+	APIKeyScopePagesRead APIKeyScope = "pages:read"
+)
+
+// APIKey represents a key a system such as a CAD uses to call the API on
+// behalf of an agency. Each key is stored twice, under
+// agency#<id>/apikey#<keyId> so an agency can list its keys and under
+// apikey#<keyId>/meta so the gateway can look a key up when it is used. Only
+// the lookup row holds the key's hash, the key itself is never stored.
+type APIKey struct {
+	PK       string        `dynamodbav:"pk"`
+	SK       string        `dynamodbav:"sk"`
+	Type     EntityType    `dynamodbav:"type"`
+	AgencyID string        `dynamodbav:"agencyId"`
+	Name     string        `dynamodbav:"name"`
+	Scopes   []APIKeyScope `dynamodbav:"scopes"`
+	Hash     string        `dynamodbav:"hash,omitempty"`
+	// LastUsed is recorded on the agency row by the gateway, at most once a
+	// minute.
+	LastUsed  *time.Time `dynamodbav:"lastUsed,omitempty"`
+	Created   time.Time  `dynamodbav:"created"`
+	CreatedBy string     `dynamodbav:"createdBy"`
+}
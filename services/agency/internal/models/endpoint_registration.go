@@ -17,8 +17,11 @@ type EndpointRegistration struct {
 	Type       EntityType         `dynamodbav:"type"`
 	Status     RegistrationStatus `dynamodbav:"status"`
 	EndpointID string             `dynamodbav:"endpointId"`
-	Created    time.Time          `dynamodbav:"created"`
-	Modified   time.Time          `dynamodbav:"modified"`
-	CreatedBy  string             `dynamodbav:"createdBy"`
-	ModifiedBy string             `dynamodbav:"modifiedBy"`
+	// Disabled mirrors the endpoint, it is kept in sync by endpoint.updated
+	// events.
+	Disabled   bool      `dynamodbav:"disabled,omitempty"`
+	Created    time.Time `dynamodbav:"created"`
+	Modified   time.Time `dynamodbav:"modified"`
+	CreatedBy  string    `dynamodbav:"createdBy"`
+	ModifiedBy string    `dynamodbav:"modifiedBy"`
 }
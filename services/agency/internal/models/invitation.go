@@ -13,17 +13,39 @@ const (
 	InvitationStatusComplete InvitationStatus = "COMPLETE"
 	InvitationStatusDeclined InvitationStatus = "DECLINED"
 	InvitationStatusExpired  InvitationStatus = "EXPIRED"
+	InvitationStatusRevoked  InvitationStatus = "REVOKED"
+	InvitationStatusFailed   InvitationStatus = "FAILED"
 )
 
-// Invitation represents an invitation to join an agency.
+// Invitation represents an invitation to join an agency. Each invitation is
+// stored twice, under invite#<email>/agency#<id> so an invitee can find their
+// invitations and under agency#<id>/invite#<email> so an agency can list the
+// invitations it has sent.
 type Invitation struct {
-	PK         string           `dynamodbav:"pk"`
-	SK         string           `dynamodbav:"sk"`
-	Type       EntityType       `dynamodbav:"type"`
-	Status     InvitationStatus `dynamodbav:"status"`
-	Role       identity.Role    `dynamodbav:"role"`
-	Created    time.Time        `dynamodbav:"created"`
-	Modified   time.Time        `dynamodbav:"modified"`
-	CreatedBy  string           `dynamodbav:"createdBy"`
-	ModifiedBy string           `dynamodbav:"modifiedBy"`
+	PK     string           `dynamodbav:"pk"`
+	SK     string           `dynamodbav:"sk"`
+	Type   EntityType       `dynamodbav:"type"`
+	Status InvitationStatus `dynamodbav:"status"`
+	Role   identity.Role    `dynamodbav:"role"`
+	// UserID is the ID of the invitee, known once their user has been
+	// ensured.
+	UserID string `dynamodbav:"userId,omitempty"`
+	// Expires is when the invitation can no longer be accepted. Invitations
+	// sent before expiry was introduced have a zero value and never expire.
+	Expires    time.Time `dynamodbav:"expires"`
+	Created    time.Time `dynamodbav:"created"`
+	Modified   time.Time `dynamodbav:"modified"`
+	CreatedBy  string    `dynamodbav:"createdBy"`
+	ModifiedBy string    `dynamodbav:"modifiedBy"`
+}
+
+// Expired returns true if the invitation can no longer be accepted at now.
+func (i Invitation) Expired(now time.Time) bool {
+	return !i.Expires.IsZero() && now.After(i.Expires)
+}
+
+// Inverse returns the other row of the invitation.
+func (i Invitation) Inverse() Invitation {
+	i.PK, i.SK = i.SK, i.PK
+	return i
 }
@@ -7,4 +7,6 @@ const (
 	EntityTypeMembership   EntityType = "MEMBERSHIP"
 	EntityTypeInvitation   EntityType = "INVITATION"
 	EntityTypeRegistration EntityType = "REGISTRATION"
+	EntityTypeAPIKey       EntityType = "API_KEY"
+	EntityTypeUserState    EntityType = "USER_STATE"
 )
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+)
+
+// UserState is this service's copy of the status of a user, kept in step with
+// the user service by the user.deactivated and user.reactivated events. It is
+// stored under pk user#<id> and sk status, alongside the user's memberships.
+type UserState struct {
+	PK         string          `dynamodbav:"pk"`
+	SK         string          `dynamodbav:"sk"`
+	Type       EntityType      `dynamodbav:"type"`
+	Status     identity.Status `dynamodbav:"status"`
+	Modified   time.Time       `dynamodbav:"modified"`
+	ModifiedBy string          `dynamodbav:"modifiedBy"`
+	// StatusModified is when the status changed in unix nanoseconds. Events
+	// delivered out of order are told apart by comparing it, which the
+	// formatted modified time can't be relied on for.
+	StatusModified int64 `dynamodbav:"statusModified"`
+}
@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDynamoDB records the requests made to it. Each request is answered by the
+// matching function if one is set, and with an empty output otherwise.
+type fakeDynamoDB struct {
+	deleteItem         func(params *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error)
+	getItem            func(params *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error)
+	putItem            func(params *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
+	query              func(params *dynamodb.QueryInput) (*dynamodb.QueryOutput, error)
+	transactWriteItems func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error)
+	updateItem         func(params *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error)
+
+	deletes   []*dynamodb.DeleteItemInput
+	puts      []*dynamodb.PutItemInput
+	queries   []*dynamodb.QueryInput
+	transacts []*dynamodb.TransactWriteItemsInput
+	updates   []*dynamodb.UpdateItemInput
+}
+
+func (f *fakeDynamoDB) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.deletes = append(f.deletes, params)
+	if f.deleteItem != nil {
+		return f.deleteItem(params)
+	}
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if f.getItem != nil {
+		return f.getItem(params)
+	}
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.puts = append(f.puts, params)
+	if f.putItem != nil {
+		return f.putItem(params)
+	}
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	f.queries = append(f.queries, params)
+	if f.query != nil {
+		return f.query(params)
+	}
+	return &dynamodb.QueryOutput{}, nil
+}
+
+func (f *fakeDynamoDB) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	f.transacts = append(f.transacts, params)
+	if f.transactWriteItems != nil {
+		return f.transactWriteItems(params)
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func (f *fakeDynamoDB) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.updates = append(f.updates, params)
+	if f.updateItem != nil {
+		return f.updateItem(params)
+	}
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+// fakeSNS records the events published to it.
+type fakeSNS struct {
+	envelopes []events.Envelope
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	var envelope events.Envelope
+	if err := json.Unmarshal([]byte(aws.ToString(params.Message)), &envelope); err != nil {
+		return nil, err
+	}
+
+	f.envelopes = append(f.envelopes, envelope)
+	return &sns.PublishOutput{}, nil
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newEnvelope returns the envelope of an event with the given payload.
+func newEnvelope(t *testing.T, eventType events.Type, payload any) events.Envelope {
+	t.Helper()
+
+	envelope, err := events.NewPublisher(nil, "topic", "test").Envelope(context.Background(), eventType, payload)
+	require.NoError(t, err)
+	return envelope
+}
@@ -9,29 +9,19 @@ import (
 	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
 	"log/slog"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 )
 
 // finalizeRegistration finalizes an endpoint registration.
-func finalizeRegistration(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) func(context.Context, events.SNSEntity, int) error {
-	type message struct {
-		RegistrationCode string `json:"registrationCode"`
-		AgencyID         string `json:"agencyId"`
-		EndpointId       string `json:"endpointId"`
-	}
-
-	logAndHandleError := eventProcessorErrorHandler(config, logger, snsClient, evtRegistrationCreateFailed)
-
-	return func(ctx context.Context, record events.SNSEntity, retryCount int) error {
-		var message message
-
-		if err := json.Unmarshal([]byte(record.Message), &message); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to create registration", message, err)
+func finalizeRegistration(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.EndpointResolved](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to create registration: %w", err)
 		}
 
 		readPendingRegistrationResult, err := dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
@@ -47,30 +37,30 @@ func finalizeRegistration(config Config, logger *slog.Logger, dynamoClient *dyna
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to create registration", message, err)
+			return fmt.Errorf("failed to create registration: %w", err)
 		}
 
 		if readPendingRegistrationResult.Item == nil {
-			return logAndHandleError(ctx, retryCount, "failed to create registration", message, errors.New("registration doesn't exist"))
+			return errors.New("registration doesn't exist")
 		}
 
 		var pendingRegistration models.EndpointRegistration
 		if err := attributevalue.UnmarshalMap(readPendingRegistrationResult.Item, &pendingRegistration); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to create registration", message, err)
+			return fmt.Errorf("failed to create registration: %w", err)
 		}
 
 		if pendingRegistration.Status != models.RegistrationStatusPending {
-			return logAndHandleError(ctx, retryCount, "failed to create registration", message, errors.New("registration is not pending"))
+			return errors.New("registration is not pending")
 		}
 
 		// Mirror the pending registration into a new finalRegistration model to avoid any mutation of the original.
 		finalRegistration := pendingRegistration
 		finalRegistration.Status = models.RegistrationStatusComplete
-		finalRegistration.SK = fmt.Sprintf("endpoint#%s", message.EndpointId)
+		finalRegistration.SK = fmt.Sprintf("endpoint#%s", message.EndpointID)
 
 		finalRegistrationAV, err := attributevalue.MarshalMap(finalRegistration)
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to create registration", message, err)
+			return fmt.Errorf("failed to create registration: %w", err)
 		}
 
 		// When we finalize a registration we replace registrationcode sort key on the record with an endpoint
@@ -88,30 +78,57 @@ func finalizeRegistration(config Config, logger *slog.Logger, dynamoClient *dyna
 				},
 			},
 		}); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to create registration", message, err)
+			return fmt.Errorf("failed to create registration: %w", err)
 		}
 
 		if _, err := dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
 			TableName: aws.String(config.AgencyTableName),
 			Item:      finalRegistrationAV,
 		}); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to create registration", message, err)
+			return fmt.Errorf("failed to create registration: %w", err)
 		}
 
-		if _, err := snsClient.Publish(ctx, &sns.PublishInput{
-			TopicArn: aws.String(config.EventsTopicARN),
-			Message:  aws.String(fmt.Sprintf(`{"endpointId": "%s", "agencyId": "%s"}`, message.EndpointId, message.AgencyID)),
-			MessageAttributes: map[string]snstypes.MessageAttributeValue{
-				"type": {
-					DataType:    aws.String("String"),
-					StringValue: aws.String(evtRegistrationCreated),
-				},
-			},
-		}); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to create registration", message, err)
+		created, err := publisher.Envelope(ctx, events.TypeAgencyRegistrationCreated, events.AgencyRegistrationCreated{
+			EndpointID: message.EndpointID,
+			AgencyID:   message.AgencyID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create registration: %w", err)
+		}
+
+		if err := publisher.PublishEnvelope(ctx, created); err != nil {
+			return fmt.Errorf("failed to create registration: %w", err)
+		}
+
+		logger.DebugContext(ctx, "published event", slog.String("type", string(events.TypeAgencyRegistrationCreated)))
+
+		// The pending registration is gone, a redelivery couldn't finalize it
+		// again. The published event is recorded for replayRegistration
+		// instead.
+		return consumer.SetResult(ctx, created)
+	}
+}
+
+// replayRegistration publishes the event finalizeRegistration published for a
+// redelivered endpoint.resolved event again, with the same ID, so a redrive
+// still reaches the endpoint service and consumers that already handled it
+// skip it. Events finalized without a recorded result are skipped.
+func replayRegistration(logger *slog.Logger, publisher *events.Publisher) consumer.ReplayFunc {
+	return func(ctx context.Context, envelope events.Envelope, entry consumer.Entry) error {
+		if len(entry.Result) == 0 {
+			return nil
+		}
+
+		var created events.Envelope
+		if err := json.Unmarshal(entry.Result, &created); err != nil {
+			return fmt.Errorf("failed to replay registration: %w", err)
+		}
+
+		if err := publisher.PublishEnvelope(ctx, created); err != nil {
+			return fmt.Errorf("failed to replay registration: %w", err)
 		}
 
-		logger.DebugContext(ctx, "published event", slog.String("type", evtRegistrationCreated))
+		logger.DebugContext(ctx, "replayed event", slog.String("type", string(created.Type)))
 
 		return nil
 	}
@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	lambdaevents "github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryLedger is a consumer.Ledger held in memory.
+type memoryLedger struct {
+	entries map[string]consumer.Entry
+}
+
+func (l *memoryLedger) Claim(ctx context.Context, key string, messageID string) (consumer.Entry, bool, error) {
+	if entry, ok := l.entries[key]; ok {
+		return entry, false, nil
+	}
+	l.entries[key] = consumer.Entry{Status: consumer.StatusProcessing, MessageID: messageID}
+	return consumer.Entry{}, true, nil
+}
+
+func (l *memoryLedger) Complete(ctx context.Context, key string, result json.RawMessage) error {
+	entry := l.entries[key]
+	entry.Status = consumer.StatusSucceeded
+	entry.Result = result
+	l.entries[key] = entry
+	return nil
+}
+
+func (l *memoryLedger) Release(ctx context.Context, key string) error {
+	delete(l.entries, key)
+	return nil
+}
+
+// newRecord returns the SQS record of the envelope delivered through SNS.
+func newRecord(t *testing.T, messageID string, envelope events.Envelope) lambdaevents.SQSMessage {
+	t.Helper()
+
+	message, err := json.Marshal(envelope)
+	require.NoError(t, err)
+
+	body, err := json.Marshal(lambdaevents.SNSEntity{
+		Message: string(message),
+		MessageAttributes: map[string]any{
+			"type": map[string]any{"Type": "String", "Value": string(envelope.Type)},
+		},
+	})
+	require.NoError(t, err)
+
+	return lambdaevents.SQSMessage{
+		MessageId:  messageID,
+		Body:       string(body),
+		Attributes: map[string]string{"ApproximateReceiveCount": "1"},
+	}
+}
+
+func TestFinalizeRegistrationRedelivered(t *testing.T) {
+	pending, err := attributevalue.MarshalMap(models.EndpointRegistration{
+		PK:     "agency#agency-1",
+		SK:     "registration#ABCD2345",
+		Type:   models.EntityTypeRegistration,
+		Status: models.RegistrationStatusPending,
+	})
+	require.NoError(t, err)
+
+	var (
+		client = &fakeDynamoDB{
+			getItem: func(params *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+				return &dynamodb.GetItemOutput{Item: pending}, nil
+			},
+		}
+		sns       = new(fakeSNS)
+		publisher = events.NewPublisher(sns, "topic", "agency")
+		c         = consumer.New(discardLogger, 3)
+		resolved  = newEnvelope(t, events.TypeEndpointResolved, events.EndpointResolved{
+			RegistrationCode: "ABCD2345",
+			AgencyID:         "agency-1",
+			EndpointID:       "endpoint-1",
+		})
+	)
+
+	c.UseLedger(&memoryLedger{entries: make(map[string]consumer.Entry)})
+	c.Handle(
+		events.TypeEndpointResolved,
+		finalizeRegistration(Config{}, discardLogger, client, publisher),
+		consumer.OnReplay(replayRegistration(discardLogger, publisher)))
+
+	response, err := c.Process(context.Background(), lambdaevents.SQSEvent{
+		Records: []lambdaevents.SQSMessage{
+			newRecord(t, "message-1", resolved),
+			// SNS delivered the event to the queue twice.
+			newRecord(t, "message-2", resolved),
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, response.BatchItemFailures)
+
+	// The registration is finalized once, the redelivery publishes the same
+	// event again.
+	assert.Len(t, client.deletes, 1)
+	assert.Len(t, client.puts, 1)
+	require.Len(t, sns.envelopes, 2)
+	assert.Equal(t, events.TypeAgencyRegistrationCreated, sns.envelopes[0].Type)
+	assert.Equal(t, sns.envelopes[0].ID, sns.envelopes[1].ID)
+	assert.JSONEq(t, string(sns.envelopes[0].Payload), string(sns.envelopes[1].Payload))
+}
@@ -2,51 +2,42 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"log/slog"
+	"time"
 
-	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/invitations"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
 )
 
-func markInviteFailed(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client) func(context.Context, events.SNSEntity, int) error {
-	type message struct {
-		Email    string `json:"email"`
-		AgencyID string `json:"agencyId"`
-	}
-	return func(ctx context.Context, record events.SNSEntity, retryCount int) error {
-		var message message
-
-		if err := json.Unmarshal([]byte(record.Message), &message); err != nil {
+// markInviteFailed marks an invitation as failed when the user service could
+// not ensure the invitee. The invitation can be resent.
+func markInviteFailed(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.UserEnsureInvite](envelope)
+		if err != nil {
 			logger.ErrorContext(ctx, "failed to unmarshal message", slog.Any("error", err))
 			return err
 		}
 
-		_, err := dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-			TableName: aws.String(config.AgencyTableName),
-			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{
-					Value: fmt.Sprintf("invite#%s", message.Email),
-				},
-				"sk": &types.AttributeValueMemberS{
-					Value: fmt.Sprintf("agency#%s", message.AgencyID),
-				},
-			},
-			UpdateExpression: aws.String("set #status = :status"),
-			ExpressionAttributeNames: map[string]string{
-				"#status": "status",
-			},
-			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":status": &types.AttributeValueMemberS{
-					Value: "FAILED",
-				},
-			},
-		})
-
+		invite, err := invitations.Get(ctx, dynamoClient, config.AgencyTableName, message.Email, message.AgencyID)
 		if err != nil {
+			logger.ErrorContext(ctx, "failed to get invite", slog.Any("error", err))
+			return err
+		}
+
+		if invite == nil || invite.Status != models.InvitationStatusPending {
+			return nil
+		}
+
+		failed := *invite
+		failed.Status = models.InvitationStatusFailed
+		failed.Modified = time.Now()
+		failed.ModifiedBy = "system"
+
+		if err := invitations.Put(ctx, dynamoClient, config.AgencyTableName, failed, invite); err != nil {
 			logger.ErrorContext(ctx, "failed to update invite", slog.Any("error", err))
 			return err
 		}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// markRegistrationDeclined marks a pending registration as declined by the
+// owner of the endpoint.
+func markRegistrationDeclined(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.EndpointRegistrationDeclined](envelope)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to unmarshal message", slog.Any("error", err))
+			return err
+		}
+
+		_, err = dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(config.AgencyTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("agency#%s", message.AgencyID),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("registration#%s", message.RegistrationCode),
+				},
+			},
+			ConditionExpression: aws.String("#status = :pending"),
+			UpdateExpression:    aws.String("SET #status = :status, #endpointId = :endpointId, #modified = :modified, #modifiedBy = :modifiedBy"),
+			ExpressionAttributeNames: map[string]string{
+				"#status":     "status",
+				"#endpointId": "endpointId",
+				"#modified":   "modified",
+				"#modifiedBy": "modifiedBy",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pending":    &types.AttributeValueMemberS{Value: models.RegistrationStatusPending},
+				":status":     &types.AttributeValueMemberS{Value: models.RegistrationStatusDeclined},
+				":endpointId": &types.AttributeValueMemberS{Value: message.EndpointID},
+				":modified":   &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339Nano)},
+				":modifiedBy": &types.AttributeValueMemberS{Value: message.DeclinedBy},
+			},
+		})
+
+		// The registration was removed or already settled, there is nothing to
+		// decline.
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			logger.WarnContext(
+				ctx,
+				"registration is not pending",
+				slog.String("agencyId", message.AgencyID),
+				slog.String("registrationCode", message.RegistrationCode))
+			return nil
+		}
+
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to update registration", slog.Any("error", err))
+			return err
+		}
+
+		return nil
+	}
+}
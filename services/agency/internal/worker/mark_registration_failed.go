@@ -2,32 +2,36 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
 )
 
-// finalizeRegistration finalizes an endpoint registration.
-func markRegistrationFailed(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client) func(context.Context, events.SNSEntity, int) error {
-	type message struct {
-		RegistrationCode string `json:"registrationCode"`
-		AgencyID         string `json:"agencyId"`
-		EndpointId       string `json:"userId"`
-	}
-
-	return func(ctx context.Context, record events.SNSEntity, retryCount int) error {
-		var message message
+// resolutionFailedExpired is the reason the endpoint service gives when the
+// registration code has expired.
+const resolutionFailedExpired = "EXPIRED"
 
-		if err := json.Unmarshal([]byte(record.Message), &message); err != nil {
+// markRegistrationFailed marks a registration whose endpoint couldn't be
+// resolved as failed, or as expired when the registration code had expired.
+func markRegistrationFailed(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.EndpointResolutionFailed](envelope)
+		if err != nil {
 			logger.ErrorContext(ctx, "failed to unmarshal message", slog.Any("error", err))
 			return err
 		}
 
+		status := "FAILED"
+		if message.Reason == resolutionFailedExpired {
+			status = models.RegistrationStatusExpired
+		}
+
 		if _, err := dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 			TableName: aws.String(config.AgencyTableName),
 			Key: map[string]types.AttributeValue{
@@ -44,7 +48,7 @@ func markRegistrationFailed(config Config, logger *slog.Logger, dynamoClient *dy
 			},
 			ExpressionAttributeValues: map[string]types.AttributeValue{
 				":status": &types.AttributeValueMemberS{
-					Value: "FAILED",
+					Value: status,
 				},
 			},
 		}); err != nil {
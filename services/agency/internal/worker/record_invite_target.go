@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/invitations"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// recordInviteTarget records the user an invitation was sent to once the user
+// service has ensured they exist. The invitee becomes a member when they accept
+// the invitation, an invitation that expired before the user was ensured is
+// marked as expired instead.
+func recordInviteTarget(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.UserInviteTargetEnsured](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to record invite target: %w", err)
+		}
+
+		invite, err := invitations.Get(ctx, dynamoClient, config.AgencyTableName, message.Email, message.AgencyID)
+		if err != nil {
+			return fmt.Errorf("failed to record invite target: %w", err)
+		}
+
+		if invite == nil {
+			return errors.New("invite doesn't exist")
+		}
+
+		// The invite was revoked or responded to before the user was ensured.
+		if invite.Status != models.InvitationStatusPending {
+			logger.InfoContext(
+				ctx,
+				"invite is no longer pending",
+				slog.String("agencyId", message.AgencyID),
+				slog.String("status", invite.Status))
+			return nil
+		}
+
+		now := time.Now()
+
+		updated := *invite
+		updated.UserID = message.UserID
+		updated.Modified = now
+		updated.ModifiedBy = "system"
+
+		if invite.Expired(now) {
+			updated.Status = models.InvitationStatusExpired
+		}
+
+		if err := invitations.Put(ctx, dynamoClient, config.AgencyTableName, updated, invite); err != nil {
+			return fmt.Errorf("failed to record invite target: %w", err)
+		}
+
+		return nil
+	}
+}
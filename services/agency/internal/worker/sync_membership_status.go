@@ -0,0 +1,203 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+)
+
+// syncMembershipStatus marks the active memberships of a deactivated user
+// inactive, and makes them active again when the user is reactivated. Pending
+// memberships are left as they are.
+//
+// Deactivating and reactivating in quick succession can deliver the events out
+// of order, so the user's status is recorded first and only replaced by a later
+// change. Each membership is updated only while the recorded status is still
+// the one being applied.
+func syncMembershipStatus(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.UserDeactivated](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to sync membership status: %w", err)
+		}
+
+		statusModified := &types.AttributeValueMemberN{
+			Value: strconv.FormatInt(message.Modified.UnixNano(), 10),
+		}
+
+		stateAV, err := attributevalue.MarshalMap(models.UserState{
+			PK:             fmt.Sprintf("user#%s", message.UserID),
+			SK:             "status",
+			Type:           models.EntityTypeUserState,
+			Status:         message.Status,
+			Modified:       message.Modified,
+			ModifiedBy:     message.ModifiedBy,
+			StatusModified: message.Modified.UnixNano(),
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal user status: %w", err)
+		}
+
+		_, err = dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(config.AgencyTableName),
+			Item:                stateAV,
+			ConditionExpression: aws.String("attribute_not_exists(#pk) OR #statusModified <= :statusModified"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk":             "pk",
+				"#statusModified": "statusModified",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":statusModified": statusModified,
+			},
+		})
+
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			logger.InfoContext(ctx, "ignoring stale user status", slog.String("userId", message.UserID))
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to put user status: %w", err)
+		}
+
+		from, to := models.MembershipStatusInactive, models.MembershipStatusActive
+		if message.Status == identity.StatusInactive {
+			from, to = models.MembershipStatusActive, models.MembershipStatusInactive
+		}
+
+		paginator := dynamodb.NewQueryPaginator(dynamoClient, &dynamodb.QueryInput{
+			TableName:              aws.String(config.AgencyTableName),
+			KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk": "pk",
+				"#sk": "sk",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("user#%s", message.UserID),
+				},
+				":sk": &types.AttributeValueMemberS{Value: "agency#"},
+			},
+		})
+
+		var memberships []models.Membership
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to sync membership status: %w", err)
+			}
+
+			var results []models.Membership
+			if err := attributevalue.UnmarshalListOfMaps(page.Items, &results); err != nil {
+				return fmt.Errorf("failed to sync membership status: %w", err)
+			}
+
+			memberships = append(memberships, results...)
+		}
+
+		now := time.Now().Format(time.RFC3339Nano)
+
+		for _, membership := range memberships {
+			if membership.Status != from {
+				continue
+			}
+
+			agencyID := strings.TrimPrefix(membership.SK, "agency#")
+
+			items := []types.TransactWriteItem{
+				{
+					ConditionCheck: &types.ConditionCheck{
+						TableName: aws.String(config.AgencyTableName),
+						Key: map[string]types.AttributeValue{
+							"pk": stateAV["pk"],
+							"sk": stateAV["sk"],
+						},
+						ConditionExpression: aws.String("#statusModified = :statusModified"),
+						ExpressionAttributeNames: map[string]string{
+							"#statusModified": "statusModified",
+						},
+						ExpressionAttributeValues: map[string]types.AttributeValue{
+							":statusModified": statusModified,
+						},
+					},
+				},
+			}
+			for _, key := range [][2]string{
+				{fmt.Sprintf("user#%s", message.UserID), fmt.Sprintf("agency#%s", agencyID)},
+				{fmt.Sprintf("agency#%s", agencyID), fmt.Sprintf("user#%s", message.UserID)},
+			} {
+				items = append(items, types.TransactWriteItem{
+					Update: &types.Update{
+						TableName: aws.String(config.AgencyTableName),
+						Key: map[string]types.AttributeValue{
+							"pk": &types.AttributeValueMemberS{Value: key[0]},
+							"sk": &types.AttributeValueMemberS{Value: key[1]},
+						},
+						// The membership may have changed or been removed since it
+						// was read.
+						ConditionExpression: aws.String("#status = :from"),
+						UpdateExpression:    aws.String("SET #status = :to, #modified = :modified, #modifiedBy = :modifiedBy"),
+						ExpressionAttributeNames: map[string]string{
+							"#status":     "status",
+							"#modified":   "modified",
+							"#modifiedBy": "modifiedBy",
+						},
+						ExpressionAttributeValues: map[string]types.AttributeValue{
+							":from":       &types.AttributeValueMemberS{Value: from},
+							":to":         &types.AttributeValueMemberS{Value: to},
+							":modified":   &types.AttributeValueMemberS{Value: now},
+							":modifiedBy": &types.AttributeValueMemberS{Value: "system"},
+						},
+					},
+				})
+			}
+
+			_, err := dynamoClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+				TransactItems: items,
+			})
+
+			var canceled *types.TransactionCanceledException
+			if errors.As(err, &canceled) && conditionCheckFailed(canceled) {
+				// A later status change has been recorded, the memberships
+				// are left to it.
+				if aws.ToString(canceled.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+					logger.InfoContext(ctx, "user status changed while syncing memberships", slog.String("userId", message.UserID))
+					return nil
+				}
+				continue
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to sync membership status for agency %s: %w", agencyID, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// conditionCheckFailed returns true if a transaction was canceled because one
+// of its conditions failed.
+func conditionCheckFailed(canceled *types.TransactionCanceledException) bool {
+	for _, reason := range canceled.CancellationReasons {
+		if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+			return true
+		}
+	}
+	return false
+}
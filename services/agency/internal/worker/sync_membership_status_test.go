@@ -0,0 +1,170 @@
+package worker
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/agency/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var userStatusModified = time.Date(2025, 1, 2, 3, 4, 5, 600, time.UTC)
+
+// newMembershipTable returns a table holding the user's active membership in
+// agency-1, inactive membership in agency-2 and pending membership in
+// agency-3.
+func newMembershipTable(t *testing.T) *fakeDynamoDB {
+	t.Helper()
+
+	var items []map[string]types.AttributeValue
+	for agencyID, status := range map[string]models.MembershipStatus{
+		"agency-1": models.MembershipStatusActive,
+		"agency-2": models.MembershipStatusInactive,
+		"agency-3": models.MembershipStatusPending,
+	} {
+		item, err := attributevalue.MarshalMap(models.Membership{
+			PK:     "user#user-1",
+			SK:     "agency#" + agencyID,
+			Type:   models.EntityTypeMembership,
+			Status: status,
+			Role:   identity.RoleReader,
+		})
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+
+	return &fakeDynamoDB{
+		query: func(params *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{Items: items}, nil
+		},
+	}
+}
+
+func userStatusEvent(t *testing.T, status identity.Status) events.Envelope {
+	eventType := events.TypeUserReactivated
+	if status == identity.StatusInactive {
+		eventType = events.TypeUserDeactivated
+	}
+
+	return newEnvelope(t, eventType, events.UserDeactivated{
+		UserID:     "user-1",
+		Status:     status,
+		Modified:   userStatusModified,
+		ModifiedBy: "admin",
+	})
+}
+
+func TestSyncMembershipStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   identity.Status
+		agencyID string
+		from, to models.MembershipStatus
+	}{
+		{
+			name:     "deactivated",
+			status:   identity.StatusInactive,
+			agencyID: "agency-1",
+			from:     models.MembershipStatusActive,
+			to:       models.MembershipStatusInactive,
+		},
+		{
+			name:     "reactivated",
+			status:   identity.StatusActive,
+			agencyID: "agency-2",
+			from:     models.MembershipStatusInactive,
+			to:       models.MembershipStatusActive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMembershipTable(t)
+
+			handler := syncMembershipStatus(Config{}, discardLogger, client)
+			require.NoError(t, handler(context.Background(), userStatusEvent(t, tt.status)))
+
+			statusModified := &types.AttributeValueMemberN{Value: strconv.FormatInt(userStatusModified.UnixNano(), 10)}
+
+			// The status is recorded unless a later one has been.
+			require.Len(t, client.puts, 1)
+			put := client.puts[0]
+			assert.Equal(t, "attribute_not_exists(#pk) OR #statusModified <= :statusModified", aws.ToString(put.ConditionExpression))
+			assert.Equal(t, statusModified, put.ExpressionAttributeValues[":statusModified"])
+
+			var state models.UserState
+			require.NoError(t, attributevalue.UnmarshalMap(put.Item, &state))
+			assert.Equal(t, "user#user-1", state.PK)
+			assert.Equal(t, "status", state.SK)
+			assert.Equal(t, tt.status, state.Status)
+			assert.Equal(t, userStatusModified.UnixNano(), state.StatusModified)
+
+			// Only the membership with the status being changed from is
+			// updated, while the user's status is still the one recorded.
+			require.Len(t, client.transacts, 1)
+			items := client.transacts[0].TransactItems
+			require.Len(t, items, 3)
+
+			check := items[0].ConditionCheck
+			require.NotNil(t, check)
+			assert.Equal(t, &types.AttributeValueMemberS{Value: "user#user-1"}, check.Key["pk"])
+			assert.Equal(t, &types.AttributeValueMemberS{Value: "status"}, check.Key["sk"])
+			assert.Equal(t, "#statusModified = :statusModified", aws.ToString(check.ConditionExpression))
+			assert.Equal(t, statusModified, check.ExpressionAttributeValues[":statusModified"])
+
+			for _, item := range items[1:] {
+				require.NotNil(t, item.Update)
+				assert.Contains(t, []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "user#user-1"},
+					&types.AttributeValueMemberS{Value: "agency#" + tt.agencyID},
+				}, item.Update.Key["pk"])
+				assert.Equal(t, &types.AttributeValueMemberS{Value: tt.from}, item.Update.ExpressionAttributeValues[":from"])
+				assert.Equal(t, &types.AttributeValueMemberS{Value: tt.to}, item.Update.ExpressionAttributeValues[":to"])
+			}
+		})
+	}
+}
+
+func TestSyncMembershipStatusStale(t *testing.T) {
+	client := newMembershipTable(t)
+
+	// A later status has been recorded.
+	client.putItem = func(params *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+
+	handler := syncMembershipStatus(Config{}, discardLogger, client)
+	require.NoError(t, handler(context.Background(), userStatusEvent(t, identity.StatusInactive)))
+
+	assert.Empty(t, client.queries)
+	assert.Empty(t, client.transacts)
+}
+
+func TestSyncMembershipStatusSuperseded(t *testing.T) {
+	client := newMembershipTable(t)
+
+	// A later status is recorded while the memberships are being updated.
+	client.transactWriteItems = func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+		return nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("ConditionalCheckFailed")},
+				{Code: aws.String("None")},
+				{Code: aws.String("None")},
+			},
+		}
+	}
+
+	handler := syncMembershipStatus(Config{}, discardLogger, client)
+	require.NoError(t, handler(context.Background(), userStatusEvent(t, identity.StatusInactive)))
+
+	assert.Len(t, client.transacts, 1)
+}
@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+)
+
+// syncRegistrations copies the enabled state of an updated endpoint onto the
+// registration rows of every agency it is registered to.
+func syncRegistrations(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.EndpointUpdated](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to sync registrations: %w", err)
+		}
+
+		for _, agencyID := range message.Agencies {
+			_, err := dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName: aws.String(config.AgencyTableName),
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("agency#%s", agencyID),
+					},
+					"sk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("endpoint#%s", message.EndpointID),
+					},
+				},
+				// Don't recreate a registration the agency has already removed.
+				ConditionExpression: aws.String("attribute_exists(#pk)"),
+				UpdateExpression:    aws.String("SET #disabled = :disabled, #modified = :modified, #modifiedBy = :modifiedBy"),
+				ExpressionAttributeNames: map[string]string{
+					"#pk":         "pk",
+					"#disabled":   "disabled",
+					"#modified":   "modified",
+					"#modifiedBy": "modifiedBy",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":disabled":   &types.AttributeValueMemberBOOL{Value: !message.Enabled},
+					":modified":   &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339Nano)},
+					":modifiedBy": &types.AttributeValueMemberS{Value: "system"},
+				},
+			})
+
+			var conditionFailed *types.ConditionalCheckFailedException
+			if errors.As(err, &conditionFailed) {
+				continue
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to sync registrations for agency %s: %w", agencyID, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// deleteRegistrations removes the registration rows of a deleted endpoint from
+// every agency it was registered to.
+func deleteRegistrations(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.EndpointDeleted](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to delete registrations: %w", err)
+		}
+
+		for _, agencyID := range message.Agencies {
+			if _, err := dynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(config.AgencyTableName),
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("agency#%s", agencyID),
+					},
+					"sk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("endpoint#%s", message.EndpointID),
+					},
+				},
+			}); err != nil {
+				return fmt.Errorf("failed to delete registrations for agency %s: %w", agencyID, err)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func registrationKey(key map[string]types.AttributeValue) string {
+	return key["pk"].(*types.AttributeValueMemberS).Value + "|" + key["sk"].(*types.AttributeValueMemberS).Value
+}
+
+func TestSyncRegistrations(t *testing.T) {
+	// The agency-2 registration has already been removed.
+	client := &fakeDynamoDB{
+		updateItem: func(params *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+			if registrationKey(params.Key) == "agency#agency-2|endpoint#endpoint-1" {
+				return nil, &types.ConditionalCheckFailedException{}
+			}
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	require.NoError(t, syncRegistrations(Config{}, discardLogger, client)(context.Background(), newEnvelope(t, events.TypeEndpointUpdated, events.EndpointUpdated{
+		EndpointID: "endpoint-1",
+		Enabled:    false,
+		Agencies:   []string{"agency-1", "agency-2", "agency-3"},
+	})))
+
+	var keys []string
+	for _, update := range client.updates {
+		assert.Equal(t, "attribute_exists(#pk)", aws.ToString(update.ConditionExpression))
+		assert.Equal(t, &types.AttributeValueMemberBOOL{Value: true}, update.ExpressionAttributeValues[":disabled"])
+		keys = append(keys, registrationKey(update.Key))
+	}
+	assert.Equal(t, []string{
+		"agency#agency-1|endpoint#endpoint-1",
+		"agency#agency-2|endpoint#endpoint-1",
+		"agency#agency-3|endpoint#endpoint-1",
+	}, keys)
+}
+
+func TestSyncRegistrationsFailed(t *testing.T) {
+	client := &fakeDynamoDB{
+		updateItem: func(params *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+			return nil, errors.New("throttled")
+		},
+	}
+
+	err := syncRegistrations(Config{}, discardLogger, client)(context.Background(), newEnvelope(t, events.TypeEndpointUpdated, events.EndpointUpdated{
+		EndpointID: "endpoint-1",
+		Enabled:    true,
+		Agencies:   []string{"agency-1", "agency-2"},
+	}))
+
+	// The event is redelivered rather than skipping the remaining agencies.
+	assert.Error(t, err)
+	assert.Len(t, client.updates, 1)
+}
+
+func TestDeleteRegistrations(t *testing.T) {
+	client := new(fakeDynamoDB)
+
+	require.NoError(t, deleteRegistrations(Config{}, discardLogger, client)(context.Background(), newEnvelope(t, events.TypeEndpointDeleted, events.EndpointDeleted{
+		EndpointID: "endpoint-1",
+		Agencies:   []string{"agency-1", "agency-2"},
+	})))
+
+	var keys []string
+	for _, input := range client.deletes {
+		keys = append(keys, registrationKey(input.Key))
+	}
+	assert.Equal(t, []string{
+		"agency#agency-1|endpoint#endpoint-1",
+		"agency#agency-2|endpoint#endpoint-1",
+	}, keys)
+}
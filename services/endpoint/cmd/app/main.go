@@ -10,7 +10,9 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/caarlos0/env/v11"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/services/endpoint/internal/app"
 )
 
@@ -35,8 +37,10 @@ func run(ctx context.Context) error {
 	}
 
 	dynamoClient := dynamodb.NewFromConfig(awsconf)
+	snsClient := sns.NewFromConfig(awsconf)
+	publisher := events.NewPublisher(snsClient, conf.EventsTopicARN, "endpoint")
 
-	handler := app.NewServer(conf, logger, dynamoClient)
+	handler := app.NewServer(conf, logger, dynamoClient, publisher)
 
 	lambda.Start(awsapigatewayv2handler.NewLambdaHandler(handler))
 
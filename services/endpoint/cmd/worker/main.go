@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 
 	"github.com/aws/aws-lambda-go/lambda"
@@ -11,6 +12,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/caarlos0/env/v11"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
 	"github.com/jsmithdenverdev/pager/services/endpoint/internal/worker"
 )
 
@@ -38,8 +41,22 @@ func run(ctx context.Context) error {
 
 	dynamoClient := dynamodb.NewFromConfig(awsconf)
 	snsClient := sns.NewFromConfig(awsconf)
+	publisher := events.NewPublisher(snsClient, conf.EventsTopicARN, "endpoint")
+	httpClient := &http.Client{Timeout: conf.DeliveryTimeout}
 
-	lambda.Start(worker.EventProcessor(conf, logger, dynamoClient, snsClient))
+	pushSender, err := worker.NewPushSender(conf, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create push sender: %w", err)
+	}
+
+	senders := map[models.EndpointType]worker.Sender{
+		models.EndpointTypeWebhook: worker.NewWebhookSender(conf, httpClient),
+		models.EndpointTypePush:    pushSender,
+		models.EndpointTypeSMS:     worker.NewSMSSender(conf, httpClient),
+		models.EndpointTypeEmail:   worker.NewEmailSender(conf),
+	}
+
+	lambda.Start(worker.EventProcessor(conf, logger, dynamoClient, publisher, senders))
 
 	return nil
 }
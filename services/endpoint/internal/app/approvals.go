@@ -0,0 +1,248 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/pkg/outbox"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+// listApprovals returns the requests agencies have made to register an
+// endpoint, including those already approved or declined.
+// Only the owner of the endpoint can list its approvals.
+func listApprovals(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			endpointid  = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		endpoint, status := readOwnedEndpoint(r, config, logger, client, user, endpointid)
+		if endpoint == nil {
+			w.WriteHeader(status)
+			return
+		}
+
+		approvals, err := queryApprovals(r.Context(), config, client, endpointid)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to query endpoint approvals", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		results := make([]approvalResponse, 0, len(approvals))
+		for _, approval := range approvals {
+			results = append(results, toApprovalResponse(approval))
+		}
+
+		if err := json.NewEncoder(w).Encode(listResponse[approvalResponse]{
+			Results: results,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// decideApproval approves or declines a pending request by an agency to
+// register an endpoint. Approving resolves the registration so the agency can
+// complete it, declining marks the agency's registration as declined. The
+// event is staged in the outbox with the decision, so a decision is never left
+// without it and deciding again is a conflict.
+// Only the owner of the endpoint can decide.
+func decideApproval(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher, decision models.ApprovalStatus) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			endpointid  = r.PathValue("id")
+			agencyid    = r.PathValue("agencyId")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		endpoint, status := readOwnedEndpoint(r, config, logger, dynamoClient, user, endpointid)
+		if endpoint == nil {
+			w.WriteHeader(status)
+			return
+		}
+
+		key := map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("endpoint#%s", endpointid),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("approval#%s", agencyid),
+			},
+		}
+
+		result, err := dynamoClient.GetItem(r.Context(), &dynamodb.GetItemInput{
+			TableName:      aws.String(config.EndpointTableName),
+			Key:            key,
+			ConsistentRead: aws.Bool(true),
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get approval", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var approval models.Approval
+		if err := attributevalue.UnmarshalMap(result.Item, &approval); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal approval record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if approval.Status != models.ApprovalStatusPending {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		var envelope events.Envelope
+		if decision == models.ApprovalStatusDeclined {
+			envelope, err = publisher.Envelope(r.Context(), events.TypeEndpointRegistrationDeclined, events.EndpointRegistrationDeclined{
+				RegistrationCode: approval.RegistrationCode,
+				AgencyID:         agencyid,
+				EndpointID:       endpointid,
+				DeclinedBy:       user.ID,
+			})
+		} else {
+			envelope, err = publisher.Envelope(r.Context(), events.TypeEndpointResolved, events.EndpointResolved{
+				RegistrationCode: approval.RegistrationCode,
+				AgencyID:         agencyid,
+				EndpointID:       endpointid,
+			})
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to create event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now()
+
+		outboxItem, err := outbox.Put(config.EndpointTableName, envelope, now)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to create event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_, err = dynamoClient.TransactWriteItems(r.Context(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{
+					Update: &types.Update{
+						TableName: aws.String(config.EndpointTableName),
+						Key:       key,
+						// Another decision may have been made since the
+						// approval was read.
+						ConditionExpression: aws.String("#status = :pending"),
+						UpdateExpression:    aws.String("SET #status = :status, #modified = :modified, #modifiedBy = :modifiedBy"),
+						ExpressionAttributeNames: map[string]string{
+							"#status":     "status",
+							"#modified":   "modified",
+							"#modifiedBy": "modifiedBy",
+						},
+						ExpressionAttributeValues: map[string]types.AttributeValue{
+							":pending":    &types.AttributeValueMemberS{Value: models.ApprovalStatusPending},
+							":status":     &types.AttributeValueMemberS{Value: decision},
+							":modified":   &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+							":modifiedBy": &types.AttributeValueMemberS{Value: user.ID},
+						},
+					},
+				},
+				outboxItem,
+			},
+		})
+
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to update approval", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		approval.Status = decision
+		approval.Modified = now
+		approval.ModifiedBy = user.ID
+
+		if err := encode(w, r, http.StatusOK, toApprovalResponse(approval)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// readOwnedEndpoint reads an endpoint the user owns. When the endpoint can't be
+// returned the status to respond with is returned instead.
+func readOwnedEndpoint(r *http.Request, config Config, logger *slog.Logger, client dynamoDBAPI, user identity.User, endpointID string) (*models.Endpoint, int) {
+	result, err := client.GetItem(r.Context(), &dynamodb.GetItemInput{
+		TableName: aws.String(config.EndpointTableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("endpoint#%s", endpointID),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: "meta",
+			},
+		},
+	})
+
+	if err != nil {
+		logger.ErrorContext(r.Context(), "failed to get endpoint", slog.Any("error", err))
+		return nil, http.StatusInternalServerError
+	}
+
+	if result.Item == nil {
+		return nil, http.StatusNotFound
+	}
+
+	var endpoint models.Endpoint
+	if err := attributevalue.UnmarshalMap(result.Item, &endpoint); err != nil {
+		logger.ErrorContext(r.Context(), "failed to unmarshal endpoint record", slog.Any("error", err))
+		return nil, http.StatusInternalServerError
+	}
+
+	if endpoint.UserID != user.ID {
+		return nil, http.StatusForbidden
+	}
+
+	return &endpoint, http.StatusOK
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var endpointOwner = identity.User{ID: "owner"}
+
+// newApprovalTable returns a table holding the owner's endpoint and an
+// approval requested by agency-1 with the given status.
+func newApprovalTable(t *testing.T, status models.ApprovalStatus) *fakeDynamoDB {
+	t.Helper()
+
+	client := new(fakeDynamoDB)
+	client.put(t, models.Endpoint{
+		KeyFields: models.KeyFields{PK: "endpoint#endpoint-1", SK: "meta", Type: models.EntityTypeEndpoint},
+		UserID:    "owner",
+	})
+	client.put(t, models.Approval{
+		KeyFields:        models.KeyFields{PK: "endpoint#endpoint-1", SK: "approval#agency-1", Type: models.EntityTypeApproval},
+		Status:           status,
+		AgencyID:         "agency-1",
+		RegistrationCode: "ABCD2345",
+		RequestedBy:      "writer",
+	})
+	return client
+}
+
+func decide(t *testing.T, client *fakeDynamoDB, decision models.ApprovalStatus, user identity.User) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serve(
+		t,
+		decideApproval(Config{EndpointTableName: "endpoints"}, discardLogger, client, testPublisher, decision),
+		user,
+		httptest.NewRequest(http.MethodPost, "/endpoints/endpoint-1/approvals/agency-1", nil),
+		map[string]string{"id": "endpoint-1", "agencyId": "agency-1"})
+}
+
+func TestDecideApproval(t *testing.T) {
+	tests := []struct {
+		name      string
+		decision  models.ApprovalStatus
+		eventType events.Type
+		payload   string
+	}{
+		{
+			name:      "approve",
+			decision:  models.ApprovalStatusApproved,
+			eventType: events.TypeEndpointResolved,
+			payload:   `{"registrationCode": "ABCD2345", "agencyId": "agency-1", "endpointId": "endpoint-1"}`,
+		},
+		{
+			name:      "decline",
+			decision:  models.ApprovalStatusDeclined,
+			eventType: events.TypeEndpointRegistrationDeclined,
+			payload:   `{"registrationCode": "ABCD2345", "agencyId": "agency-1", "endpointId": "endpoint-1", "declinedBy": "owner"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newApprovalTable(t, models.ApprovalStatusPending)
+
+			w := decide(t, client, tt.decision, endpointOwner)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response approvalResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			assert.Equal(t, tt.decision, response.Status)
+			assert.Equal(t, "owner", response.ModifiedBy)
+
+			// The decision and its event are written together.
+			require.Len(t, client.transacts, 1)
+			transact := client.transacts[0]
+			require.Len(t, transact.TransactItems, 2)
+
+			update := transact.TransactItems[0].Update
+			require.NotNil(t, update)
+			assert.Equal(t, "#status = :pending", aws.ToString(update.ConditionExpression))
+			assert.Equal(t, &types.AttributeValueMemberS{Value: tt.decision}, update.ExpressionAttributeValues[":status"])
+
+			envelopes := outboxEnvelopes(t, transact)
+			require.Len(t, envelopes, 1)
+			assert.Equal(t, tt.eventType, envelopes[0].Type)
+			assert.JSONEq(t, tt.payload, string(envelopes[0].Payload))
+		})
+	}
+}
+
+func TestDecideApprovalRepeated(t *testing.T) {
+	for _, status := range []models.ApprovalStatus{models.ApprovalStatusApproved, models.ApprovalStatusDeclined} {
+		t.Run(status, func(t *testing.T) {
+			client := newApprovalTable(t, status)
+
+			// The event was staged with the first decision, deciding again
+			// doesn't stage another.
+			assert.Equal(t, http.StatusConflict, decide(t, client, models.ApprovalStatusApproved, endpointOwner).Code)
+			assert.Equal(t, http.StatusConflict, decide(t, client, models.ApprovalStatusDeclined, endpointOwner).Code)
+			assert.Empty(t, client.transacts)
+		})
+	}
+}
+
+func TestDecideApprovalConcurrent(t *testing.T) {
+	client := newApprovalTable(t, models.ApprovalStatusPending)
+
+	// Another decision was made between reading the approval and writing this
+	// one.
+	client.transactWriteItems = func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+		return nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")}},
+		}
+	}
+
+	assert.Equal(t, http.StatusConflict, decide(t, client, models.ApprovalStatusApproved, endpointOwner).Code)
+}
+
+func TestDecideApprovalNotFound(t *testing.T) {
+	client := newApprovalTable(t, models.ApprovalStatusPending)
+
+	w := serve(
+		t,
+		decideApproval(Config{}, discardLogger, client, testPublisher, models.ApprovalStatusApproved),
+		endpointOwner,
+		httptest.NewRequest(http.MethodPost, "/endpoints/endpoint-1/approvals/agency-2", nil),
+		map[string]string{"id": "endpoint-1", "agencyId": "agency-2"})
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, client.transacts)
+}
+
+func TestDecideApprovalNotOwner(t *testing.T) {
+	client := newApprovalTable(t, models.ApprovalStatusPending)
+
+	assert.Equal(t, http.StatusForbidden, decide(t, client, models.ApprovalStatusApproved, identity.User{ID: "writer"}).Code)
+	assert.Empty(t, client.transacts)
+}
@@ -1,10 +1,16 @@
 package app
 
-import "log/slog"
+import (
+	"log/slog"
+	"time"
+)
 
 type Config struct {
-	LogLevel          slog.Level `env:"LOG_LEVEL"`
-	Environment       string     `env:"ENVIRONMENT"`
-	EndpointTableName string     `env:"ENDPOINT_TABLE_NAME"`
-	EventsTopicARN    string     `env:"EVENTS_TOPIC_ARN"`
+	LogLevel              slog.Level    `env:"LOG_LEVEL"`
+	Environment           string        `env:"ENVIRONMENT"`
+	EndpointTableName     string        `env:"ENDPOINT_TABLE_NAME"`
+	EventsTopicARN        string        `env:"EVENTS_TOPIC_ARN"`
+	SecretRotationOverlap time.Duration `env:"SECRET_ROTATION_OVERLAP" envDefault:"24h"`
+	RegistrationCodeTTL   time.Duration `env:"REGISTRATION_CODE_TTL" envDefault:"24h"`
+	RegistrationLinkURL   string        `env:"REGISTRATION_LINK_URL" envDefault:"pager://register-endpoint"`
 }
@@ -1,25 +1,26 @@
 package app
 
 import (
-	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/google/uuid"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/pkg/webhook"
 	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
 )
 
-func createEndpoint(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) http.Handler {
+func createEndpoint(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var (
 			user        identity.User
@@ -55,44 +56,38 @@ func createEndpoint(config Config, logger *slog.Logger, dynamoClient *dynamodb.C
 		}
 
 		var (
-			now              = time.Now()
-			id               = uuid.New().String()
-			registrationCode = fmt.Sprintf("%x", sha256.Sum256([]byte(id)))
+			now           = time.Now()
+			id            = uuid.New().String()
+			signingSecret string
 		)
 
-		endpointAV, err := attributevalue.MarshalMap(models.Endpoint{
+		// Webhook deliveries are signed so receivers can verify they came from
+		// pager. The secret is only ever returned here and when it is rotated.
+		if req.EndpointType == models.EndpointTypeWebhook {
+			signingSecret, err = webhook.NewSecret()
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to generate signing secret", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		endpoint := models.Endpoint{
 			KeyFields: models.KeyFields{
 				PK:   fmt.Sprintf("endpoint#%s", id),
 				SK:   "meta",
 				Type: models.EntityTypeEndpoint,
 			},
-			AuditableFields:  models.NewAuditableFields(user.ID, now),
-			UserID:           user.ID,
-			Name:             req.Name,
-			EndpointType:     req.EndpointType,
-			RegistrationCode: registrationCode,
-			URL:              req.URL,
-		})
-		if err != nil {
-			logger.ErrorContext(r.Context(), "failed to marshal endpoint", slog.Any("error", err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		registrationCodeAV, err := attributevalue.MarshalMap(models.RegistrationCode{
-			KeyFields: models.KeyFields{
-				PK:   fmt.Sprintf("rc#%s", registrationCode),
-				SK:   "registrationcode",
-				Type: models.EntityTypeRegistrationCode,
-			},
 			AuditableFields: models.NewAuditableFields(user.ID, now),
-			EndpointID:      id,
 			UserID:          user.ID,
-		})
-		if err != nil {
-			logger.ErrorContext(r.Context(), "failed to marshal registration code", slog.Any("error", err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			Name:            req.Name,
+			EndpointType:    req.EndpointType,
+			URL:             req.URL,
+			PushPlatform:    req.PushPlatform,
+			PushToken:       req.PushToken,
+			PhoneNumber:     req.PhoneNumber,
+			EmailAddress:    req.EmailAddress,
+			SigningSecret:   signingSecret,
 		}
 
 		ownerAV, err := attributevalue.MarshalMap(models.Owner{
@@ -109,38 +104,93 @@ func createEndpoint(config Config, logger *slog.Logger, dynamoClient *dynamodb.C
 			return
 		}
 
-		// Use DynamoDB TransactWriteItems for atomic creation
-		_, err = dynamoClient.TransactWriteItems(r.Context(), &dynamodb.TransactWriteItemsInput{
-			TransactItems: []types.TransactWriteItem{
-				{
-					Put: &types.Put{
-						TableName: aws.String(config.EndpointTableName),
-						Item:      endpointAV,
+		// Registration codes are short enough that a new code can collide with
+		// one in use, in which case the endpoint is written with another code.
+		var registrationCode models.RegistrationCode
+		for attempt := 1; ; attempt++ {
+			registrationCode, err = newRegistrationCode(config, user.ID, id, now)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to create registration code", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			endpoint.RegistrationCode = strings.TrimPrefix(registrationCode.PK, "rc#")
+			endpoint.RegistrationCodeExpires = &registrationCode.Expires
+
+			endpointAV, err := attributevalue.MarshalMap(endpoint)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to marshal endpoint", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			registrationCodeAV, err := attributevalue.MarshalMap(registrationCode)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to marshal registration code", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			// Use DynamoDB TransactWriteItems for atomic creation
+			_, err = dynamoClient.TransactWriteItems(r.Context(), &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{
+						Put: &types.Put{
+							TableName: aws.String(config.EndpointTableName),
+							Item:      endpointAV,
+						},
 					},
-				},
-				{
-					Put: &types.Put{
-						TableName: aws.String(config.EndpointTableName),
-						Item:      registrationCodeAV,
+					{
+						Put: &types.Put{
+							TableName:           aws.String(config.EndpointTableName),
+							Item:                registrationCodeAV,
+							ConditionExpression: aws.String("attribute_not_exists(#pk)"),
+							ExpressionAttributeNames: map[string]string{
+								"#pk": "pk",
+							},
+						},
 					},
-				},
-				{
-					Put: &types.Put{
-						TableName: aws.String(config.EndpointTableName),
-						Item:      ownerAV,
+					{
+						Put: &types.Put{
+							TableName: aws.String(config.EndpointTableName),
+							Item:      ownerAV,
+						},
 					},
 				},
-			},
-		})
+			})
 
-		if err != nil {
-			logger.ErrorContext(r.Context(), "failed to transact write endpoint entities", slog.Any("error", err))
+			if conditionFailedAt(err, 1) && attempt < maxRegistrationCodeAttempts {
+				continue
+			}
+
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to transact write endpoint entities", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			break
+		}
+
+		if err := publisher.Publish(r.Context(), events.TypeEndpointCreated, events.EndpointCreated{
+			EndpointID:   id,
+			UserID:       user.ID,
+			Name:         endpoint.Name,
+			EndpointType: endpoint.EndpointType,
+			Enabled:      !endpoint.Disabled,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		w.WriteHeader(http.StatusCreated)
-		if err = encode(w, r, int(http.StatusCreated), createEndpointResponse{ID: id}); err != nil {
+		if err = encode(w, r, int(http.StatusCreated), createEndpointResponse{
+			ID:                      id,
+			RegistrationCode:        endpoint.RegistrationCode,
+			RegistrationCodeExpires: registrationCode.Expires,
+			SigningSecret:           signingSecret,
+		}); err != nil {
 			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
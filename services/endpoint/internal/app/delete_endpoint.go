@@ -0,0 +1,135 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+// deleteEndpoint removes an endpoint along with its ownership, registration
+// code, registrations and approvals. Delivery records are left to expire.
+// Only the owner of the endpoint can delete it.
+func deleteEndpoint(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			endpointid  = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		result, err := dynamoClient.GetItem(r.Context(), &dynamodb.GetItemInput{
+			TableName: aws.String(config.EndpointTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("endpoint#%s", endpointid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get endpoint", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var endpoint models.Endpoint
+		if err := attributevalue.UnmarshalMap(result.Item, &endpoint); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal endpoint record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if endpoint.UserID != user.ID {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		registrations, err := queryRegistrations(r.Context(), config, dynamoClient, endpointid)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to query endpoint registrations", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		approvals, err := queryApprovals(r.Context(), config, dynamoClient, endpointid)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to query endpoint approvals", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		keys := [][2]string{
+			{fmt.Sprintf("endpoint#%s", endpointid), "meta"},
+			{fmt.Sprintf("user#%s", endpoint.UserID), fmt.Sprintf("endpoint#%s", endpointid)},
+			{fmt.Sprintf("rc#%s", endpoint.RegistrationCode), "registrationcode"},
+		}
+
+		var agencies []string
+		for _, registration := range registrations {
+			agencyID := strings.TrimPrefix(registration.SK, "agency#")
+			agencies = append(agencies, agencyID)
+			keys = append(keys,
+				[2]string{fmt.Sprintf("endpoint#%s", endpointid), fmt.Sprintf("agency#%s", agencyID)},
+				[2]string{fmt.Sprintf("agency#%s", agencyID), fmt.Sprintf("endpoint#%s", endpointid)})
+		}
+
+		for _, approval := range approvals {
+			keys = append(keys, [2]string{approval.PK, approval.SK})
+		}
+
+		items := make([]types.TransactWriteItem, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, types.TransactWriteItem{
+				Delete: &types.Delete{
+					TableName: aws.String(config.EndpointTableName),
+					Key: map[string]types.AttributeValue{
+						"pk": &types.AttributeValueMemberS{Value: key[0]},
+						"sk": &types.AttributeValueMemberS{Value: key[1]},
+					},
+				},
+			})
+		}
+
+		if err := transactWriteItems(r.Context(), dynamoClient, items); err != nil {
+			logger.ErrorContext(r.Context(), "failed to delete endpoint", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := publisher.Publish(r.Context(), events.TypeEndpointDeleted, events.EndpointDeleted{
+			EndpointID: endpointid,
+			UserID:     endpoint.UserID,
+			Agencies:   agencies,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
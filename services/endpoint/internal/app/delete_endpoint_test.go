@@ -0,0 +1,73 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func remove(t *testing.T, client *fakeDynamoDB, sns *fakeSNS, user identity.User) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serve(
+		t,
+		deleteEndpoint(Config{EndpointTableName: "endpoints"}, discardLogger, client, events.NewPublisher(sns, "topic", "endpoint")),
+		user,
+		httptest.NewRequest(http.MethodDelete, "/endpoints/endpoint-1", nil),
+		map[string]string{"id": "endpoint-1"})
+}
+
+func TestDeleteEndpoint(t *testing.T) {
+	var (
+		client = newEndpointTable(t, "agency-1", "agency-2")
+		sns    = new(fakeSNS)
+	)
+	client.put(t, models.Approval{
+		KeyFields: models.KeyFields{PK: "endpoint#endpoint-1", SK: "approval#agency-3", Type: models.EntityTypeApproval},
+		Status:    models.ApprovalStatusPending,
+		AgencyID:  "agency-3",
+	})
+
+	require.Equal(t, http.StatusNoContent, remove(t, client, sns, endpointOwner).Code)
+
+	require.Len(t, client.transacts, 1)
+	var keys []string
+	for _, item := range client.transacts[0].TransactItems {
+		require.NotNil(t, item.Delete)
+		keys = append(keys, itemKey(item.Delete.Key))
+	}
+	assert.ElementsMatch(t, []string{
+		"endpoint#endpoint-1|meta",
+		"user#owner|endpoint#endpoint-1",
+		"rc#ABCD2345|registrationcode",
+		"endpoint#endpoint-1|agency#agency-1",
+		"agency#agency-1|endpoint#endpoint-1",
+		"endpoint#endpoint-1|agency#agency-2",
+		"agency#agency-2|endpoint#endpoint-1",
+		"endpoint#endpoint-1|approval#agency-3",
+	}, keys)
+
+	require.Len(t, sns.envelopes, 1)
+	require.Equal(t, events.TypeEndpointDeleted, sns.envelopes[0].Type)
+	var payload events.EndpointDeleted
+	require.NoError(t, json.Unmarshal(sns.envelopes[0].Payload, &payload))
+	assert.Equal(t, []string{"agency-1", "agency-2"}, payload.Agencies)
+}
+
+func TestDeleteEndpointNotOwner(t *testing.T) {
+	var (
+		client = newEndpointTable(t, "agency-1")
+		sns    = new(fakeSNS)
+	)
+
+	assert.Equal(t, http.StatusForbidden, remove(t, client, sns, identity.User{ID: "writer"}).Code)
+	assert.Empty(t, client.transacts)
+	assert.Empty(t, sns.envelopes)
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/pkg/outbox"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDynamoDB reads items from memory by key, or by partition and sort key
+// prefix for queries, and records the writes made to it. Writes are answered by
+// the matching function if one is set, and succeed otherwise. Nothing written is
+// read back.
+type fakeDynamoDB struct {
+	items map[string]map[string]types.AttributeValue
+
+	transactWriteItems func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error)
+
+	transacts []*dynamodb.TransactWriteItemsInput
+}
+
+func itemKey(key map[string]types.AttributeValue) string {
+	return key["pk"].(*types.AttributeValueMemberS).Value + "|" + key["sk"].(*types.AttributeValueMemberS).Value
+}
+
+// put stores the model, which must have pk and sk attributes.
+func (f *fakeDynamoDB) put(t *testing.T, model any) {
+	t.Helper()
+
+	item, err := attributevalue.MarshalMap(model)
+	require.NoError(t, err)
+
+	if f.items == nil {
+		f.items = make(map[string]map[string]types.AttributeValue)
+	}
+	f.items[itemKey(item)] = item
+}
+
+func (f *fakeDynamoDB) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f.items[itemKey(params.Key)]}, nil
+}
+
+func (f *fakeDynamoDB) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	var (
+		pk     = params.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS).Value
+		prefix = params.ExpressionAttributeValues[":sk"].(*types.AttributeValueMemberS).Value
+		keys   []string
+	)
+	for key := range f.items {
+		if strings.HasPrefix(key, pk+"|"+prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	output := new(dynamodb.QueryOutput)
+	for _, key := range keys {
+		output.Items = append(output.Items, f.items[key])
+	}
+	return output, nil
+}
+
+func (f *fakeDynamoDB) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	f.transacts = append(f.transacts, params)
+	if f.transactWriteItems != nil {
+		return f.transactWriteItems(params)
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// outboxEnvelopes returns the envelopes staged in the outbox by the
+// transaction.
+func outboxEnvelopes(t *testing.T, input *dynamodb.TransactWriteItemsInput) []events.Envelope {
+	t.Helper()
+
+	var envelopes []events.Envelope
+	for _, item := range input.TransactItems {
+		if item.Put == nil || !strings.HasPrefix(item.Put.Item["pk"].(*types.AttributeValueMemberS).Value, outbox.KeyPrefix) {
+			continue
+		}
+
+		var envelope events.Envelope
+		require.NoError(t, json.Unmarshal([]byte(item.Put.Item["envelope"].(*types.AttributeValueMemberS).Value), &envelope))
+		envelopes = append(envelopes, envelope)
+	}
+	return envelopes
+}
+
+// nopSNS accepts every message published to it.
+type nopSNS struct{}
+
+func (nopSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	return &sns.PublishOutput{}, nil
+}
+
+// fakeSNS records the events published to it.
+type fakeSNS struct {
+	envelopes []events.Envelope
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	var envelope events.Envelope
+	if err := json.Unmarshal([]byte(aws.ToString(params.Message)), &envelope); err != nil {
+		return nil, err
+	}
+	f.envelopes = append(f.envelopes, envelope)
+	return &sns.PublishOutput{}, nil
+}
+
+var (
+	discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	testPublisher = events.NewPublisher(nopSNS{}, "topic", "endpoint")
+)
+
+// serve sends the request to the handler as the user, with the path values
+// set.
+func serve(t *testing.T, handler http.Handler, user identity.User, r *http.Request, pathValues map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	userinfo, err := json.Marshal(user)
+	require.NoError(t, err)
+	r.Header.Set("x-pager-userinfo", string(userinfo))
+
+	for name, value := range pathValues {
+		r.SetPathValue(name, value)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
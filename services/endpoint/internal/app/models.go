@@ -3,6 +3,8 @@ package app
 import (
 	"context"
 	"fmt"
+	"net/mail"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
@@ -15,32 +17,46 @@ import (
 //-----------------------------------------------------------------------------
 
 type endpointResponse struct {
-	ID               string         `json:"id"`
-	UserID           string         `json:"userId"`
-	EndpointType     string         `json:"endpointType"`
-	Name             string         `json:"name"`
-	URL              string         `json:"url"`
-	Registrations    map[string]any `json:"registrations"`
-	RegistrationCode string         `json:"registrationCode"`
-	Created          time.Time      `json:"created"`
-	Modified         time.Time      `json:"modified"`
-	CreatedBy        string         `json:"createdBy"`
-	ModifiedBy       string         `json:"modifiedBy"`
+	ID                      string         `json:"id"`
+	UserID                  string         `json:"userId"`
+	EndpointType            string         `json:"endpointType"`
+	Name                    string         `json:"name"`
+	URL                     string         `json:"url"`
+	Registrations           map[string]any `json:"registrations"`
+	RegistrationCode        string         `json:"registrationCode"`
+	RegistrationCodeExpires *time.Time     `json:"registrationCodeExpires,omitempty"`
+	PushPlatform            string         `json:"pushPlatform,omitempty"`
+	PhoneNumber             string         `json:"phoneNumber,omitempty"`
+	EmailAddress            string         `json:"emailAddress,omitempty"`
+	Disabled                bool           `json:"disabled"`
+	DisabledReason          string         `json:"disabledReason,omitempty"`
+	Suspended               bool           `json:"suspended,omitempty"`
+	Created                 time.Time      `json:"created"`
+	Modified                time.Time      `json:"modified"`
+	CreatedBy               string         `json:"createdBy"`
+	ModifiedBy              string         `json:"modifiedBy"`
 }
 
 func toEndpointResponse(endpoint models.Endpoint) endpointResponse {
 	return endpointResponse{
-		ID:               strings.Split(endpoint.PK, "#")[1],
-		UserID:           endpoint.UserID,
-		EndpointType:     endpoint.EndpointType,
-		Name:             endpoint.Name,
-		URL:              endpoint.URL,
-		Registrations:    endpoint.Registrations,
-		RegistrationCode: endpoint.RegistrationCode,
-		Created:          endpoint.Created,
-		Modified:         endpoint.Modified,
-		CreatedBy:        endpoint.CreatedBy,
-		ModifiedBy:       endpoint.ModifiedBy,
+		ID:                      strings.Split(endpoint.PK, "#")[1],
+		UserID:                  endpoint.UserID,
+		EndpointType:            endpoint.EndpointType,
+		Name:                    endpoint.Name,
+		URL:                     endpoint.URL,
+		Registrations:           endpoint.Registrations,
+		RegistrationCode:        endpoint.RegistrationCode,
+		RegistrationCodeExpires: endpoint.RegistrationCodeExpires,
+		PushPlatform:            endpoint.PushPlatform,
+		PhoneNumber:             endpoint.PhoneNumber,
+		EmailAddress:            endpoint.EmailAddress,
+		Disabled:                endpoint.Disabled,
+		DisabledReason:          endpoint.DisabledReason,
+		Suspended:               endpoint.Suspended,
+		Created:                 endpoint.Created,
+		Modified:                endpoint.Modified,
+		CreatedBy:               endpoint.CreatedBy,
+		ModifiedBy:              endpoint.ModifiedBy,
 	}
 }
 
@@ -48,18 +64,50 @@ type createEndpointRequest struct {
 	URL          string `json:"url"`
 	Name         string `json:"name"`
 	EndpointType string `json:"endpointType"`
+	PushPlatform string `json:"pushPlatform"`
+	PushToken    string `json:"pushToken"`
+	PhoneNumber  string `json:"phoneNumber"`
+	EmailAddress string `json:"emailAddress"`
 }
 
+// e164Pattern matches a phone number in E.164 format, e.g. +13035550123.
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
 func (r createEndpointRequest) valid(ctx context.Context) map[string]string {
 	problems := make(map[string]string)
 
 	allowedEndpointTypes := []models.EndpointType{
 		models.EndpointTypePush,
 		models.EndpointTypeWebhook,
+		models.EndpointTypeSMS,
+		models.EndpointTypeEmail,
+	}
+
+	allowedPushPlatforms := []models.PushPlatform{
+		models.PushPlatformFCM,
+		models.PushPlatformAPNS,
 	}
 
-	if r.URL == "" {
-		problems["url"] = "url is required"
+	switch r.EndpointType {
+	case models.EndpointTypeWebhook:
+		if r.URL == "" {
+			problems["url"] = "url is required"
+		}
+	case models.EndpointTypePush:
+		if !slices.Contains(allowedPushPlatforms, r.PushPlatform) {
+			problems["pushPlatform"] = fmt.Sprintf("pushPlatform must be one of: %s", strings.Join(allowedPushPlatforms, ", "))
+		}
+		if r.PushToken == "" {
+			problems["pushToken"] = "pushToken is required"
+		}
+	case models.EndpointTypeSMS:
+		if !e164Pattern.MatchString(r.PhoneNumber) {
+			problems["phoneNumber"] = "phoneNumber must be in E.164 format, e.g. +13035550123"
+		}
+	case models.EndpointTypeEmail:
+		if address, err := mail.ParseAddress(r.EmailAddress); err != nil || address.Address != r.EmailAddress {
+			problems["emailAddress"] = "emailAddress must be a valid email address"
+		}
 	}
 
 	if r.Name == "" {
@@ -77,8 +125,137 @@ func (r createEndpointRequest) valid(ctx context.Context) map[string]string {
 	return problems
 }
 
+// updateEndpointRequest changes the fields of an endpoint that are set.
+// Fields that don't apply to the type of the endpoint are rejected once the
+// endpoint has been read, see validFor.
+type updateEndpointRequest struct {
+	Name         *string `json:"name"`
+	URL          *string `json:"url"`
+	PushToken    *string `json:"pushToken"`
+	PhoneNumber  *string `json:"phoneNumber"`
+	EmailAddress *string `json:"emailAddress"`
+	Enabled      *bool   `json:"enabled"`
+}
+
+func (r updateEndpointRequest) valid(ctx context.Context) map[string]string {
+	problems := make(map[string]string)
+
+	if r.Name != nil && *r.Name == "" {
+		problems["name"] = "name cannot be empty"
+	}
+
+	if r.URL != nil && *r.URL == "" {
+		problems["url"] = "url cannot be empty"
+	}
+
+	if r.PushToken != nil && *r.PushToken == "" {
+		problems["pushToken"] = "pushToken cannot be empty"
+	}
+
+	if r.PhoneNumber != nil && !e164Pattern.MatchString(*r.PhoneNumber) {
+		problems["phoneNumber"] = "phoneNumber must be in E.164 format, e.g. +13035550123"
+	}
+
+	if r.EmailAddress != nil {
+		if address, err := mail.ParseAddress(*r.EmailAddress); err != nil || address.Address != *r.EmailAddress {
+			problems["emailAddress"] = "emailAddress must be a valid email address"
+		}
+	}
+
+	return problems
+}
+
+// validFor returns the fields of the request that can't be set on an endpoint
+// of the given type.
+func (r updateEndpointRequest) validFor(endpointType models.EndpointType) map[string]string {
+	problems := make(map[string]string)
+
+	if r.URL != nil && endpointType != models.EndpointTypeWebhook {
+		problems["url"] = "url can only be set on WEBHOOK endpoints"
+	}
+
+	if r.PushToken != nil && endpointType != models.EndpointTypePush {
+		problems["pushToken"] = "pushToken can only be set on PUSH endpoints"
+	}
+
+	if r.PhoneNumber != nil && endpointType != models.EndpointTypeSMS {
+		problems["phoneNumber"] = "phoneNumber can only be set on SMS endpoints"
+	}
+
+	if r.EmailAddress != nil && endpointType != models.EndpointTypeEmail {
+		problems["emailAddress"] = "emailAddress can only be set on EMAIL endpoints"
+	}
+
+	return problems
+}
+
 type createEndpointResponse struct {
-	ID string `json:"id"`
+	ID                      string    `json:"id"`
+	RegistrationCode        string    `json:"registrationCode"`
+	RegistrationCodeExpires time.Time `json:"registrationCodeExpires"`
+	SigningSecret           string    `json:"signingSecret,omitempty"`
+}
+
+type regenerateRegistrationCodeResponse struct {
+	RegistrationCode        string    `json:"registrationCode"`
+	RegistrationCodeExpires time.Time `json:"registrationCodeExpires"`
+}
+
+type registrationQRResponse struct {
+	RegistrationCode        string     `json:"registrationCode"`
+	RegistrationCodeExpires *time.Time `json:"registrationCodeExpires,omitempty"`
+	Link                    string     `json:"link"`
+	PNG                     string     `json:"png"`
+	SVG                     string     `json:"svg"`
+}
+
+type rotateSecretRequest struct {
+	// OverlapMinutes is how long the previous secret remains valid. When
+	// omitted the configured default is used.
+	OverlapMinutes *int `json:"overlapMinutes"`
+}
+
+func (r rotateSecretRequest) valid(ctx context.Context) map[string]string {
+	problems := make(map[string]string)
+
+	if r.OverlapMinutes != nil && (*r.OverlapMinutes < 0 || *r.OverlapMinutes > maxSecretOverlapMinutes) {
+		problems["overlapMinutes"] = fmt.Sprintf("overlapMinutes must be between 0 and %d", maxSecretOverlapMinutes)
+	}
+
+	return problems
+}
+
+type rotateSecretResponse struct {
+	SigningSecret                string     `json:"signingSecret"`
+	PreviousSigningSecretExpires *time.Time `json:"previousSigningSecretExpires,omitempty"`
+}
+
+//-----------------------------------------------------------------------------
+// APPROVAL
+//-----------------------------------------------------------------------------
+
+type approvalResponse struct {
+	EndpointID  string    `json:"endpointId"`
+	AgencyID    string    `json:"agencyId"`
+	Status      string    `json:"status"`
+	RequestedBy string    `json:"requestedBy"`
+	Created     time.Time `json:"created"`
+	Modified    time.Time `json:"modified"`
+	CreatedBy   string    `json:"createdBy"`
+	ModifiedBy  string    `json:"modifiedBy"`
+}
+
+func toApprovalResponse(approval models.Approval) approvalResponse {
+	return approvalResponse{
+		EndpointID:  strings.Split(approval.PK, "#")[1],
+		AgencyID:    approval.AgencyID,
+		Status:      approval.Status,
+		RequestedBy: approval.RequestedBy,
+		Created:     approval.Created,
+		Modified:    approval.Modified,
+		CreatedBy:   approval.CreatedBy,
+		ModifiedBy:  approval.ModifiedBy,
+	}
 }
 
 //-----------------------------------------------------------------------------
@@ -5,22 +5,35 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
 	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
 )
 
 // readEndpoint returns a single endpoint by ID.
+// The calling user must own the endpoint, be a member of an agency the
+// endpoint is registered to or be a platform admin. Only the owner is shown
+// the registration code, holding it is what lets an agency request to
+// register the endpoint.
 func readEndpoint(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var (
-			userid     = r.Header.Get("x-pager-userid")
-			endpointid = r.PathValue("id")
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			endpointid  = r.PathValue("id")
 		)
 
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		result, err := client.GetItem(r.Context(), &dynamodb.GetItemInput{
 			TableName: aws.String(config.EndpointTableName),
 			Key: map[string]types.AttributeValue{
@@ -39,31 +52,53 @@ func readEndpoint(config Config, logger *slog.Logger, client *dynamodb.Client) h
 			return
 		}
 
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
 		var endpoint models.Endpoint
-		if result.Item != nil {
-			if err := attributevalue.UnmarshalMap(result.Item, &endpoint); err != nil {
-				logger.ErrorContext(r.Context(), "failed to unmarshal endpoint record", slog.Any("error", err))
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		if err := attributevalue.UnmarshalMap(result.Item, &endpoint); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal endpoint record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 
-		// Ensure the endpoint belongs to the calling user. This means we're doing
-		// a read no matter what which isn't ideal, but it's the only way to
-		// enforce this.
-		// We could also store the endpoint using the userid as the primary key,
-		// which would force us to only return the endpoint if it belongs to the
-		// user, but that breaks away from the Unauthorized pattern we use for
-		// other reads.
-		if endpoint.UserID != userid {
+		if !canReadEndpoint(user, endpoint) {
 			w.WriteHeader(http.StatusForbidden)
 			return
 		}
 
-		if err := json.NewEncoder(w).Encode(toEndpointResponse(endpoint)); err != nil {
+		response := toEndpointResponse(endpoint)
+		if endpoint.UserID != user.ID {
+			response.RegistrationCode = ""
+			response.RegistrationCodeExpires = nil
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 	})
 }
+
+// canReadEndpoint returns true if the user owns the endpoint, is a member of an
+// agency the endpoint is registered to or is a platform admin.
+func canReadEndpoint(user identity.User, endpoint models.Endpoint) bool {
+	if endpoint.UserID == user.ID {
+		return true
+	}
+
+	if slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+		return true
+	}
+
+	for agencyID := range endpoint.Registrations {
+		if _, ok := user.Memberships[agencyID]; ok {
+			return true
+		}
+	}
+
+	return false
+}
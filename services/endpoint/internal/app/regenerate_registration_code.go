@@ -0,0 +1,146 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+)
+
+// regenerateRegistrationCode replaces the registration code of an endpoint
+// with a new one, for example once the old code has expired. The old code stops
+// working immediately.
+// Only the owner of the endpoint can regenerate its code.
+func regenerateRegistrationCode(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			endpointid  = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		endpoint, status := readOwnedEndpoint(r, config, logger, client, user, endpointid)
+		if endpoint == nil {
+			w.WriteHeader(status)
+			return
+		}
+
+		now := time.Now()
+
+		for attempt := 1; ; attempt++ {
+			registrationCode, err := newRegistrationCode(config, user.ID, endpointid, now)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to create registration code", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			registrationCodeAV, err := attributevalue.MarshalMap(registrationCode)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to marshal registration code", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			code := strings.TrimPrefix(registrationCode.PK, "rc#")
+
+			_, err = client.TransactWriteItems(r.Context(), &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{
+						// Guard against a concurrent regeneration replacing the
+						// code between our read and write.
+						Update: &types.Update{
+							TableName: aws.String(config.EndpointTableName),
+							Key: map[string]types.AttributeValue{
+								"pk": &types.AttributeValueMemberS{
+									Value: fmt.Sprintf("endpoint#%s", endpointid),
+								},
+								"sk": &types.AttributeValueMemberS{
+									Value: "meta",
+								},
+							},
+							ConditionExpression: aws.String("#registrationCode = :currentRegistrationCode"),
+							UpdateExpression:    aws.String("SET #registrationCode = :registrationCode, #registrationCodeExpires = :registrationCodeExpires, #modified = :modified, #modifiedBy = :modifiedBy"),
+							ExpressionAttributeNames: map[string]string{
+								"#registrationCode":        "registrationCode",
+								"#registrationCodeExpires": "registrationCodeExpires",
+								"#modified":                "modified",
+								"#modifiedBy":              "modifiedBy",
+							},
+							ExpressionAttributeValues: map[string]types.AttributeValue{
+								":currentRegistrationCode": &types.AttributeValueMemberS{Value: endpoint.RegistrationCode},
+								":registrationCode":        &types.AttributeValueMemberS{Value: code},
+								":registrationCodeExpires": &types.AttributeValueMemberS{Value: registrationCode.Expires.Format(time.RFC3339Nano)},
+								":modified":                &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+								":modifiedBy":              &types.AttributeValueMemberS{Value: user.ID},
+							},
+						},
+					},
+					{
+						Put: &types.Put{
+							TableName:           aws.String(config.EndpointTableName),
+							Item:                registrationCodeAV,
+							ConditionExpression: aws.String("attribute_not_exists(#pk)"),
+							ExpressionAttributeNames: map[string]string{
+								"#pk": "pk",
+							},
+						},
+					},
+					{
+						Delete: &types.Delete{
+							TableName: aws.String(config.EndpointTableName),
+							Key: map[string]types.AttributeValue{
+								"pk": &types.AttributeValueMemberS{
+									Value: fmt.Sprintf("rc#%s", endpoint.RegistrationCode),
+								},
+								"sk": &types.AttributeValueMemberS{
+									Value: "registrationcode",
+								},
+							},
+						},
+					},
+				},
+			})
+
+			if conditionFailedAt(err, 0) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+
+			if conditionFailedAt(err, 1) && attempt < maxRegistrationCodeAttempts {
+				continue
+			}
+
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to regenerate registration code", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if err := encode(w, r, http.StatusOK, regenerateRegistrationCodeResponse{
+				RegistrationCode:        code,
+				RegistrationCodeExpires: registrationCode.Expires,
+			}); err != nil {
+				logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			return
+		}
+	})
+}
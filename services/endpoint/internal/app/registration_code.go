@@ -0,0 +1,41 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+const (
+	// registrationCodeRetention is how long the row of an expired registration
+	// code is kept so that using it is reported as expired rather than unknown.
+	registrationCodeRetention = 7 * 24 * time.Hour
+	// maxRegistrationCodeAttempts is how many codes are tried before giving up
+	// on finding one that isn't already in use.
+	maxRegistrationCodeAttempts = 3
+)
+
+// newRegistrationCode returns a new registration code row for an endpoint that
+// expires after the configured TTL.
+func newRegistrationCode(config Config, userID, endpointID string, now time.Time) (models.RegistrationCode, error) {
+	code, err := models.NewRegistrationCode()
+	if err != nil {
+		return models.RegistrationCode{}, fmt.Errorf("failed to generate registration code: %w", err)
+	}
+
+	expires := now.Add(config.RegistrationCodeTTL)
+
+	return models.RegistrationCode{
+		KeyFields: models.KeyFields{
+			PK:   fmt.Sprintf("rc#%s", code),
+			SK:   "registrationcode",
+			Type: models.EntityTypeRegistrationCode,
+		},
+		AuditableFields: models.NewAuditableFields(userID, now),
+		EndpointID:      endpointID,
+		UserID:          userID,
+		Expires:         expires,
+		TTL:             expires.Add(registrationCodeRetention).Unix(),
+	}, nil
+}
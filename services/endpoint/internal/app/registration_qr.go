@@ -0,0 +1,90 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/qr"
+)
+
+// registrationQRScale is the width in pixels of each module of the PNG.
+const registrationQRScale = 8
+
+// registrationQR returns the registration code of an endpoint along with a QR
+// code of a deep link containing it, as both a PNG and an SVG. An agency admin
+// scans the code from the owner's phone and calls register-endpoint with it.
+// Only the owner of the endpoint can see its registration code.
+func registrationQR(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			endpointid  = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		endpoint, status := readOwnedEndpoint(r, config, logger, client, user, endpointid)
+		if endpoint == nil {
+			w.WriteHeader(status)
+			return
+		}
+
+		link, err := registrationLink(config, endpoint.RegistrationCode)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to build registration link", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		code, err := qr.Encode(link)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode registration link", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		png, err := code.PNG(registrationQRScale)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to render registration QR code", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, registrationQRResponse{
+			RegistrationCode:        endpoint.RegistrationCode,
+			RegistrationCodeExpires: endpoint.RegistrationCodeExpires,
+			Link:                    link,
+			PNG:                     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+			SVG:                     string(code.SVG()),
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// registrationLink returns the deep link for a registration code, the
+// configured registration link URL with the code added as a query parameter.
+func registrationLink(config Config, registrationCode string) (string, error) {
+	link, err := url.Parse(config.RegistrationLinkURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set("registrationCode", registrationCode)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
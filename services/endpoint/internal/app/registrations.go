@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+const (
+	// maxTransactItems is the most items DynamoDB accepts in a single
+	// transaction.
+	maxTransactItems = 100
+	// maxRegistrationAttempts is how many times the registrations of an
+	// endpoint are read and written before giving up on them changing
+	// underneath the write.
+	maxRegistrationAttempts = 3
+)
+
+// queryRegistrations returns the endpoint#<id>/agency#<id> registration rows
+// of an endpoint. Creating or removing a registration writes these rows along
+// with the registrations map on the endpoint. The rows are read rather than the
+// map because they are what the callers go on to update or delete.
+func queryRegistrations(ctx context.Context, config Config, client dynamoDBAPI, endpointID string) ([]models.Registration, error) {
+	registrations, err := queryItems[models.Registration](ctx, config, client, fmt.Sprintf("endpoint#%s", endpointID), "agency#")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query registrations: %w", err)
+	}
+	return registrations, nil
+}
+
+// queryApprovals returns the registration approvals of an endpoint.
+func queryApprovals(ctx context.Context, config Config, client dynamoDBAPI, endpointID string) ([]models.Approval, error) {
+	approvals, err := queryItems[models.Approval](ctx, config, client, fmt.Sprintf("endpoint#%s", endpointID), "approval#")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approvals: %w", err)
+	}
+	return approvals, nil
+}
+
+// queryItems reads every item in a partition whose sort key has the prefix.
+func queryItems[T any](ctx context.Context, config Config, client dynamoDBAPI, pk, skPrefix string) ([]T, error) {
+	var (
+		items             []T
+		exclusiveStartKey map[string]types.AttributeValue
+	)
+
+	for {
+		result, err := client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(config.EndpointTableName),
+			KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk": "pk",
+				"#sk": "sk",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: pk,
+				},
+				":sk": &types.AttributeValueMemberS{
+					Value: skPrefix,
+				},
+			},
+			ExclusiveStartKey: exclusiveStartKey,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var page []T
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+
+		if result.LastEvaluatedKey == nil {
+			return items, nil
+		}
+		exclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// transactWriteItems writes items in as few transactions as DynamoDB allows.
+// Each transaction is atomic but an endpoint registered to enough agencies
+// spans several, so every item written must be safe to write again. A failed
+// transaction stops the write, see conditionFailedAt for which of the items
+// failed.
+func transactWriteItems(ctx context.Context, client dynamoDBAPI, items []types.TransactWriteItem) error {
+	var offset int
+	for chunk := range slices.Chunk(items, maxTransactItems) {
+		if _, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: chunk,
+		}); err != nil {
+			return &transactWriteError{offset: offset, err: err}
+		}
+		offset += len(chunk)
+	}
+
+	return nil
+}
+
+// transactWriteError is a failed transaction of transactWriteItems, offset is
+// the index of its first item among all of the items.
+type transactWriteError struct {
+	offset int
+	err    error
+}
+
+func (e *transactWriteError) Error() string { return e.err.Error() }
+
+func (e *transactWriteError) Unwrap() error { return e.err }
+
+// conditionFailedAt reports whether a transaction was canceled because the
+// condition of the item at index failed. For transactWriteItems the index is
+// among all of the items written.
+func conditionFailedAt(err error, index int) bool {
+	var transactErr *transactWriteError
+	if errors.As(err, &transactErr) {
+		index -= transactErr.offset
+	}
+
+	var canceled *types.TransactionCanceledException
+	if index < 0 || !errors.As(err, &canceled) || len(canceled.CancellationReasons) <= index {
+		return false
+	}
+	return aws.ToString(canceled.CancellationReasons[index].Code) == "ConditionalCheckFailed"
+}
+
+// conditionFailed reports whether a transaction was canceled because the
+// condition of any of its items failed.
+func conditionFailed(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return false
+	}
+	return slices.ContainsFunc(canceled.CancellationReasons, func(reason types.CancellationReason) bool {
+		return aws.ToString(reason.Code) == "ConditionalCheckFailed"
+	})
+}
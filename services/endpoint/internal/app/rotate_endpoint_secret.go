@@ -0,0 +1,199 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/pkg/webhook"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+// maxSecretOverlapMinutes caps how long a rotated secret stays valid (7 days).
+const maxSecretOverlapMinutes = 7 * 24 * 60
+
+// rotateEndpointSecret replaces the signing secret of a WEBHOOK endpoint. The
+// previous secret keeps being used to sign deliveries until the overlap window
+// ends so the receiver can switch to the new secret without dropping pages.
+// Only the owner of the endpoint can rotate its secret.
+func rotateEndpointSecret(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			endpointid  = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// The request body is optional, an empty body uses the default overlap.
+		req, problems, err := decodeValid[rotateSecretRequest](r)
+		if err != nil && !errors.Is(err, io.EOF) {
+			if len(problems) > 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				if err := json.NewEncoder(w).Encode(problems); err != nil {
+					logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		result, err := client.GetItem(r.Context(), &dynamodb.GetItemInput{
+			TableName: aws.String(config.EndpointTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("endpoint#%s", endpointid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get endpoint", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var endpoint models.Endpoint
+		if err := attributevalue.UnmarshalMap(result.Item, &endpoint); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal endpoint record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if endpoint.UserID != user.ID {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if endpoint.EndpointType != models.EndpointTypeWebhook {
+			w.WriteHeader(http.StatusBadRequest)
+			if err := json.NewEncoder(w).Encode(map[string]string{
+				"endpointType": "only WEBHOOK endpoints have a signing secret",
+			}); err != nil {
+				logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+
+		signingSecret, err := webhook.NewSecret()
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to generate signing secret", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var (
+			now     = time.Now()
+			overlap = config.SecretRotationOverlap
+			expires *time.Time
+		)
+
+		if req.OverlapMinutes != nil {
+			overlap = time.Duration(*req.OverlapMinutes) * time.Minute
+		}
+
+		// Endpoints created before deliveries were signed have no secret to
+		// carry over.
+		if endpoint.SigningSecret != "" && overlap > 0 {
+			expires = aws.Time(now.Add(overlap))
+		}
+
+		var (
+			updateExpression = "SET #signingSecret = :signingSecret, #modified = :modified, #modifiedBy = :modifiedBy"
+			names            = map[string]string{
+				"#signingSecret": "signingSecret",
+				"#modified":      "modified",
+				"#modifiedBy":    "modifiedBy",
+			}
+			values = map[string]types.AttributeValue{
+				":signingSecret": &types.AttributeValueMemberS{Value: signingSecret},
+				":modified":      &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+				":modifiedBy":    &types.AttributeValueMemberS{Value: user.ID},
+			}
+			// Guard against a concurrent rotation replacing the secret between
+			// our read and write.
+			conditionExpression = "attribute_not_exists(#signingSecret)"
+		)
+
+		if endpoint.SigningSecret != "" {
+			conditionExpression = "#signingSecret = :currentSigningSecret"
+			values[":currentSigningSecret"] = &types.AttributeValueMemberS{Value: endpoint.SigningSecret}
+		}
+
+		if expires != nil {
+			updateExpression += ", #previousSigningSecret = :previousSigningSecret, #previousSigningSecretExpires = :previousSigningSecretExpires"
+			names["#previousSigningSecret"] = "previousSigningSecret"
+			names["#previousSigningSecretExpires"] = "previousSigningSecretExpires"
+			values[":previousSigningSecret"] = &types.AttributeValueMemberS{Value: endpoint.SigningSecret}
+			values[":previousSigningSecretExpires"] = &types.AttributeValueMemberS{Value: expires.Format(time.RFC3339Nano)}
+		} else {
+			updateExpression += " REMOVE #previousSigningSecret, #previousSigningSecretExpires"
+			names["#previousSigningSecret"] = "previousSigningSecret"
+			names["#previousSigningSecretExpires"] = "previousSigningSecretExpires"
+		}
+
+		_, err = client.UpdateItem(r.Context(), &dynamodb.UpdateItemInput{
+			TableName: aws.String(config.EndpointTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("endpoint#%s", endpointid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+			UpdateExpression:          aws.String(updateExpression),
+			ConditionExpression:       aws.String(conditionExpression),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		})
+
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to rotate signing secret", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, rotateSecretResponse{
+			SigningSecret:                signingSecret,
+			PreviousSigningSecretExpires: expires,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -6,10 +6,20 @@ import (
 	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
 )
 
-func addRoutes(mux *http.ServeMux, config Config, logger *slog.Logger, client *dynamodb.Client) {
-	mux.Handle(fmt.Sprintf("GET /%s", config.Environment), listEndpoints(config, logger, client))
-	mux.Handle(fmt.Sprintf("GET /%s/{id}", config.Environment), readEndpoint(config, logger, client))
-	mux.Handle(fmt.Sprintf("POST /%s", config.Environment), createEndpoint(config, logger, client, nil))
+func addRoutes(mux *http.ServeMux, config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) {
+	mux.Handle(fmt.Sprintf("GET /%s", config.Environment), listEndpoints(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("GET /%s/{id}", config.Environment), readEndpoint(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("POST /%s", config.Environment), createEndpoint(config, logger, dynamoClient, publisher))
+	mux.Handle(fmt.Sprintf("PATCH /%s/{id}", config.Environment), updateEndpoint(config, logger, dynamoClient, publisher))
+	mux.Handle(fmt.Sprintf("DELETE /%s/{id}", config.Environment), deleteEndpoint(config, logger, dynamoClient, publisher))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/secret", config.Environment), rotateEndpointSecret(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/registration-code", config.Environment), regenerateRegistrationCode(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("GET /%s/{id}/registration-qr", config.Environment), registrationQR(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("GET /%s/{id}/approvals", config.Environment), listApprovals(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/approvals/{agencyId}/approve", config.Environment), decideApproval(config, logger, dynamoClient, publisher, models.ApprovalStatusApproved))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/approvals/{agencyId}/decline", config.Environment), decideApproval(config, logger, dynamoClient, publisher, models.ApprovalStatusDeclined))
 }
@@ -0,0 +1,278 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+// disabledByOwnerReason is recorded when the owner of an endpoint disables it.
+const disabledByOwnerReason = "disabled by owner"
+
+// updateEndpoint changes an endpoint and carries the change to its
+// registrations. Setting enabled re-enables an endpoint, including one that was
+// disabled because its provider rejected it, so a user replacing a device can
+// update the token and enable the endpoint in a single request.
+// An endpoint registered to more agencies than fit in one transaction is
+// updated in several, see transactWriteItems, and its registrations can briefly
+// disagree with it.
+// Only the owner of the endpoint can update it.
+func updateEndpoint(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			endpointid  = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		req, problems, err := decodeValid[updateEndpointRequest](r)
+		if err != nil {
+			if len(problems) > 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				if err := json.NewEncoder(w).Encode(problems); err != nil {
+					logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		result, err := dynamoClient.GetItem(r.Context(), &dynamodb.GetItemInput{
+			TableName: aws.String(config.EndpointTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("endpoint#%s", endpointid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get endpoint", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var endpoint models.Endpoint
+		if err := attributevalue.UnmarshalMap(result.Item, &endpoint); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal endpoint record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if endpoint.UserID != user.ID {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if problems := req.validFor(endpoint.EndpointType); len(problems) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			if err := json.NewEncoder(w).Encode(problems); err != nil {
+				logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+
+		now := time.Now()
+
+		var (
+			set    = []string{"#modified = :modified", "#modifiedBy = :modifiedBy"}
+			remove []string
+			names  = map[string]string{
+				"#pk":         "pk",
+				"#modified":   "modified",
+				"#modifiedBy": "modifiedBy",
+			}
+			values = map[string]types.AttributeValue{
+				":modified":   &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+				":modifiedBy": &types.AttributeValueMemberS{Value: user.ID},
+			}
+		)
+
+		for _, field := range []struct {
+			name  string
+			value *string
+			dest  *string
+		}{
+			{"name", req.Name, &endpoint.Name},
+			{"url", req.URL, &endpoint.URL},
+			{"pushToken", req.PushToken, &endpoint.PushToken},
+			{"phoneNumber", req.PhoneNumber, &endpoint.PhoneNumber},
+			{"emailAddress", req.EmailAddress, &endpoint.EmailAddress},
+		} {
+			if field.value == nil {
+				continue
+			}
+			set = append(set, fmt.Sprintf("#%s = :%s", field.name, field.name))
+			names["#"+field.name] = field.name
+			values[":"+field.name] = &types.AttributeValueMemberS{Value: *field.value}
+			*field.dest = *field.value
+		}
+
+		if req.Enabled != nil {
+			names["#disabled"] = "disabled"
+			names["#disabledReason"] = "disabledReason"
+			values[":disabled"] = &types.AttributeValueMemberBOOL{Value: !*req.Enabled}
+
+			set = append(set, "#disabled = :disabled")
+			if *req.Enabled {
+				remove = append(remove, "#disabledReason")
+				endpoint.DisabledReason = ""
+			} else {
+				set = append(set, "#disabledReason = :disabledReason")
+				values[":disabledReason"] = &types.AttributeValueMemberS{Value: disabledByOwnerReason}
+				endpoint.DisabledReason = disabledByOwnerReason
+			}
+			endpoint.Disabled = !*req.Enabled
+		}
+
+		endpoint.Modified = now
+		endpoint.ModifiedBy = user.ID
+
+		updateExpression := "SET " + strings.Join(set, ", ")
+		if len(remove) > 0 {
+			updateExpression += " REMOVE " + strings.Join(remove, ", ")
+		}
+
+		endpointUpdate := types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String(config.EndpointTableName),
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("endpoint#%s", endpointid),
+					},
+					"sk": &types.AttributeValueMemberS{
+						Value: "meta",
+					},
+				},
+				ConditionExpression:       aws.String("attribute_exists(#pk)"),
+				UpdateExpression:          aws.String(updateExpression),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			},
+		}
+
+		var agencies []string
+		for attempt := 1; ; attempt++ {
+			registrations, err := queryRegistrations(r.Context(), config, dynamoClient, endpointid)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to query endpoint registrations", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			items := []types.TransactWriteItem{endpointUpdate}
+			agencies = nil
+
+			// Registration rows copy the URL and disabled flag of the endpoint,
+			// both the endpoint#<id>/agency#<id> row and its
+			// agency#<id>/endpoint#<id> inverse are kept in step.
+			for _, registration := range registrations {
+				agencyID := strings.TrimPrefix(registration.SK, "agency#")
+				agencies = append(agencies, agencyID)
+
+				for _, key := range [][2]string{
+					{fmt.Sprintf("endpoint#%s", endpointid), fmt.Sprintf("agency#%s", agencyID)},
+					{fmt.Sprintf("agency#%s", agencyID), fmt.Sprintf("endpoint#%s", endpointid)},
+				} {
+					items = append(items, types.TransactWriteItem{
+						Update: &types.Update{
+							TableName: aws.String(config.EndpointTableName),
+							Key: map[string]types.AttributeValue{
+								"pk": &types.AttributeValueMemberS{Value: key[0]},
+								"sk": &types.AttributeValueMemberS{Value: key[1]},
+							},
+							// Don't recreate a registration that has since been
+							// removed.
+							ConditionExpression: aws.String("attribute_exists(#pk)"),
+							UpdateExpression:    aws.String("SET #url = :url, #disabled = :disabled, #modified = :modified, #modifiedBy = :modifiedBy"),
+							ExpressionAttributeNames: map[string]string{
+								"#pk":         "pk",
+								"#url":        "url",
+								"#disabled":   "disabled",
+								"#modified":   "modified",
+								"#modifiedBy": "modifiedBy",
+							},
+							ExpressionAttributeValues: map[string]types.AttributeValue{
+								":url":        &types.AttributeValueMemberS{Value: endpoint.URL},
+								":disabled":   &types.AttributeValueMemberBOOL{Value: endpoint.Disabled},
+								":modified":   values[":modified"],
+								":modifiedBy": values[":modifiedBy"],
+							},
+						},
+					})
+				}
+			}
+
+			err = transactWriteItems(r.Context(), dynamoClient, items)
+
+			// The endpoint was deleted between our read and write.
+			if conditionFailedAt(err, 0) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			// A registration was removed between our read and write, the
+			// update is written again for the registrations that remain.
+			if conditionFailed(err) && attempt < maxRegistrationAttempts {
+				continue
+			}
+
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to update endpoint", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			break
+		}
+
+		if err := publisher.Publish(r.Context(), events.TypeEndpointUpdated, events.EndpointUpdated{
+			EndpointID:   endpointid,
+			UserID:       endpoint.UserID,
+			Name:         endpoint.Name,
+			EndpointType: endpoint.EndpointType,
+			Enabled:      !endpoint.Disabled,
+			Agencies:     agencies,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, toEndpointResponse(endpoint)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEndpointTable returns a table holding the owner's webhook endpoint
+// registered to each of the agencies.
+func newEndpointTable(t *testing.T, agencies ...string) *fakeDynamoDB {
+	t.Helper()
+
+	client := new(fakeDynamoDB)
+	client.put(t, models.Endpoint{
+		KeyFields:        models.KeyFields{PK: "endpoint#endpoint-1", SK: "meta", Type: models.EntityTypeEndpoint},
+		UserID:           "owner",
+		Name:             "webhook",
+		EndpointType:     models.EndpointTypeWebhook,
+		URL:              "https://example.com/old",
+		RegistrationCode: "ABCD2345",
+	})
+	for _, agencyID := range agencies {
+		for _, key := range [][2]string{
+			{"endpoint#endpoint-1", "agency#" + agencyID},
+			{"agency#" + agencyID, "endpoint#endpoint-1"},
+		} {
+			client.put(t, models.Registration{
+				KeyFields:    models.KeyFields{PK: key[0], SK: key[1]},
+				Type:         models.EntityTypeRegistration,
+				URL:          "https://example.com/old",
+				EndpointType: models.EndpointTypeWebhook,
+			})
+		}
+	}
+	return client
+}
+
+// removeRegistration deletes both rows of the endpoint's registration to the
+// agency.
+func removeRegistration(client *fakeDynamoDB, agencyID string) {
+	delete(client.items, "endpoint#endpoint-1|agency#"+agencyID)
+	delete(client.items, "agency#"+agencyID+"|endpoint#endpoint-1")
+}
+
+// canceled returns the error of a transaction of n items canceled because the
+// condition of the item at index failed.
+func canceled(n, index int) error {
+	reasons := make([]types.CancellationReason, n)
+	for i := range reasons {
+		reasons[i].Code = aws.String("None")
+	}
+	reasons[index].Code = aws.String("ConditionalCheckFailed")
+	return &types.TransactionCanceledException{CancellationReasons: reasons}
+}
+
+func update(t *testing.T, client *fakeDynamoDB, sns *fakeSNS, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serve(
+		t,
+		updateEndpoint(Config{EndpointTableName: "endpoints"}, discardLogger, client, events.NewPublisher(sns, "topic", "endpoint")),
+		endpointOwner,
+		httptest.NewRequest(http.MethodPatch, "/endpoints/endpoint-1", strings.NewReader(body)),
+		map[string]string{"id": "endpoint-1"})
+}
+
+// updated returns the payload of the endpoint.updated event published.
+func updated(t *testing.T, sns *fakeSNS) events.EndpointUpdated {
+	t.Helper()
+
+	require.Len(t, sns.envelopes, 1)
+	require.Equal(t, events.TypeEndpointUpdated, sns.envelopes[0].Type)
+
+	var payload events.EndpointUpdated
+	require.NoError(t, json.Unmarshal(sns.envelopes[0].Payload, &payload))
+	return payload
+}
+
+func TestUpdateEndpoint(t *testing.T) {
+	var (
+		client = newEndpointTable(t, "agency-1", "agency-2")
+		sns    = new(fakeSNS)
+	)
+
+	w := update(t, client, sns, `{"url": "https://example.com/new"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// The endpoint and both rows of each registration are written together.
+	require.Len(t, client.transacts, 1)
+	items := client.transacts[0].TransactItems
+	require.Len(t, items, 5)
+
+	var keys []string
+	for _, item := range items {
+		require.NotNil(t, item.Update)
+		assert.Equal(t, "attribute_exists(#pk)", aws.ToString(item.Update.ConditionExpression))
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "https://example.com/new"}, item.Update.ExpressionAttributeValues[":url"])
+		keys = append(keys, itemKey(item.Update.Key))
+	}
+	assert.Equal(t, []string{
+		"endpoint#endpoint-1|meta",
+		"endpoint#endpoint-1|agency#agency-1",
+		"agency#agency-1|endpoint#endpoint-1",
+		"endpoint#endpoint-1|agency#agency-2",
+		"agency#agency-2|endpoint#endpoint-1",
+	}, keys)
+
+	assert.Equal(t, []string{"agency-1", "agency-2"}, updated(t, sns).Agencies)
+}
+
+func TestUpdateEndpointRegistrationRemoved(t *testing.T) {
+	var (
+		client = newEndpointTable(t, "agency-1", "agency-2")
+		sns    = new(fakeSNS)
+	)
+
+	// The registration to agency-2 is removed between reading the
+	// registrations and writing them.
+	client.transactWriteItems = func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+		if len(client.transacts) > 1 {
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		}
+		removeRegistration(client, "agency-2")
+		return nil, canceled(len(params.TransactItems), 3)
+	}
+
+	w := update(t, client, sns, `{"enabled": false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// The update is written again without the removed registration.
+	require.Len(t, client.transacts, 2)
+	assert.Len(t, client.transacts[1].TransactItems, 3)
+	assert.Equal(t, []string{"agency-1"}, updated(t, sns).Agencies)
+}
+
+func TestUpdateEndpointDeleted(t *testing.T) {
+	var (
+		client = newEndpointTable(t, "agency-1")
+		sns    = new(fakeSNS)
+	)
+
+	// The endpoint is deleted between reading it and writing it.
+	client.transactWriteItems = func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+		return nil, canceled(len(params.TransactItems), 0)
+	}
+
+	assert.Equal(t, http.StatusNotFound, update(t, client, sns, `{"name": "renamed"}`).Code)
+	assert.Len(t, client.transacts, 1)
+	assert.Empty(t, sns.envelopes)
+}
+
+func TestUpdateEndpointChunked(t *testing.T) {
+	var agencies []string
+	for i := range 60 {
+		agencies = append(agencies, fmt.Sprintf("agency-%02d", i))
+	}
+
+	t.Run("written", func(t *testing.T) {
+		var (
+			client = newEndpointTable(t, agencies...)
+			sns    = new(fakeSNS)
+		)
+
+		require.Equal(t, http.StatusOK, update(t, client, sns, `{"name": "renamed"}`).Code)
+
+		// The endpoint and 120 registration rows don't fit in one transaction.
+		require.Len(t, client.transacts, 2)
+		assert.Len(t, client.transacts[0].TransactItems, maxTransactItems)
+		assert.Len(t, client.transacts[1].TransactItems, 21)
+		assert.Len(t, updated(t, sns).Agencies, 60)
+	})
+
+	t.Run("second transaction fails", func(t *testing.T) {
+		var (
+			client = newEndpointTable(t, agencies...)
+			sns    = new(fakeSNS)
+		)
+
+		// The first item of the second transaction is a registration row, its
+		// condition failing isn't the endpoint being deleted.
+		client.transactWriteItems = func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+			if len(client.transacts)%2 == 0 {
+				return nil, canceled(len(params.TransactItems), 0)
+			}
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		}
+
+		assert.Equal(t, http.StatusInternalServerError, update(t, client, sns, `{"name": "renamed"}`).Code)
+		assert.Len(t, client.transacts, 2*maxRegistrationAttempts)
+		assert.Empty(t, sns.envelopes)
+	})
+}
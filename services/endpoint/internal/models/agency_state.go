@@ -0,0 +1,22 @@
+package models
+
+type AgencyStatus = string
+
+const (
+	AgencyStatusActive   AgencyStatus = "ACTIVE"
+	AgencyStatusInactive AgencyStatus = "INACTIVE"
+)
+
+// AgencyState is this service's copy of the status of an agency, kept in step
+// with the agency service by the agency.deactivated and agency.reactivated
+// events. It is stored under pk agency#<id> and sk status, alongside the
+// agency's registrations.
+type AgencyState struct {
+	KeyFields
+	AuditableFields
+	Status AgencyStatus `dynamodbav:"status"`
+	// StatusModified is when the status changed in unix nanoseconds. Events
+	// delivered out of order are told apart by comparing it, which the
+	// formatted modified time can't be relied on for.
+	StatusModified int64 `dynamodbav:"statusModified"`
+}
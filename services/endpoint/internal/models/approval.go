@@ -0,0 +1,23 @@
+package models
+
+type ApprovalStatus = string
+
+const (
+	ApprovalStatusPending  ApprovalStatus = "PENDING"
+	ApprovalStatusApproved ApprovalStatus = "APPROVED"
+	ApprovalStatusDeclined ApprovalStatus = "DECLINED"
+)
+
+// Approval is a request by an agency to register an endpoint, which the owner
+// of the endpoint must approve before the registration is completed. The
+// endpoint and agency are encoded within the pk (endpoint#<endpointId>) and sk
+// (approval#<agencyId>).
+type Approval struct {
+	KeyFields
+	AuditableFields
+	Status           ApprovalStatus `dynamodbav:"status"`
+	AgencyID         string         `dynamodbav:"agencyId"`
+	RegistrationCode string         `dynamodbav:"registrationCode"`
+	// RequestedBy is the agency writer that registered the endpoint.
+	RequestedBy string `dynamodbav:"requestedBy"`
+}
@@ -0,0 +1,25 @@
+package models
+
+type DeliveryStatus = string
+
+const (
+	DeliveryStatusSucceeded DeliveryStatus = "SUCCEEDED"
+	DeliveryStatusFailed    DeliveryStatus = "FAILED"
+)
+
+// Delivery tracks the delivery of a page to a single endpoint across
+// invocations of the worker. The page and endpoint are encoded within the pk
+// (delivery#<pageId>) and sk (endpoint#<endpointId>). Once a delivery has
+// succeeded the endpoint is skipped when the page is redelivered.
+type Delivery struct {
+	KeyFields
+	AuditableFields
+	Status         DeliveryStatus `dynamodbav:"status"`
+	Attempts       int            `dynamodbav:"attempts"`
+	LastStatusCode int            `dynamodbav:"lastStatusCode"`
+	LastError      string         `dynamodbav:"lastError"`
+	// ProviderMessageIDs are the identifiers assigned by the provider, such as
+	// the Twilio message SIDs of each SMS segment.
+	ProviderMessageIDs []string `dynamodbav:"providerMessageIds,omitempty"`
+	TTL                int64    `dynamodbav:"ttl"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Endpoint represents an Endpoint that can be used to send notifications.
 // Endpoints are registered to an agency.
 type Endpoint struct {
@@ -11,4 +13,42 @@ type Endpoint struct {
 	Registrations    map[string]any `dynamodbav:"registrations"`
 	UserID           string         `dynamodbav:"userId"`
 	RegistrationCode string         `dynamodbav:"registrationCode"`
+	// RegistrationCodeExpires is when RegistrationCode stops being accepted.
+	RegistrationCodeExpires *time.Time `dynamodbav:"registrationCodeExpires,omitempty"`
+	// PushPlatform and PushToken identify the device of a PUSH endpoint.
+	PushPlatform PushPlatform `dynamodbav:"pushPlatform,omitempty"`
+	PushToken    string       `dynamodbav:"pushToken,omitempty"`
+	// PhoneNumber is the E.164 number an SMS endpoint delivers to.
+	PhoneNumber string `dynamodbav:"phoneNumber,omitempty"`
+	// EmailAddress is the address an EMAIL endpoint delivers to.
+	EmailAddress string `dynamodbav:"emailAddress,omitempty"`
+	// Disabled endpoints are skipped during delivery. DisabledReason records
+	// why, for example when a push provider reports the token is no longer
+	// valid.
+	Disabled       bool   `dynamodbav:"disabled,omitempty"`
+	DisabledReason string `dynamodbav:"disabledReason,omitempty"`
+	// Suspended endpoints belong to a deactivated user. They are skipped
+	// during delivery like disabled endpoints, but suspension is lifted when
+	// the user is reactivated without changing what the owner chose.
+	Suspended bool `dynamodbav:"suspended,omitempty"`
+	// SigningSecret is used to sign deliveries to WEBHOOK endpoints.
+	SigningSecret string `dynamodbav:"signingSecret,omitempty"`
+	// PreviousSigningSecret is the secret that was replaced by the last
+	// rotation. Deliveries are also signed with it until
+	// PreviousSigningSecretExpires so receivers have time to switch over.
+	PreviousSigningSecret        string     `dynamodbav:"previousSigningSecret,omitempty"`
+	PreviousSigningSecretExpires *time.Time `dynamodbav:"previousSigningSecretExpires,omitempty"`
+}
+
+// SigningSecrets returns the secrets a delivery made at the given time should
+// be signed with, newest first.
+func (e Endpoint) SigningSecrets(now time.Time) []string {
+	var secrets []string
+	if e.SigningSecret != "" {
+		secrets = append(secrets, e.SigningSecret)
+	}
+	if e.PreviousSigningSecret != "" && e.PreviousSigningSecretExpires != nil && now.Before(*e.PreviousSigningSecretExpires) {
+		secrets = append(secrets, e.PreviousSigningSecret)
+	}
+	return secrets
 }
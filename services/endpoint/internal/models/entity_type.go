@@ -7,4 +7,8 @@ const (
 	EntityTypeRegistrationCode = "REGISTRATION_CODE"
 	EntityTypeOwner            = "OWNER"
 	EntityTypeRegistration     = "REGISTRATION"
+	EntityTypeDelivery         = "DELIVERY"
+	EntityTypeApproval         = "APPROVAL"
+	EntityTypeAgencyState      = "AGENCY_STATE"
+	EntityTypeUserState        = "USER_STATE"
 )
@@ -9,6 +9,15 @@ type EndpointType = string
 const (
 	EndpointTypePush    EndpointType = "PUSH"
 	EndpointTypeWebhook EndpointType = "WEBHOOK"
+	EndpointTypeSMS     EndpointType = "SMS"
+	EndpointTypeEmail   EndpointType = "EMAIL"
+)
+
+type PushPlatform = string
+
+const (
+	PushPlatformFCM  PushPlatform = "FCM"
+	PushPlatformAPNS PushPlatform = "APNS"
 )
 
 type KeyFields struct {
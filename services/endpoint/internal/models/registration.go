@@ -6,4 +6,7 @@ type Registration struct {
 	Type         EntityType   `dynamodbav:"type"`
 	URL          string       `dynamodbav:"url"`
 	EndpointType EndpointType `dynamodbav:"endpointType"`
+	// Disabled mirrors the endpoint so deliveries can skip it without reading
+	// the endpoint.
+	Disabled bool `dynamodbav:"disabled,omitempty"`
 }
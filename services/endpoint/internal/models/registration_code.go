@@ -1,10 +1,79 @@
 package models
 
+import (
+	"crypto/rand"
+	"strings"
+	"time"
+)
+
 // RegistrationCode represents a registration of an endpoint to an account. The
 // endpoint must be registered to an account before it can be used.
+// Codes are only valid until Expires, the row itself is kept until TTL so that
+// an expired code can be told apart from one that never existed.
 type RegistrationCode struct {
 	KeyFields
 	AuditableFields
-	EndpointID string `dynamodbav:"endpointId"`
-	UserID     string `dynamodbav:"userId"`
+	EndpointID string    `dynamodbav:"endpointId"`
+	UserID     string    `dynamodbav:"userId"`
+	Expires    time.Time `dynamodbav:"expires"`
+	TTL        int64     `dynamodbav:"ttl"`
+}
+
+// Expired reports whether the code can no longer be used to register the
+// endpoint. Codes created before codes expired have no expiry.
+func (rc RegistrationCode) Expired(now time.Time) bool {
+	return !rc.Expires.IsZero() && !now.Before(rc.Expires)
+}
+
+// RegistrationCodeLength is the number of characters in a registration code.
+const RegistrationCodeLength = 8
+
+// crockfordAlphabet is the Crockford base32 alphabet. It leaves out I, L, O and
+// U so codes can't be misread.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewRegistrationCode returns a random registration code, 8 characters of
+// Crockford base32 (40 bits).
+func NewRegistrationCode() (string, error) {
+	b := make([]byte, RegistrationCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, RegistrationCodeLength)
+	for i := range b {
+		// 256 is a multiple of 32 so masking keeps every character equally
+		// likely.
+		code[i] = crockfordAlphabet[b[i]&31]
+	}
+
+	return string(code), nil
+}
+
+// NormalizeRegistrationCode converts a code as typed by a person into its
+// canonical form. Case, hyphens and spaces are ignored and the characters
+// Crockford base32 leaves out are read as the digits they look like. Anything
+// that isn't the length of a registration code, such as the long codes issued
+// before codes were shortened, is returned unchanged.
+func NormalizeRegistrationCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ':
+			return -1
+		case 'i', 'I', 'l', 'L':
+			return '1'
+		case 'o', 'O':
+			return '0'
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}, code)
+
+	if len(normalized) != RegistrationCodeLength {
+		return code
+	}
+
+	return normalized
 }
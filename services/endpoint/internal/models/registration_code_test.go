@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRegistrationCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		code, err := NewRegistrationCode()
+		require.NoError(t, err)
+
+		assert.Len(t, code, RegistrationCodeLength)
+		for _, r := range code {
+			assert.Contains(t, crockfordAlphabet, string(r))
+		}
+
+		// A new code is already in its normalized form.
+		assert.Equal(t, code, NormalizeRegistrationCode(code))
+
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+	}
+}
+
+func TestNormalizeRegistrationCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{name: "canonical", code: "ABCD2345", want: "ABCD2345"},
+		{name: "lower case", code: "abcd2345", want: "ABCD2345"},
+		{name: "hyphenated", code: "ABCD-2345", want: "ABCD2345"},
+		{name: "spaced", code: " ABCD 2345 ", want: "ABCD2345"},
+		{name: "look alike letters", code: "iLoO-lIo0", want: "11001100"},
+		{name: "too short", code: "ABC-234", want: "ABC-234"},
+		{name: "legacy", code: "0f8fad5b-d9cb-469f-a165-70867728950e", want: "0f8fad5b-d9cb-469f-a165-70867728950e"},
+		{name: "empty", code: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeRegistrationCode(tt.code))
+		})
+	}
+}
+
+func TestRegistrationCodeExpired(t *testing.T) {
+	var (
+		now     = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		expires = now.Add(15 * time.Minute)
+	)
+
+	tests := []struct {
+		name    string
+		expires time.Time
+		now     time.Time
+		want    bool
+	}{
+		{name: "before expiry", expires: expires, now: now, want: false},
+		{name: "at expiry", expires: expires, now: expires, want: true},
+		{name: "after expiry", expires: expires, now: expires.Add(time.Nanosecond), want: true},
+		{name: "no expiry", now: now.Add(100 * 365 * 24 * time.Hour), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RegistrationCode{Expires: tt.expires}.Expired(tt.now))
+		})
+	}
+}
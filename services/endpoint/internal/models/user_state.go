@@ -0,0 +1,16 @@
+package models
+
+import "github.com/jsmithdenverdev/pager/pkg/identity"
+
+// UserState is this service's copy of the status of a user, kept in step with
+// the user service by the user.deactivated and user.reactivated events. It is
+// stored under pk user#<id> and sk status, alongside the user's endpoints.
+type UserState struct {
+	KeyFields
+	AuditableFields
+	Status identity.Status `dynamodbav:"status"`
+	// StatusModified is when the status changed in unix nanoseconds. Events
+	// delivered out of order are told apart by comparing it, which the
+	// formatted modified time can't be relied on for.
+	StatusModified int64 `dynamodbav:"statusModified"`
+}
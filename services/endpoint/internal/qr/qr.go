@@ -0,0 +1,478 @@
+// Package qr encodes short text, such as registration links, as QR codes and
+// renders them as PNG or SVG images.
+//
+// Only what the endpoint service needs is implemented: byte mode, error
+// correction level M and versions 1 through 10, which holds up to 213 bytes.
+package qr
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrTooLong is returned when the text doesn't fit in the largest supported
+// version.
+var ErrTooLong = errors.New("qr: text too long")
+
+// Code is an encoded QR code, a square grid of dark and light modules.
+type Code struct {
+	// Size is the width and height of the code in modules, not including the
+	// quiet zone.
+	Size     int
+	modules  []bool
+	function []bool
+}
+
+// Dark reports whether the module at column x and row y is dark. Modules
+// outside the code are light.
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y*c.Size+x]
+}
+
+// blockLayout describes how the codewords of a version are split into error
+// correction blocks at level M.
+type blockLayout struct {
+	ecPerBlock int
+	// groups holds the number of blocks and data codewords per block of each
+	// group, the blocks of the second group are one codeword longer.
+	groups [][2]int
+}
+
+// layouts is indexed by version.
+var layouts = [...]blockLayout{
+	1:  {10, [][2]int{{1, 16}}},
+	2:  {16, [][2]int{{1, 28}}},
+	3:  {26, [][2]int{{1, 44}}},
+	4:  {18, [][2]int{{2, 32}}},
+	5:  {24, [][2]int{{2, 43}}},
+	6:  {16, [][2]int{{4, 27}}},
+	7:  {18, [][2]int{{4, 31}}},
+	8:  {22, [][2]int{{2, 38}, {2, 39}}},
+	9:  {22, [][2]int{{3, 36}, {2, 37}}},
+	10: {26, [][2]int{{4, 43}, {1, 44}}},
+}
+
+// alignmentPositions holds the row and column centers of the alignment
+// patterns of each version.
+var alignmentPositions = [...][]int{
+	1:  nil,
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+const maxVersion = 10
+
+// dataCodewords returns the number of data codewords in a version.
+func (l blockLayout) dataCodewords() int {
+	var n int
+	for _, group := range l.groups {
+		n += group[0] * group[1]
+	}
+	return n
+}
+
+// Encode encodes text as a QR code using the smallest version it fits in.
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+
+	version := 0
+	for v := 1; v <= maxVersion; v++ {
+		// Mode indicator, character count and the data itself.
+		if 4+countBits(v)+len(data)*8 <= layouts[v].dataCodewords()*8 {
+			version = v
+			break
+		}
+	}
+
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	code := newCode(version)
+	code.drawCodewords(interleave(version, encodeData(version, data)))
+	code.applyBestMask()
+
+	return code, nil
+}
+
+// countBits is the length of the character count field in byte mode.
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// encodeData builds the data codewords: the byte mode segment, a terminator
+// and padding up to the capacity of the version.
+func encodeData(version int, data []byte) []byte {
+	var (
+		capacity = layouts[version].dataCodewords() * 8
+		bits     bitBuffer
+	)
+
+	bits.append(0b0100, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	bits.append(0, min(4, capacity-bits.len()))
+	bits.append(0, (8-bits.len()%8)%8)
+
+	for pad := 0xEC; bits.len() < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	return bits.bytes()
+}
+
+// interleave splits the data codewords into blocks, computes the error
+// correction codewords of each and interleaves them in the order they are
+// placed in the code.
+func interleave(version int, data []byte) []byte {
+	var (
+		layout    = layouts[version]
+		generator = rsGenerator(layout.ecPerBlock)
+		dataBlock [][]byte
+		ecBlock   [][]byte
+	)
+
+	for _, group := range layout.groups {
+		for range group[0] {
+			block := data[:group[1]]
+			data = data[group[1]:]
+			dataBlock = append(dataBlock, block)
+			ecBlock = append(ecBlock, rsRemainder(block, generator))
+		}
+	}
+
+	var result []byte
+
+	longest := len(dataBlock[len(dataBlock)-1])
+	for i := range longest {
+		for _, block := range dataBlock {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+
+	for i := range layout.ecPerBlock {
+		for _, block := range ecBlock {
+			result = append(result, block[i])
+		}
+	}
+
+	return result
+}
+
+// newCode returns a code of the given version with its function patterns
+// drawn. Format information is reserved but not yet set.
+func newCode(version int) *Code {
+	size := version*4 + 17
+
+	c := &Code{
+		Size:     size,
+		modules:  make([]bool, size*size),
+		function: make([]bool, size*size),
+	}
+
+	for i := range size {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(size-4, 3)
+	c.drawFinder(3, size-4)
+
+	positions := alignmentPositions[version]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// Alignment patterns would overlap the finder patterns.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	c.drawFormat(0)
+	c.drawVersion(version)
+
+	return c
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y*c.Size+x] = dark
+	c.function[y*c.Size+x] = true
+}
+
+// drawFinder draws a finder pattern and its separator centered on x, y.
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= c.Size || yy >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern centered on x, y.
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information for level M and the
+// given mask, along with the dark module.
+func (c *Code) drawFormat(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := range 6 {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := range 8 {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+
+	c.setFunction(8, c.Size-8, true)
+}
+
+// formatBits returns the 15 bit format information for level M and a mask.
+func formatBits(mask int) int {
+	// Level M is encoded as 00 so the data is just the mask.
+	data := mask
+	rem := data
+	for range 10 {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// drawVersion draws both copies of the version information, which is only
+// present from version 7.
+func (c *Code) drawVersion(version int) {
+	if version < 7 {
+		return
+	}
+
+	bits := versionBits(version)
+	for i := range 18 {
+		dark := (bits>>i)&1 == 1
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// versionBits returns the 18 bit version information.
+func versionBits(version int) int {
+	rem := version
+	for range 12 {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return version<<12 | rem
+}
+
+// drawCodewords places the codewords in the zigzag order, two columns at a
+// time from the bottom right, skipping function modules. Modules left over
+// once the codewords run out are the remainder bits and stay light.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		// The vertical timing pattern is skipped entirely.
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.Size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if c.function[y*c.Size+x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y*c.Size+x] = (codewords[i>>3]>>(7-i&7))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// masks are the eight data masks, a module is inverted where its mask is true.
+var masks = [8]func(x, y int) bool{
+	func(x, y int) bool { return (x+y)%2 == 0 },
+	func(x, y int) bool { return y%2 == 0 },
+	func(x, y int) bool { return x%3 == 0 },
+	func(x, y int) bool { return (x+y)%3 == 0 },
+	func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+	func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+	func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+	func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+}
+
+// applyMask inverts the data modules selected by a mask. Applying the same mask
+// twice undoes it.
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.function[y*c.Size+x] && masks[mask](x, y) {
+				c.modules[y*c.Size+x] = !c.modules[y*c.Size+x]
+			}
+		}
+	}
+}
+
+// applyBestMask applies the mask with the lowest penalty score, the one that
+// makes the code easiest to scan.
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, math.MaxInt
+	for mask := range masks {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if penalty := c.penalty(); penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask)
+	}
+
+	c.applyMask(best)
+	c.drawFormat(best)
+}
+
+// penalty scores the code using the four rules of the QR specification.
+func (c *Code) penalty() int {
+	var penalty int
+
+	// Runs of five or more modules of the same color, and the finder-like
+	// 1:1:3:1:1 pattern with four light modules on either side.
+	finderLike := []bool{true, false, true, true, true, false, true, false, false, false, false}
+	for _, line := range c.lines() {
+		run := 1
+		for i := 1; i <= len(line); i++ {
+			if i < len(line) && line[i] == line[i-1] {
+				run++
+				continue
+			}
+			if run >= 5 {
+				penalty += 3 + run - 5
+			}
+			run = 1
+		}
+
+		for i := 0; i+len(finderLike) <= len(line); i++ {
+			forward, backward := true, true
+			for j, dark := range finderLike {
+				forward = forward && line[i+j] == dark
+				backward = backward && line[i+len(finderLike)-1-j] == dark
+			}
+			if forward {
+				penalty += 40
+			}
+			if backward {
+				penalty += 40
+			}
+		}
+	}
+
+	// 2x2 blocks of the same color.
+	for y := 0; y < c.Size-1; y++ {
+		for x := 0; x < c.Size-1; x++ {
+			dark := c.Dark(x, y)
+			if c.Dark(x+1, y) == dark && c.Dark(x, y+1) == dark && c.Dark(x+1, y+1) == dark {
+				penalty += 3
+			}
+		}
+	}
+
+	// Deviation of the proportion of dark modules from half, in steps of 5%.
+	var dark int
+	for _, module := range c.modules {
+		if module {
+			dark++
+		}
+	}
+	total := c.Size * c.Size
+	penalty += abs(dark*20-total*10) / total * 10
+
+	return penalty
+}
+
+// lines returns every row and column of the code.
+func (c *Code) lines() [][]bool {
+	lines := make([][]bool, 0, c.Size*2)
+	for y := range c.Size {
+		lines = append(lines, c.modules[y*c.Size:(y+1)*c.Size])
+	}
+	for x := range c.Size {
+		column := make([]bool, c.Size)
+		for y := range c.Size {
+			column[y] = c.modules[y*c.Size+x]
+		}
+		lines = append(lines, column)
+	}
+	return lines
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// bitBuffer accumulates a big-endian sequence of bits.
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+func (b *bitBuffer) len() int {
+	return len(*b)
+}
+
+func (b *bitBuffer) bytes() []byte {
+	result := make([]byte, (len(*b)+7)/8)
+	for i, bit := range *b {
+		if bit {
+			result[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return result
+}
@@ -0,0 +1,233 @@
+package qr
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// HELLO WORLD encoded at 1-M, from the worked example in the specification.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	assert.Equal(t, want, rsRemainder(data, rsGenerator(10)))
+}
+
+func TestFormatBits(t *testing.T) {
+	assert.Equal(t, 0b101010000010010, formatBits(0))
+	assert.Equal(t, 0b100000011001110, formatBits(5))
+	assert.Equal(t, 0b100101010100000, formatBits(7))
+}
+
+func TestVersionBits(t *testing.T) {
+	assert.Equal(t, 0b000111110010010100, versionBits(7))
+	assert.Equal(t, 0b001010010011010011, versionBits(10))
+}
+
+func TestEncode(t *testing.T) {
+	tests := map[string]struct {
+		text    string
+		version int
+	}{
+		"empty":              {"", 1},
+		"registration link":  {"pager://register-endpoint?code=7KQM2XHD", 3},
+		"largest version 1":  {strings.Repeat("a", 14), 1},
+		"smallest version 2": {strings.Repeat("a", 15), 2},
+		"version 7":          {strings.Repeat("a", 120), 7},
+		"version 10":         {strings.Repeat("a", 213), 10},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			code, err := Encode(test.text)
+			require.NoError(t, err)
+			assert.Equal(t, test.version*4+17, code.Size)
+			assert.Equal(t, test.text, decode(t, code))
+		})
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	_, err := Encode(strings.Repeat("a", 214))
+	assert.ErrorIs(t, err, ErrTooLong)
+}
+
+func TestPNG(t *testing.T) {
+	code, err := Encode("pager://register-endpoint?code=7KQM2XHD")
+	require.NoError(t, err)
+
+	b, err := code.PNG(4)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+
+	width := (code.Size + quietZone*2) * 4
+	assert.Equal(t, width, img.Bounds().Dx())
+	assert.Equal(t, width, img.Bounds().Dy())
+
+	// The top left module of the finder pattern is dark and the quiet zone
+	// around it is light.
+	r, _, _, _ := img.At(quietZone*4, quietZone*4).RGBA()
+	assert.Zero(t, r)
+	r, _, _, _ = img.At(quietZone*4-1, quietZone*4).RGBA()
+	assert.NotZero(t, r)
+}
+
+func TestSVG(t *testing.T) {
+	code, err := Encode("pager://register-endpoint?code=7KQM2XHD")
+	require.NoError(t, err)
+
+	svg := string(code.SVG())
+	assert.Contains(t, svg, `viewBox="0 0 37 37"`)
+	assert.Contains(t, svg, "M4,4h1v1h-1z")
+}
+
+// decode reads the text back out of a code, checking the format information
+// and error correction along the way.
+func decode(t *testing.T, code *Code) string {
+	t.Helper()
+
+	version := (code.Size - 17) / 4
+
+	var format int
+	for i := range 6 {
+		format |= bit(code.Dark(8, i)) << i
+	}
+	format |= bit(code.Dark(8, 7)) << 6
+	format |= bit(code.Dark(8, 8)) << 7
+	format |= bit(code.Dark(7, 8)) << 8
+	for i := 9; i < 15; i++ {
+		format |= bit(code.Dark(14-i, 8)) << i
+	}
+
+	var second int
+	for i := range 8 {
+		second |= bit(code.Dark(code.Size-1-i, 8)) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(code.Dark(8, code.Size-15+i)) << i
+	}
+
+	require.Equal(t, format, second, "format information copies differ")
+	mask := (format ^ 0x5412) >> 10 & 7
+	require.Equal(t, formatBits(mask), format)
+
+	// Unmask a copy of the modules, function modules are left as they are.
+	unmasked := newCode(version)
+	copy(unmasked.modules, code.modules)
+	unmasked.applyMask(mask)
+
+	layout := layouts[version]
+	total := layout.dataCodewords() + layout.ecPerBlock*blockCount(layout)
+
+	var bits bitBuffer
+	for right := code.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range code.Size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = code.Size - 1 - vert
+				}
+				if unmasked.function[y*code.Size+x] || bits.len() >= total*8 {
+					continue
+				}
+				bits = append(bits, unmasked.modules[y*code.Size+x])
+			}
+		}
+	}
+	codewords := bits.bytes()
+
+	// Deinterleave the blocks and check each is a valid Reed-Solomon codeword.
+	var blocks [][]byte
+	for _, group := range layout.groups {
+		for range group[0] {
+			blocks = append(blocks, make([]byte, 0, group[1]+layout.ecPerBlock))
+		}
+	}
+	longest := layout.groups[len(layout.groups)-1][1]
+	for i := range longest {
+		for b := range blocks {
+			if i < blockDataLength(layout, b) {
+				blocks[b] = append(blocks[b], codewords[0])
+				codewords = codewords[1:]
+			}
+		}
+	}
+	for range layout.ecPerBlock {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[0])
+			codewords = codewords[1:]
+		}
+	}
+
+	var data []byte
+	for b, block := range blocks {
+		var root byte = 1
+		for range layout.ecPerBlock {
+			var syndrome byte
+			for _, c := range block {
+				syndrome = gfMultiply(syndrome, root) ^ c
+			}
+			require.Zero(t, syndrome, "block %d has errors", b)
+			root = gfMultiply(root, 0x02)
+		}
+		data = append(data, block[:blockDataLength(layout, b)]...)
+	}
+
+	var stream bitBuffer
+	for _, b := range data {
+		stream.append(int(b), 8)
+	}
+	read := func(n int) int {
+		var v int
+		for _, dark := range stream[:n] {
+			v = v<<1 | bit(dark)
+		}
+		stream = stream[n:]
+		return v
+	}
+
+	require.Equal(t, 0b0100, read(4), "not byte mode")
+	length := read(countBits(version))
+	text := make([]byte, length)
+	for i := range text {
+		text[i] = byte(read(8))
+	}
+
+	return string(text)
+}
+
+func blockCount(layout blockLayout) int {
+	var n int
+	for _, group := range layout.groups {
+		n += group[0]
+	}
+	return n
+}
+
+func blockDataLength(layout blockLayout, block int) int {
+	for _, group := range layout.groups {
+		if block < group[0] {
+			return group[1]
+		}
+		block -= group[0]
+	}
+	return 0
+}
+
+func bit(dark bool) int {
+	if dark {
+		return 1
+	}
+	return 0
+}
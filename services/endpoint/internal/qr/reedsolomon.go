@@ -0,0 +1,49 @@
+package qr
+
+// gfMultiply multiplies two elements of GF(2^8) modulo the QR code polynomial
+// x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(a, b byte) byte {
+	var result int
+	for i := 7; i >= 0; i-- {
+		result = (result << 1) ^ ((result >> 7) * 0x11D)
+		if (b>>i)&1 == 1 {
+			result ^= int(a)
+		}
+	}
+	return byte(result)
+}
+
+// rsGenerator returns the coefficients of the Reed-Solomon generator polynomial
+// of the given degree, highest power first and excluding the leading 1.
+func rsGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	var root byte = 1
+	for range degree {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+
+	return result
+}
+
+// rsRemainder returns the error correction codewords of data, the remainder of
+// dividing it by the generator polynomial.
+func rsRemainder(data, generator []byte) []byte {
+	result := make([]byte, len(generator))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range generator {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// quietZone is the width in modules of the light border scanners need around a
+// code.
+const quietZone = 4
+
+// Image returns the code as a grayscale image, each module scale pixels wide,
+// surrounded by the quiet zone.
+func (c *Code) Image(scale int) image.Image {
+	scale = max(scale, 1)
+	width := (c.Size + quietZone*2) * scale
+
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for py := range width {
+		for px := range width {
+			shade := color.White
+			if c.Dark(px/scale-quietZone, py/scale-quietZone) {
+				shade = color.Black
+			}
+			img.Set(px, py, shade)
+		}
+	}
+
+	return img
+}
+
+// PNG returns the code encoded as a PNG, each module scale pixels wide.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image(scale)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG returns the code as an SVG document measured in modules, so it can be
+// scaled to any size without blurring.
+func (c *Code) SVG() []byte {
+	var (
+		width = c.Size + quietZone*2
+		path  strings.Builder
+	)
+
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.Dark(x, y) {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", width, width)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="#FFFFFF"/>`+"\n")
+	fmt.Fprintf(&buf, `<path d="%s" fill="#000000"/>`+"\n", path.String())
+	fmt.Fprintf(&buf, "</svg>\n")
+
+	return buf.Bytes()
+}
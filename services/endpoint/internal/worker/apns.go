@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apnsTokenLifetime is how long a provider token is reused. APNs rejects
+// tokens older than an hour and throttles ones refreshed more often than every
+// 20 minutes.
+const apnsTokenLifetime = 45 * time.Minute
+
+// apnsClient sends notifications with the APNs HTTP/2 provider API using
+// token based authentication.
+type apnsClient struct {
+	client  *http.Client
+	baseURL string
+	keyID   string
+	teamID  string
+	topic   string
+	key     *ecdsa.PrivateKey
+
+	mu     sync.Mutex
+	jwt    string
+	issued time.Time
+}
+
+func newAPNSClient(client *http.Client, baseURL, keyID, teamID, topic, privateKey string) (*apnsClient, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, errors.New("apns private key is not PEM encoded")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse apns private key: %w", err)
+	}
+
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apns private key is not an ECDSA key")
+	}
+
+	return &apnsClient{
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		keyID:   keyID,
+		teamID:  teamID,
+		topic:   topic,
+		key:     key,
+	}, nil
+}
+
+// send makes a single attempt to deliver the notification to a device token.
+func (c *apnsClient) send(ctx context.Context, token string, notification notification) (bool, int, error) {
+	jwt, err := c.token()
+	if err != nil {
+		return false, 0, err
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"aps": map[string]any{
+			"alert": map[string]string{
+				"title": notification.Title,
+			},
+			"sound": "default",
+		},
+		"pageId":     notification.PageID,
+		"endpointId": notification.EndpointID,
+	})
+	if err != nil {
+		return false, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/3/device/%s", c.baseURL, token), bytes.NewReader(payload))
+	if err != nil {
+		return false, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+jwt)
+	req.Header.Set("apns-topic", c.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, resp.StatusCode, nil
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		body.Reason == "BadDeviceToken",
+		body.Reason == "DeviceTokenNotForTopic",
+		body.Reason == "Unregistered":
+		return false, resp.StatusCode, fmt.Errorf("%w: apns: %s", errEndpointInvalid, body.Reason)
+	case body.Reason == "ExpiredProviderToken":
+		c.mu.Lock()
+		c.jwt = ""
+		c.mu.Unlock()
+		return true, resp.StatusCode, fmt.Errorf("apns: %s", body.Reason)
+	}
+
+	return retryableStatus(resp.StatusCode), resp.StatusCode, fmt.Errorf("apns: unexpected status code %d: %s", resp.StatusCode, body.Reason)
+}
+
+// token returns the provider token, signing a new one once the current one has
+// been in use for apnsTokenLifetime.
+func (c *apnsClient) token() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	if c.jwt != "" && now.Sub(c.issued) < apnsTokenLifetime {
+		return c.jwt, nil
+	}
+
+	jwt, err := signJWT(
+		map[string]string{"alg": "ES256", "kid": c.keyID},
+		map[string]any{
+			"iss": c.teamID,
+			"iat": now.Unix(),
+		},
+		func(digest []byte) ([]byte, error) {
+			r, s, err := ecdsa.Sign(rand.Reader, c.key, digest)
+			if err != nil {
+				return nil, err
+			}
+			// JWS ES256 signatures are the fixed width concatenation of r and s.
+			signature := make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+			return signature, nil
+		})
+	if err != nil {
+		return "", err
+	}
+
+	c.jwt = jwt
+	c.issued = now
+
+	return c.jwt, nil
+}
@@ -1,11 +1,41 @@
 package worker
 
-import "log/slog"
+import (
+	"log/slog"
+	"time"
+)
 
 type Config struct {
-	LogLevel          slog.Level `env:"LOG_LEVEL"`
-	Environment       string     `env:"ENVIRONMENT"`
-	EndpointTableName string     `env:"ENDPOINT_TABLE_NAME"`
-	EventsTopicARN    string     `env:"EVENTS_TOPIC_ARN"`
-	EventRetryCount   int        `env:"EVENT_RETRY_COUNT"`
+	LogLevel            slog.Level    `env:"LOG_LEVEL"`
+	Environment         string        `env:"ENVIRONMENT"`
+	EndpointTableName   string        `env:"ENDPOINT_TABLE_NAME"`
+	EventsTopicARN      string        `env:"EVENTS_TOPIC_ARN"`
+	EventRetryCount     int           `env:"EVENT_RETRY_COUNT"`
+	DeliveryTimeout     time.Duration `env:"DELIVERY_TIMEOUT" envDefault:"3s"`
+	DeliveryMaxAttempts int           `env:"DELIVERY_MAX_ATTEMPTS" envDefault:"3"`
+	DeliveryBaseBackoff time.Duration `env:"DELIVERY_BASE_BACKOFF" envDefault:"200ms"`
+	DeliveryMaxBackoff  time.Duration `env:"DELIVERY_MAX_BACKOFF" envDefault:"2s"`
+	DeliveryConcurrency int           `env:"DELIVERY_CONCURRENCY" envDefault:"10"`
+	DeliveryRecordTTL   time.Duration `env:"DELIVERY_RECORD_TTL" envDefault:"168h"`
+	EventLedgerLease    time.Duration `env:"EVENT_LEDGER_LEASE" envDefault:"180s"`
+	EventLedgerTTL      time.Duration `env:"EVENT_LEDGER_TTL" envDefault:"336h"`
+	OutboxRecordTTL     time.Duration `env:"OUTBOX_RECORD_TTL" envDefault:"168h"`
+	OutboxSweepAge      time.Duration `env:"OUTBOX_SWEEP_AGE" envDefault:"15m"`
+	FCMBaseURL          string        `env:"FCM_BASE_URL" envDefault:"https://fcm.googleapis.com"`
+	FCMCredentials      string        `env:"FCM_CREDENTIALS"`
+	APNSBaseURL         string        `env:"APNS_BASE_URL" envDefault:"https://api.push.apple.com"`
+	APNSKeyID           string        `env:"APNS_KEY_ID"`
+	APNSTeamID          string        `env:"APNS_TEAM_ID"`
+	APNSTopic           string        `env:"APNS_TOPIC"`
+	APNSPrivateKey      string        `env:"APNS_PRIVATE_KEY"`
+	SMSBaseURL          string        `env:"SMS_BASE_URL" envDefault:"https://api.twilio.com"`
+	SMSAccountSID       string        `env:"SMS_ACCOUNT_SID"`
+	SMSAuthToken        string        `env:"SMS_AUTH_TOKEN"`
+	SMSFromNumber       string        `env:"SMS_FROM_NUMBER"`
+	SMSMaxSegments      int           `env:"SMS_MAX_SEGMENTS" envDefault:"4"`
+	SMTPHost            string        `env:"SMTP_HOST"`
+	SMTPPort            int           `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername        string        `env:"SMTP_USERNAME"`
+	SMTPPassword        string        `env:"SMTP_PASSWORD"`
+	SMTPFrom            string        `env:"SMTP_FROM"`
 }
@@ -2,34 +2,24 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
 )
 
-func deleteRegistration(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) func(ctx context.Context, snsRecord events.SNSEntity, retryCount int) error {
-	type message struct {
-		AgencyID   string `json:"agencyId"`
-		EndpointID string `json:"endpointId"`
-	}
-
-	logAndHandleError := eventProcessorErrorHandler(config, logger, snsClient, evtRegistrationDeleteFailed)
-
-	return func(ctx context.Context, snsRecord events.SNSEntity, retryCount int) error {
-		var message message
-
-		if err := json.Unmarshal([]byte(snsRecord.Message), &message); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete endpoint registration", message, err)
+func deleteRegistration(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.AgencyRegistrationDeleted](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to delete endpoint registration: %w", err)
 		}
 
 		queryEndpointResult, err := dynamoClient.Query(ctx, &dynamodb.QueryInput{
@@ -50,17 +40,17 @@ func deleteRegistration(config Config, logger *slog.Logger, dynamoClient *dynamo
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete endpoint registration", message, err)
+			return fmt.Errorf("failed to delete endpoint registration: %w", err)
 		}
 
 		if len(queryEndpointResult.Items) == 0 {
-			return logAndHandleError(ctx, retryCount, "failed to delete endpoint registration", message, errors.New("endpoint doesn't exist"))
+			return errors.New("endpoint doesn't exist")
 		}
 
 		var endpoint models.Endpoint
 
 		if err := attributevalue.UnmarshalMap(queryEndpointResult.Items[0], &endpoint); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete endpoint registration", message, err)
+			return fmt.Errorf("failed to delete endpoint registration: %w", err)
 		}
 
 		if endpoint.Registrations == nil {
@@ -70,55 +60,71 @@ func deleteRegistration(config Config, logger *slog.Logger, dynamoClient *dynamo
 
 		registrations, err := attributevalue.MarshalMap(endpoint.Registrations)
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete endpoint registration", message, err)
+			return fmt.Errorf("failed to delete endpoint registration: %w", err)
 		}
 
-		if _, err := dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-			TableName: aws.String(config.EndpointTableName),
-			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{
-					Value: fmt.Sprintf("endpoint#%s", message.EndpointID),
+		// The registration rows are what deliveries are routed by, remove them
+		// along with the endpoint's record of the agency.
+		if _, err := dynamoClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{
+					Delete: &types.Delete{
+						TableName: aws.String(config.EndpointTableName),
+						Key: map[string]types.AttributeValue{
+							"pk": &types.AttributeValueMemberS{
+								Value: fmt.Sprintf("endpoint#%s", message.EndpointID),
+							},
+							"sk": &types.AttributeValueMemberS{
+								Value: fmt.Sprintf("agency#%s", message.AgencyID),
+							},
+						},
+					},
 				},
-				"sk": &types.AttributeValueMemberS{
-					Value: "meta",
+				{
+					Delete: &types.Delete{
+						TableName: aws.String(config.EndpointTableName),
+						Key: map[string]types.AttributeValue{
+							"pk": &types.AttributeValueMemberS{
+								Value: fmt.Sprintf("agency#%s", message.AgencyID),
+							},
+							"sk": &types.AttributeValueMemberS{
+								Value: fmt.Sprintf("endpoint#%s", message.EndpointID),
+							},
+						},
+					},
 				},
-			},
-			UpdateExpression: aws.String("SET #registrations = :registrations"),
-			ExpressionAttributeNames: map[string]string{
-				"#registrations": "registrations",
-			},
-			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":registrations": &types.AttributeValueMemberM{
-					Value: registrations,
+				{
+					Update: &types.Update{
+						TableName: aws.String(config.EndpointTableName),
+						Key: map[string]types.AttributeValue{
+							"pk": &types.AttributeValueMemberS{
+								Value: fmt.Sprintf("endpoint#%s", message.EndpointID),
+							},
+							"sk": &types.AttributeValueMemberS{
+								Value: "meta",
+							},
+						},
+						UpdateExpression: aws.String("SET #registrations = :registrations"),
+						ExpressionAttributeNames: map[string]string{
+							"#registrations": "registrations",
+						},
+						ExpressionAttributeValues: map[string]types.AttributeValue{
+							":registrations": &types.AttributeValueMemberM{
+								Value: registrations,
+							},
+						},
+					},
 				},
 			},
 		}); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete endpoint registration", message, err)
+			return fmt.Errorf("failed to delete endpoint registration: %w", err)
 		}
 
-		messageBody, err := json.Marshal(struct {
-			AgencyID   string `json:"agencyId"`
-			EndpointID string `json:"endpointId"`
-		}{
+		if err := publisher.Publish(ctx, events.TypeEndpointRegistrationDeleted, events.EndpointRegistrationDeleted{
 			AgencyID:   message.AgencyID,
 			EndpointID: message.EndpointID,
-		})
-
-		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete endpoint registration", message, err)
-		}
-
-		if _, err = snsClient.Publish(ctx, &sns.PublishInput{
-			TopicArn: aws.String(config.EventsTopicARN),
-			Message:  aws.String(string(messageBody)),
-			MessageAttributes: map[string]snstypes.MessageAttributeValue{
-				"type": {
-					DataType:    aws.String("String"),
-					StringValue: aws.String(evtRegistrationDeleted),
-				},
-			},
 		}); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete endpoint registration", message, err)
+			return fmt.Errorf("failed to delete endpoint registration: %w", err)
 		}
 
 		return nil
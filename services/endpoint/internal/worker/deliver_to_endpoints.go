@@ -1,36 +1,50 @@
 package worker
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"net/http"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
 )
 
-func deliverToEndpoints(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) func(ctx context.Context, snsRecord events.SNSEntity, retryCount int) error {
-	type message struct {
-		AgencyID string `json:"agencyId"`
-		Title    string `json:"title"`
-		PageID   string `json:"pageId"`
-	}
-
-	logAndHandleError := eventProcessorErrorHandler(config, logger, snsClient, evtEndpointResolutionFailed)
+// deliverToEndpoints delivers a page to every endpoint registered to an
+// agency using the Sender for each endpoint's type. Endpoints are delivered to
+// concurrently so a slow or failing endpoint does not hold up the others. The
+// outcome for each endpoint is recorded, and endpoints that have already been
+// delivered to are skipped when SQS redelivers the message after a partial
+// failure.
+func deliverToEndpoints(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher, senders map[models.EndpointType]Sender) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.EndpointDeliver](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal endpoint.deliver message: %w", err)
+		}
 
-	return func(ctx context.Context, snsRecord events.SNSEntity, retryCount int) error {
-		var message message
+		// Pages already on their way when an agency was deactivated are dropped
+		// rather than delivered.
+		inactive, err := agencyInactive(ctx, config, dynamoClient, message.AgencyID)
+		if err != nil {
+			return fmt.Errorf("failed to get agency status: %w", err)
+		}
 
-		if err := json.Unmarshal([]byte(snsRecord.Message), &message); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to unmarshal endpoint.deliver message", message, err)
+		if inactive {
+			logger.InfoContext(
+				ctx,
+				"skipping delivery to inactive agency",
+				slog.String("pageId", message.PageID),
+				slog.String("agencyId", message.AgencyID))
+			return nil
 		}
 
 		queryEndpointsResult, err := dynamoClient.Query(ctx, &dynamodb.QueryInput{
@@ -51,13 +65,44 @@ func deliverToEndpoints(config Config, logger *slog.Logger, dynamoClient *dynamo
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to query endpoints", message, err)
+			return fmt.Errorf("failed to query endpoints: %w", err)
 		}
 
 		var registeredEndpoints []models.Registration
 
 		if err := attributevalue.UnmarshalListOfMaps(queryEndpointsResult.Items, &registeredEndpoints); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to unmarshal endpoints for agency", message, err)
+			return fmt.Errorf("failed to unmarshal endpoints for agency: %w", err)
+		}
+
+		queryDeliveriesResult, err := dynamoClient.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(config.EndpointTableName),
+			ConsistentRead:         aws.Bool(true),
+			KeyConditionExpression: aws.String("#pk = :pk"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk": "pk",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("delivery#%s", message.PageID),
+				},
+			},
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to query deliveries: %w", err)
+		}
+
+		var deliveries []models.Delivery
+
+		if err := attributevalue.UnmarshalListOfMaps(queryDeliveriesResult.Items, &deliveries); err != nil {
+			return fmt.Errorf("failed to unmarshal deliveries for page: %w", err)
+		}
+
+		delivered := make(map[string]bool)
+		for _, delivery := range deliveries {
+			if delivery.Status == models.DeliveryStatusSucceeded {
+				delivered[delivery.SK] = true
+			}
 		}
 
 		logger.InfoContext(
@@ -68,25 +113,301 @@ func deliverToEndpoints(config Config, logger *slog.Logger, dynamoClient *dynamo
 			slog.String("title", message.Title),
 			slog.Any("endpoints", registeredEndpoints))
 
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			errs      []error
+			semaphore = make(chan struct{}, max(config.DeliveryConcurrency, 1))
+		)
+
+		fail := func(endpointID string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, fmt.Errorf("endpoint %s: %w", endpointID, err))
+		}
+
 		for _, registeredEndpoint := range registeredEndpoints {
-			// TEST CODE
-			msg, err := json.Marshal(struct {
-				Title      string `json:"title"`
-				PageID     string `json:"pageId"`
-				EndpointID string `json:"endpointId"`
-			}{
-				message.Title,
-				message.PageID,
-				registeredEndpoint.PK,
-			})
-			if err != nil {
-				return logAndHandleError(ctx, retryCount, "failed to deliver to endpoint", message, err)
+			sender, ok := senders[registeredEndpoint.EndpointType]
+			if !ok {
+				logger.WarnContext(
+					ctx,
+					"no sender for endpoint type",
+					slog.String("endpoint", registeredEndpoint.SK),
+					slog.String("endpointType", registeredEndpoint.EndpointType))
+				continue
 			}
-			if registeredEndpoint.EndpointType == models.EndpointTypeWebhook {
-				http.Post(registeredEndpoint.URL, "application/json", bytes.NewBuffer(msg))
+
+			if registeredEndpoint.Disabled {
+				logger.DebugContext(
+					ctx,
+					"skipping disabled endpoint",
+					slog.String("pageId", message.PageID),
+					slog.String("endpoint", registeredEndpoint.SK))
+				continue
 			}
+
+			if delivered[registeredEndpoint.SK] {
+				logger.DebugContext(
+					ctx,
+					"skipping endpoint already delivered to",
+					slog.String("pageId", message.PageID),
+					slog.String("endpoint", registeredEndpoint.SK))
+				continue
+			}
+
+			wg.Add(1)
+			go func(registeredEndpoint models.Registration) {
+				defer wg.Done()
+
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
+
+				endpointID := strings.Split(registeredEndpoint.SK, "#")[1]
+
+				endpoint, err := getEndpoint(ctx, config, dynamoClient, endpointID)
+				if err != nil {
+					fail(endpointID, err)
+					return
+				}
+
+				// Registrations can outlive their endpoint, and disabled or
+				// suspended endpoints are not delivered to.
+				if endpoint == nil || endpoint.Disabled || endpoint.Suspended {
+					logger.DebugContext(
+						ctx,
+						"skipping missing, disabled or suspended endpoint",
+						slog.String("pageId", message.PageID),
+						slog.String("endpointId", endpointID))
+					return
+				}
+
+				result := sender.Send(ctx, *endpoint, notification{
+					Title:      message.Title,
+					Notes:      message.Notes,
+					Location:   (*location)(message.Location),
+					PageID:     message.PageID,
+					EndpointID: endpointID,
+				})
+
+				// Retrying won't help an endpoint the provider has rejected, it is
+				// disabled so future pages skip it until the owner fixes it.
+				if errors.Is(result.Err, errEndpointInvalid) {
+					if err := disableEndpoint(ctx, config, dynamoClient, endpointID, result.Err.Error()); err != nil {
+						fail(endpointID, err)
+					}
+					if err := recordDelivery(ctx, config, logger, dynamoClient, publisher, message.PageID, message.AgencyID, endpointID, *endpoint, result); err != nil {
+						fail(endpointID, err)
+					}
+					return
+				}
+
+				if err := recordDelivery(ctx, config, logger, dynamoClient, publisher, message.PageID, message.AgencyID, endpointID, *endpoint, result); err != nil {
+					result.Err = errors.Join(result.Err, err)
+				}
+
+				if result.Err != nil {
+					fail(endpointID, result.Err)
+				}
+			}(registeredEndpoint)
+		}
+
+		wg.Wait()
+
+		// Returning an error leaves the message on the queue to be redelivered.
+		// Only the endpoints that failed will be attempted again.
+		if len(errs) > 0 {
+			return fmt.Errorf("failed to deliver to endpoints: %w", errors.Join(errs...))
 		}
 
 		return nil
 	}
 }
+
+// getEndpoint reads an endpoint, returning nil if it doesn't exist. The
+// endpoint is read rather than relying on the registration so that changes
+// such as a secret rotation take effect immediately.
+func getEndpoint(ctx context.Context, config Config, dynamoClient dynamoDBAPI, endpointID string) (*models.Endpoint, error) {
+	result, err := dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(config.EndpointTableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("endpoint#%s", endpointID),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: "meta",
+			},
+		},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get endpoint: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var endpoint models.Endpoint
+	if err := attributevalue.UnmarshalMap(result.Item, &endpoint); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal endpoint: %w", err)
+	}
+
+	return &endpoint, nil
+}
+
+// agencyInactive reports whether an agency has been deactivated. Agencies this
+// service hasn't seen a status change for are active.
+func agencyInactive(ctx context.Context, config Config, dynamoClient dynamoDBAPI, agencyID string) (bool, error) {
+	result, err := dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(config.EndpointTableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("agency#%s", agencyID),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: "status",
+			},
+		},
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("failed to get agency status: %w", err)
+	}
+
+	if result.Item == nil {
+		return false, nil
+	}
+
+	var state models.AgencyState
+	if err := attributevalue.UnmarshalMap(result.Item, &state); err != nil {
+		return false, fmt.Errorf("failed to unmarshal agency status: %w", err)
+	}
+
+	return state.Status == models.AgencyStatusInactive, nil
+}
+
+// disableEndpoint marks an endpoint as disabled so it is skipped by future
+// deliveries.
+func disableEndpoint(ctx context.Context, config Config, dynamoClient dynamoDBAPI, endpointID, reason string) error {
+	if _, err := dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(config.EndpointTableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("endpoint#%s", endpointID),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: "meta",
+			},
+		},
+		ConditionExpression: aws.String("attribute_exists(#pk)"),
+		UpdateExpression:    aws.String("SET #disabled = :disabled, #disabledReason = :disabledReason, #modified = :modified, #modifiedBy = :modifiedBy"),
+		ExpressionAttributeNames: map[string]string{
+			"#pk":             "pk",
+			"#disabled":       "disabled",
+			"#disabledReason": "disabledReason",
+			"#modified":       "modified",
+			"#modifiedBy":     "modifiedBy",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":disabled":       &types.AttributeValueMemberBOOL{Value: true},
+			":disabledReason": &types.AttributeValueMemberS{Value: reason},
+			":modified":       &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339Nano)},
+			":modifiedBy":     &types.AttributeValueMemberS{Value: "system"},
+		},
+	}); err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return nil
+		}
+		return fmt.Errorf("failed to disable endpoint: %w", err)
+	}
+
+	return nil
+}
+
+// recordDelivery stores the outcome of delivering a page to an endpoint and
+// publishes the matching endpoint.delivery.succeeded or
+// endpoint.delivery.failed event.
+func recordDelivery(ctx context.Context, config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher, pageID, agencyID, endpointID string, endpoint models.Endpoint, result deliveryResult) error {
+	var (
+		now          = time.Now()
+		status       = models.DeliveryStatusSucceeded
+		eventType    = events.TypeEndpointDeliverySucceeded
+		errorMessage string
+	)
+
+	if result.Err != nil {
+		status = models.DeliveryStatusFailed
+		eventType = events.TypeEndpointDeliveryFailed
+		errorMessage = result.Err.Error()
+		logger.WarnContext(
+			ctx,
+			"failed to deliver to endpoint",
+			slog.String("pageId", pageID),
+			slog.String("endpointId", endpointID),
+			slog.Int("attempts", result.Attempts),
+			slog.Any("error", result.Err))
+	}
+
+	providerMessageIDs, err := attributevalue.Marshal(result.ProviderMessageIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal provider message ids: %w", err)
+	}
+
+	if _, err := dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(config.EndpointTableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("delivery#%s", pageID),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("endpoint#%s", endpointID),
+			},
+		},
+		UpdateExpression: aws.String("SET #type = :type, #status = :status, #lastStatusCode = :lastStatusCode, " +
+			"#lastError = :lastError, #ttl = :ttl, #modified = :now, #modifiedBy = :system, " +
+			"#providerIds = :providerIds, #created = if_not_exists(#created, :now), #createdBy = if_not_exists(#createdBy, :system) " +
+			"ADD #attempts :attempts"),
+		ExpressionAttributeNames: map[string]string{
+			"#type":           "type",
+			"#status":         "status",
+			"#lastStatusCode": "lastStatusCode",
+			"#lastError":      "lastError",
+			"#ttl":            "ttl",
+			"#modified":       "modified",
+			"#modifiedBy":     "modifiedBy",
+			"#created":        "created",
+			"#createdBy":      "createdBy",
+			"#attempts":       "attempts",
+			"#providerIds":    "providerMessageIds",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":type":           &types.AttributeValueMemberS{Value: models.EntityTypeDelivery},
+			":status":         &types.AttributeValueMemberS{Value: status},
+			":lastStatusCode": &types.AttributeValueMemberN{Value: fmt.Sprint(result.StatusCode)},
+			":lastError":      &types.AttributeValueMemberS{Value: errorMessage},
+			":ttl":            &types.AttributeValueMemberN{Value: fmt.Sprint(now.Add(config.DeliveryRecordTTL).Unix())},
+			":now":            &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+			":system":         &types.AttributeValueMemberS{Value: "system"},
+			":attempts":       &types.AttributeValueMemberN{Value: fmt.Sprint(result.Attempts)},
+			":providerIds":    providerMessageIDs,
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+
+	return publisher.Publish(ctx, eventType, events.EndpointDelivery{
+		PageID:             pageID,
+		AgencyID:           agencyID,
+		EndpointID:         endpointID,
+		EndpointType:       endpoint.EndpointType,
+		EndpointName:       endpoint.Name,
+		UserID:             endpoint.UserID,
+		StatusCode:         result.StatusCode,
+		LatencyMs:          result.Latency.Milliseconds(),
+		Attempts:           result.Attempts,
+		ProviderMessageIDs: result.ProviderMessageIDs,
+		Error:              errorMessage,
+		AttemptedAt:        now,
+	})
+}
@@ -0,0 +1,189 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReceiver is a webhook receiver that records the endpoints delivered to
+// and how many deliveries it was handling at once. Each delivery is answered
+// with the status returned by respond, which defaults to 200, after delay.
+type fakeReceiver struct {
+	delay   time.Duration
+	respond func(endpointID string) int
+
+	mu          sync.Mutex
+	delivered   []string
+	inFlight    int
+	maxInFlight int
+}
+
+func newFakeReceiver(t *testing.T, receiver *fakeReceiver) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpointID := strings.TrimPrefix(r.URL.Path, "/")
+
+		receiver.mu.Lock()
+		receiver.delivered = append(receiver.delivered, endpointID)
+		receiver.inFlight++
+		receiver.maxInFlight = max(receiver.maxInFlight, receiver.inFlight)
+		receiver.mu.Unlock()
+
+		time.Sleep(receiver.delay)
+
+		receiver.mu.Lock()
+		receiver.inFlight--
+		receiver.mu.Unlock()
+
+		status := http.StatusOK
+		if receiver.respond != nil {
+			status = receiver.respond(endpointID)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+// newDeliveryTable returns a table holding webhook endpoints registered to
+// agency-1 that deliver to url, and succeeded deliveries of page-1 to the
+// delivered endpoints.
+func newDeliveryTable(t *testing.T, url string, endpointIDs []string, delivered ...string) *fakeDynamoDB {
+	t.Helper()
+
+	var (
+		endpoints     = make(map[string]map[string]types.AttributeValue)
+		registrations []map[string]types.AttributeValue
+		deliveries    []map[string]types.AttributeValue
+	)
+	for _, endpointID := range endpointIDs {
+		endpoint, err := attributevalue.MarshalMap(models.Endpoint{
+			KeyFields:    models.KeyFields{PK: "endpoint#" + endpointID, SK: "meta", Type: models.EntityTypeEndpoint},
+			EndpointType: models.EndpointTypeWebhook,
+			URL:          url + "/" + endpointID,
+		})
+		require.NoError(t, err)
+		endpoints["endpoint#"+endpointID] = endpoint
+
+		registration, err := attributevalue.MarshalMap(models.Registration{
+			KeyFields:    models.KeyFields{PK: "agency#agency-1", SK: "endpoint#" + endpointID},
+			Type:         models.EntityTypeRegistration,
+			EndpointType: models.EndpointTypeWebhook,
+		})
+		require.NoError(t, err)
+		registrations = append(registrations, registration)
+	}
+	for _, endpointID := range delivered {
+		delivery, err := attributevalue.MarshalMap(models.Delivery{
+			KeyFields: models.KeyFields{PK: "delivery#page-1", SK: "endpoint#" + endpointID, Type: models.EntityTypeDelivery},
+			Status:    models.DeliveryStatusSucceeded,
+		})
+		require.NoError(t, err)
+		deliveries = append(deliveries, delivery)
+	}
+
+	return &fakeDynamoDB{
+		getItem: func(params *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{Item: endpoints[params.Key["pk"].(*types.AttributeValueMemberS).Value]}, nil
+		},
+		query: func(params *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			if strings.HasPrefix(params.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS).Value, "delivery#") {
+				return &dynamodb.QueryOutput{Items: deliveries}, nil
+			}
+			return &dynamodb.QueryOutput{Items: registrations}, nil
+		},
+	}
+}
+
+func deliver(t *testing.T, config Config, client *fakeDynamoDB, sns *fakeSNS) error {
+	t.Helper()
+
+	senders := map[models.EndpointType]Sender{
+		models.EndpointTypeWebhook: NewWebhookSender(config, http.DefaultClient),
+	}
+
+	return deliverToEndpoints(config, discardLogger, client, events.NewPublisher(sns, "topic", "endpoint"), senders)(
+		context.Background(),
+		newEnvelope(t, events.TypeEndpointDeliver, events.EndpointDeliver{
+			Title:    "Structure fire",
+			PageID:   "page-1",
+			AgencyID: "agency-1",
+		}))
+}
+
+func TestDeliverToEndpoints(t *testing.T) {
+	var (
+		receiver = new(fakeReceiver)
+		client   = newDeliveryTable(t, newFakeReceiver(t, receiver), []string{"endpoint-1", "endpoint-2"})
+		sns      = new(fakeSNS)
+	)
+
+	require.NoError(t, deliver(t, webhookRetryConfig, client, sns))
+
+	assert.ElementsMatch(t, []string{"endpoint-1", "endpoint-2"}, receiver.delivered)
+	assert.Len(t, client.updates, 2)
+	assert.ElementsMatch(t, []events.Type{events.TypeEndpointDeliverySucceeded, events.TypeEndpointDeliverySucceeded}, sns.types())
+}
+
+func TestDeliverToEndpointsConcurrency(t *testing.T) {
+	var endpointIDs []string
+	for _, id := range []string{"1", "2", "3", "4", "5", "6"} {
+		endpointIDs = append(endpointIDs, "endpoint-"+id)
+	}
+
+	var (
+		receiver = &fakeReceiver{delay: 50 * time.Millisecond}
+		client   = newDeliveryTable(t, newFakeReceiver(t, receiver), endpointIDs)
+		config   = webhookRetryConfig
+	)
+	config.DeliveryConcurrency = 2
+
+	require.NoError(t, deliver(t, config, client, new(fakeSNS)))
+
+	assert.Len(t, receiver.delivered, 6)
+	assert.Equal(t, 2, receiver.maxInFlight)
+}
+
+func TestDeliverToEndpointsRedelivered(t *testing.T) {
+	var (
+		receiver = &fakeReceiver{
+			respond: func(endpointID string) int {
+				if endpointID == "endpoint-2" {
+					return http.StatusServiceUnavailable
+				}
+				return http.StatusOK
+			},
+		}
+		url = newFakeReceiver(t, receiver)
+		sns = new(fakeSNS)
+	)
+
+	// endpoint-2 fails every attempt, so the page is left to be redelivered.
+	err := deliver(t, webhookRetryConfig, newDeliveryTable(t, url, []string{"endpoint-1", "endpoint-2", "endpoint-3"}), sns)
+	require.ErrorContains(t, err, "endpoint endpoint-2")
+	assert.ElementsMatch(t, []string{"endpoint-1", "endpoint-2", "endpoint-2", "endpoint-2", "endpoint-3"}, receiver.delivered)
+
+	// The redelivery only delivers to the endpoint that failed.
+	receiver.delivered = nil
+	receiver.respond = nil
+
+	client := newDeliveryTable(t, url, []string{"endpoint-1", "endpoint-2", "endpoint-3"}, "endpoint-1", "endpoint-3")
+	require.NoError(t, deliver(t, webhookRetryConfig, client, sns))
+	assert.Equal(t, []string{"endpoint-2"}, receiver.delivered)
+	assert.Len(t, client.updates, 1)
+}
@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDynamoDB records the requests made to it. Each request is answered by the
+// matching function if one is set, and with an empty output otherwise.
+type fakeDynamoDB struct {
+	mu sync.Mutex
+
+	getItem            func(params *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error)
+	putItem            func(params *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
+	updateItem         func(params *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error)
+	query              func(params *dynamodb.QueryInput) (*dynamodb.QueryOutput, error)
+	transactWriteItems func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error)
+
+	puts      []*dynamodb.PutItemInput
+	updates   []*dynamodb.UpdateItemInput
+	queries   []*dynamodb.QueryInput
+	transacts []*dynamodb.TransactWriteItemsInput
+}
+
+func (f *fakeDynamoDB) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if f.getItem != nil {
+		return f.getItem(params)
+	}
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	f.puts = append(f.puts, params)
+	f.mu.Unlock()
+	if f.putItem != nil {
+		return f.putItem(params)
+	}
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.mu.Lock()
+	f.updates = append(f.updates, params)
+	f.mu.Unlock()
+	if f.updateItem != nil {
+		return f.updateItem(params)
+	}
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	f.mu.Lock()
+	f.queries = append(f.queries, params)
+	f.mu.Unlock()
+	if f.query != nil {
+		return f.query(params)
+	}
+	return &dynamodb.QueryOutput{}, nil
+}
+
+func (f *fakeDynamoDB) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	f.mu.Lock()
+	f.transacts = append(f.transacts, params)
+	f.mu.Unlock()
+	if f.transactWriteItems != nil {
+		return f.transactWriteItems(params)
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// fakeSNS records the events published to it.
+type fakeSNS struct {
+	mu        sync.Mutex
+	envelopes []events.Envelope
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	var envelope events.Envelope
+	if err := json.Unmarshal([]byte(aws.ToString(params.Message)), &envelope); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.envelopes = append(f.envelopes, envelope)
+	f.mu.Unlock()
+	return &sns.PublishOutput{}, nil
+}
+
+// types returns the types of the events published.
+func (f *fakeSNS) types() []events.Type {
+	var published []events.Type
+	for _, envelope := range f.envelopes {
+		published = append(published, envelope.Type)
+	}
+	return published
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newEnvelope returns the envelope of an event with the given payload.
+func newEnvelope(t *testing.T, eventType events.Type, payload any) events.Envelope {
+	t.Helper()
+
+	envelope, err := events.NewPublisher(new(fakeSNS), "topic", "test").Envelope(context.Background(), eventType, payload)
+	require.NoError(t, err)
+	return envelope
+}
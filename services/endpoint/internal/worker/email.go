@@ -0,0 +1,222 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.ParseFS(templates, "templates/page.txt.tmpl"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templates, "templates/page.html.tmpl"))
+)
+
+// emailSender delivers notifications to EMAIL endpoints over SMTP.
+type emailSender struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	timeout  time.Duration
+	policy   retryPolicy
+}
+
+// NewEmailSender returns the Sender for EMAIL endpoints.
+func NewEmailSender(config Config) Sender {
+	return &emailSender{
+		host:     config.SMTPHost,
+		port:     config.SMTPPort,
+		username: config.SMTPUsername,
+		password: config.SMTPPassword,
+		from:     config.SMTPFrom,
+		timeout:  config.DeliveryTimeout,
+		policy:   newRetryPolicy(config),
+	}
+}
+
+// Send renders the notification as a multipart text and HTML email and sends
+// it. Temporary (4xx) SMTP failures are retried, permanent (5xx) failures are
+// not. The Message-ID of the email is recorded as its provider message ID.
+func (s *emailSender) Send(ctx context.Context, endpoint models.Endpoint, notification notification) deliveryResult {
+	if s.host == "" {
+		return deliveryResult{Err: errors.New("email is not configured")}
+	}
+
+	messageID := fmt.Sprintf("<%s@%s>", uuid.New().String(), s.domain())
+
+	message, err := s.message(endpoint.EmailAddress, messageID, notification)
+	if err != nil {
+		return deliveryResult{Err: err}
+	}
+
+	result := withRetries(ctx, s.policy, func(ctx context.Context) (bool, int, error) {
+		return s.send(ctx, endpoint.EmailAddress, message)
+	})
+
+	if result.Err == nil {
+		result.ProviderMessageIDs = []string{messageID}
+	}
+
+	return result
+}
+
+// send makes a single attempt to send the message, returning the SMTP reply
+// code of a failed command.
+func (s *emailSender) send(ctx context.Context, to string, message []byte) (bool, int, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.host, strconv.Itoa(s.port)))
+	if err != nil {
+		return true, 0, err
+	}
+	defer conn.Close()
+
+	if s.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(s.timeout))
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return smtpFailure(err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return smtpFailure(err)
+		}
+	}
+
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return smtpFailure(err)
+		}
+	}
+
+	if err := client.Mail(s.from); err != nil {
+		return smtpFailure(err)
+	}
+
+	if err := client.Rcpt(to); err != nil {
+		return smtpFailure(err)
+	}
+
+	data, err := client.Data()
+	if err != nil {
+		return smtpFailure(err)
+	}
+
+	if _, err := data.Write(message); err != nil {
+		return smtpFailure(err)
+	}
+
+	if err := data.Close(); err != nil {
+		return smtpFailure(err)
+	}
+
+	if err := client.Quit(); err != nil {
+		return smtpFailure(err)
+	}
+
+	return false, 250, nil
+}
+
+// smtpFailure classifies an error from an SMTP command. Transient negative
+// replies (4xx) and network errors are retried, permanent ones (5xx) are not.
+func smtpFailure(err error) (bool, int, error) {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code < 500, protoErr.Code, fmt.Errorf("smtp: %w", err)
+	}
+	return true, 0, fmt.Errorf("smtp: %w", err)
+}
+
+// message renders the email for a notification.
+func (s *emailSender) message(to, messageID string, notification notification) ([]byte, error) {
+	var (
+		buf  bytes.Buffer
+		body bytes.Buffer
+	)
+
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		render      func(*bytes.Buffer) error
+	}{
+		{"text/plain", func(b *bytes.Buffer) error { return textTemplate.Execute(b, notification) }},
+		{"text/html", func(b *bytes.Buffer) error { return htmlTemplate.Execute(b, notification) }},
+	} {
+		var rendered bytes.Buffer
+		if err := part.render(&rendered); err != nil {
+			return nil, fmt.Errorf("failed to render %s email: %w", part.contentType, err)
+		}
+
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(rendered.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	headers := []struct{ key, value string }{
+		{"From", s.from},
+		{"To", to},
+		{"Subject", mime.QEncoding.Encode("utf-8", notification.Title)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	}
+
+	for _, header := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", header.key, header.value)
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// domain is the domain of the from address, used to build Message-IDs.
+func (s *emailSender) domain() string {
+	if _, domain, ok := strings.Cut(s.from, "@"); ok {
+		return strings.TrimSuffix(domain, ">")
+	}
+	return s.host
+}
@@ -0,0 +1,264 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP is a minimal in-process SMTP server that records the messages sent
+// through it. It doesn't advertise STARTTLS or AUTH. RCPT commands are answered
+// by rcpt, which defaults to accepting every recipient.
+type fakeSMTP struct {
+	mu         sync.Mutex
+	recipients []string
+	messages   []string
+	rcpt       func(n int) string
+}
+
+func newFakeSMTP(t *testing.T, fake *fakeSMTP) Config {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go fake.serve(conn)
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	return Config{
+		DeliveryMaxAttempts: 3,
+		SMTPHost:            host,
+		SMTPPort:            portNumber,
+		SMTPFrom:            "pager@example.com",
+	}
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var (
+		reader = bufio.NewReader(conn)
+		reply  = func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+		rcpt   string
+	)
+
+	reply("220 localhost ESMTP")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		command := strings.TrimRight(line, "\r\n")
+		verb, _, _ := strings.Cut(command, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL", "RSET", "NOOP":
+			reply("250 OK")
+		case "RCPT":
+			f.mu.Lock()
+			n := len(f.messages)
+			f.mu.Unlock()
+
+			if f.rcpt != nil {
+				if response := f.rcpt(n); response != "" {
+					reply(response)
+					continue
+				}
+			}
+
+			rcpt = strings.TrimSuffix(strings.TrimPrefix(command[len("RCPT TO:"):], "<"), ">")
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+
+			f.mu.Lock()
+			f.recipients = append(f.recipients, rcpt)
+			f.messages = append(f.messages, data.String())
+			f.mu.Unlock()
+
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+var emailEndpoint = models.Endpoint{
+	EndpointType: models.EndpointTypeEmail,
+	EmailAddress: "responder@example.com",
+}
+
+// parseEmail splits a message into its headers and decoded text and HTML
+// bodies.
+func parseEmail(t *testing.T, raw string) (*mail.Message, string, string) {
+	t.Helper()
+
+	message, err := mail.ReadMessage(strings.NewReader(raw))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	bodies := map[string]string{}
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		assert.Equal(t, "quoted-printable", part.Header.Get("Content-Transfer-Encoding"))
+
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+
+		contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		bodies[contentType] = string(body)
+	}
+
+	return message, bodies["text/plain"], bodies["text/html"]
+}
+
+func TestEmailSenderRendersPage(t *testing.T) {
+	fake := &fakeSMTP{}
+	sender := NewEmailSender(newFakeSMTP(t, fake))
+
+	result := sender.Send(context.Background(), emailEndpoint, notification{
+		Title: "Structure fire",
+		Notes: "Smoke showing <2nd floor>",
+		Location: &location{
+			Description: "Main St and 3rd",
+			Latitude:    39.7392,
+			Longitude:   -104.9903,
+		},
+	})
+
+	require.NoError(t, result.Err)
+	assert.Equal(t, 1, result.Attempts)
+	require.Len(t, fake.messages, 1)
+	assert.Equal(t, []string{"responder@example.com"}, fake.recipients)
+
+	message, text, html := parseEmail(t, fake.messages[0])
+
+	assert.Equal(t, "pager@example.com", message.Header.Get("From"))
+	assert.Equal(t, "responder@example.com", message.Header.Get("To"))
+	assert.Equal(t, "Structure fire", message.Header.Get("Subject"))
+	assert.Equal(t, []string{message.Header.Get("Message-ID")}, result.ProviderMessageIDs)
+	assert.True(t, strings.HasSuffix(message.Header.Get("Message-ID"), "@example.com>"))
+
+	mapURL := "https://www.google.com/maps/search/?api=1&query=39.7392,-104.9903"
+
+	assert.Contains(t, text, "Structure fire")
+	assert.Contains(t, text, "Smoke showing <2nd floor>")
+	assert.Contains(t, text, "Location: Main St and 3rd")
+	assert.Contains(t, text, "Map: "+mapURL)
+
+	assert.Contains(t, html, "<h1>Structure fire</h1>")
+	assert.Contains(t, html, "Smoke showing &lt;2nd floor&gt;")
+	assert.Contains(t, html, "Main St and 3rd")
+	assert.Contains(t, html, `href="https://www.google.com/maps/search/?api=1&amp;query=39.7392,-104.9903"`)
+}
+
+func TestEmailSenderOmitsMissingLocation(t *testing.T) {
+	fake := &fakeSMTP{}
+	sender := NewEmailSender(newFakeSMTP(t, fake))
+
+	result := sender.Send(context.Background(), emailEndpoint, notification{
+		Title: "Medical",
+		Location: &location{
+			Description: "Trailhead parking lot",
+		},
+	})
+
+	require.NoError(t, result.Err)
+	require.Len(t, fake.messages, 1)
+
+	_, text, html := parseEmail(t, fake.messages[0])
+
+	assert.Contains(t, text, "Location: Trailhead parking lot")
+	assert.NotContains(t, text, "Map:")
+	assert.NotContains(t, html, "href=")
+}
+
+func TestEmailSenderRetriesTransientFailure(t *testing.T) {
+	var calls int
+	fake := &fakeSMTP{
+		rcpt: func(n int) string {
+			calls++
+			if calls == 1 {
+				return "451 Try again later"
+			}
+			return ""
+		},
+	}
+	sender := NewEmailSender(newFakeSMTP(t, fake))
+
+	result := sender.Send(context.Background(), emailEndpoint, notification{Title: "Structure fire"})
+
+	require.NoError(t, result.Err)
+	assert.Equal(t, 2, result.Attempts)
+	assert.Len(t, fake.messages, 1)
+}
+
+func TestEmailSenderDoesNotRetryPermanentFailure(t *testing.T) {
+	fake := &fakeSMTP{
+		rcpt: func(n int) string { return "550 No such user" },
+	}
+	sender := NewEmailSender(newFakeSMTP(t, fake))
+
+	result := sender.Send(context.Background(), emailEndpoint, notification{Title: "Structure fire"})
+
+	require.Error(t, result.Err)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, 550, result.StatusCode)
+	assert.Empty(t, fake.messages)
+}
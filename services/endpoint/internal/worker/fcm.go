@@ -0,0 +1,239 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// fcmCredentials is the subset of a Google service account key used to
+// authenticate with FCM.
+type fcmCredentials struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// fcmClient sends messages with the FCM HTTP v1 API.
+type fcmClient struct {
+	client      *http.Client
+	baseURL     string
+	credentials fcmCredentials
+	key         *rsa.PrivateKey
+
+	mu          sync.Mutex
+	accessToken string
+	expires     time.Time
+}
+
+func newFCMClient(client *http.Client, baseURL string, credentialsJSON string) (*fcmClient, error) {
+	var credentials fcmCredentials
+	if err := json.Unmarshal([]byte(credentialsJSON), &credentials); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fcm credentials: %w", err)
+	}
+
+	block, _ := pem.Decode([]byte(credentials.PrivateKey))
+	if block == nil {
+		return nil, errors.New("fcm credentials contain no private key")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fcm private key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("fcm private key is not an RSA key")
+	}
+
+	return &fcmClient{
+		client:      client,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		credentials: credentials,
+		key:         key,
+	}, nil
+}
+
+// send makes a single attempt to deliver the notification to a device token.
+func (c *fcmClient) send(ctx context.Context, token string, notification notification) (bool, int, error) {
+	accessToken, err := c.token(ctx)
+	if err != nil {
+		return true, 0, err
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"message": map[string]any{
+			"token": token,
+			"notification": map[string]string{
+				"title": notification.Title,
+			},
+			"data": map[string]string{
+				"pageId":     notification.PageID,
+				"endpointId": notification.EndpointID,
+			},
+			"android": map[string]string{
+				"priority": "high",
+			},
+		},
+	})
+	if err != nil {
+		return false, 0, err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", c.baseURL, url.PathEscape(c.credentials.ProjectID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return false, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, resp.StatusCode, nil
+	}
+
+	var body struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+
+	// The token was valid once but the app has since been uninstalled or the
+	// token expired.
+	if resp.StatusCode == http.StatusNotFound || body.Error.Status == "UNREGISTERED" {
+		return false, resp.StatusCode, fmt.Errorf("%w: fcm: %s", errEndpointInvalid, body.Error.Message)
+	}
+	for _, detail := range body.Error.Details {
+		if detail.ErrorCode == "UNREGISTERED" {
+			return false, resp.StatusCode, fmt.Errorf("%w: fcm: %s", errEndpointInvalid, body.Error.Message)
+		}
+	}
+
+	// A rejected access token is cleared so the next attempt fetches a new one.
+	if resp.StatusCode == http.StatusUnauthorized {
+		c.mu.Lock()
+		c.accessToken = ""
+		c.mu.Unlock()
+		return true, resp.StatusCode, fmt.Errorf("fcm: %s", body.Error.Message)
+	}
+
+	return retryableStatus(resp.StatusCode), resp.StatusCode, fmt.Errorf("fcm: unexpected status code %d: %s", resp.StatusCode, body.Error.Message)
+}
+
+// token returns an OAuth2 access token for the service account, exchanging a
+// signed JWT assertion for a new token when the cached one is close to expiry.
+func (c *fcmClient) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	if c.accessToken != "" && now.Before(c.expires.Add(-time.Minute)) {
+		return c.accessToken, nil
+	}
+
+	assertion, err := signJWT(
+		map[string]string{"alg": "RS256", "typ": "JWT"},
+		map[string]any{
+			"iss":   c.credentials.ClientEmail,
+			"scope": fcmScope,
+			"aud":   c.credentials.TokenURI,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+		func(digest []byte) ([]byte, error) {
+			return rsa.SignPKCS1v15(nil, c.key, crypto.SHA256, digest)
+		})
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.credentials.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch fcm access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch fcm access token: unexpected status code %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode fcm access token: %w", err)
+	}
+
+	c.accessToken = body.AccessToken
+	c.expires = now.Add(time.Duration(body.ExpiresIn) * time.Second)
+
+	return c.accessToken, nil
+}
+
+// signJWT builds a compact JWT from the header and claims, signing the SHA-256
+// digest of the signing input with sign.
+func signJWT(header map[string]string, claims map[string]any, sign func(digest []byte) ([]byte, error)) (string, error) {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(input))
+
+	signature, err := sign(digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign jwt: %w", err)
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
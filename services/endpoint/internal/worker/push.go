@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+// pushSender delivers notifications to PUSH endpoints through the provider for
+// the endpoint's platform. A platform is only available when its credentials
+// are configured.
+type pushSender struct {
+	fcm    *fcmClient
+	apns   *apnsClient
+	policy retryPolicy
+}
+
+// NewPushSender returns the Sender for PUSH endpoints.
+func NewPushSender(config Config, client *http.Client) (Sender, error) {
+	sender := &pushSender{
+		policy: newRetryPolicy(config),
+	}
+
+	if config.FCMCredentials != "" {
+		fcm, err := newFCMClient(client, config.FCMBaseURL, config.FCMCredentials)
+		if err != nil {
+			return nil, err
+		}
+		sender.fcm = fcm
+	}
+
+	if config.APNSPrivateKey != "" {
+		apns, err := newAPNSClient(client, config.APNSBaseURL, config.APNSKeyID, config.APNSTeamID, config.APNSTopic, config.APNSPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		sender.apns = apns
+	}
+
+	return sender, nil
+}
+
+func (s *pushSender) Send(ctx context.Context, endpoint models.Endpoint, notification notification) deliveryResult {
+	var attempt attemptFunc
+
+	switch endpoint.PushPlatform {
+	case models.PushPlatformFCM:
+		if s.fcm == nil {
+			return deliveryResult{Err: fmt.Errorf("push platform %s is not configured", endpoint.PushPlatform)}
+		}
+		attempt = func(ctx context.Context) (bool, int, error) {
+			return s.fcm.send(ctx, endpoint.PushToken, notification)
+		}
+	case models.PushPlatformAPNS:
+		if s.apns == nil {
+			return deliveryResult{Err: fmt.Errorf("push platform %s is not configured", endpoint.PushPlatform)}
+		}
+		attempt = func(ctx context.Context) (bool, int, error) {
+			return s.apns.send(ctx, endpoint.PushToken, notification)
+		}
+	default:
+		return deliveryResult{Err: fmt.Errorf("%w: unknown push platform %q", errEndpointInvalid, endpoint.PushPlatform)}
+	}
+
+	return withRetries(ctx, s.policy, attempt)
+}
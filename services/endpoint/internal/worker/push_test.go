@@ -0,0 +1,253 @@
+package worker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNotification = notification{
+	Title:      "Structure fire",
+	PageID:     "page-1",
+	EndpointID: "endpoint-1",
+}
+
+func pemEncode(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// newFCMStub starts a server standing in for both the Google token endpoint and
+// the FCM API. Message requests are answered by handler.
+func newFCMStub(t *testing.T, handler http.HandlerFunc) Config {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.FormValue("grant_type"))
+		assert.Len(t, strings.Split(r.FormValue("assertion"), "."), 3)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access-token",
+			"expires_in":   3600,
+		})
+	})
+	mux.HandleFunc("POST /v1/projects/pager-test/messages:send", handler)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	credentials, err := json.Marshal(fcmCredentials{
+		ProjectID:   "pager-test",
+		ClientEmail: "pager@pager-test.iam.gserviceaccount.com",
+		PrivateKey:  pemEncode(t, key),
+		TokenURI:    server.URL + "/token",
+	})
+	require.NoError(t, err)
+
+	return Config{
+		DeliveryMaxAttempts: 3,
+		FCMBaseURL:          server.URL,
+		FCMCredentials:      string(credentials),
+	}
+}
+
+// newAPNSStub starts a server standing in for APNs. Requests are answered by
+// handler.
+func newAPNSStub(t *testing.T, key *ecdsa.PrivateKey, handler http.HandlerFunc) Config {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return Config{
+		DeliveryMaxAttempts: 3,
+		APNSBaseURL:         server.URL,
+		APNSKeyID:           "KEY123",
+		APNSTeamID:          "TEAM123",
+		APNSTopic:           "com.pager.app",
+		APNSPrivateKey:      pemEncode(t, key),
+	}
+}
+
+func newAPNSKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func newTestPushSender(t *testing.T, config Config) Sender {
+	t.Helper()
+	sender, err := NewPushSender(config, http.DefaultClient)
+	require.NoError(t, err)
+	return sender
+}
+
+func TestPushSenderFCM(t *testing.T) {
+	config := newFCMStub(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer access-token", r.Header.Get("Authorization"))
+
+		var body struct {
+			Message struct {
+				Token        string            `json:"token"`
+				Notification map[string]string `json:"notification"`
+				Data         map[string]string `json:"data"`
+			} `json:"message"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "device-token", body.Message.Token)
+		assert.Equal(t, "Structure fire", body.Message.Notification["title"])
+		assert.Equal(t, "page-1", body.Message.Data["pageId"])
+
+		_, _ = w.Write([]byte(`{"name":"projects/pager-test/messages/1"}`))
+	})
+
+	result := newTestPushSender(t, config).Send(context.Background(), models.Endpoint{
+		PushPlatform: models.PushPlatformFCM,
+		PushToken:    "device-token",
+	}, testNotification)
+
+	require.NoError(t, result.Err)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+}
+
+func TestPushSenderFCMUnregistered(t *testing.T) {
+	config := newFCMStub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"status":"NOT_FOUND","message":"Requested entity was not found.","details":[{"errorCode":"UNREGISTERED"}]}}`))
+	})
+
+	result := newTestPushSender(t, config).Send(context.Background(), models.Endpoint{
+		PushPlatform: models.PushPlatformFCM,
+		PushToken:    "stale-token",
+	}, testNotification)
+
+	assert.ErrorIs(t, result.Err, errEndpointInvalid)
+	assert.Equal(t, 1, result.Attempts)
+}
+
+func TestPushSenderFCMRetriesUnavailable(t *testing.T) {
+	var calls int
+	config := newFCMStub(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":{"status":"UNAVAILABLE"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"projects/pager-test/messages/1"}`))
+	})
+
+	result := newTestPushSender(t, config).Send(context.Background(), models.Endpoint{
+		PushPlatform: models.PushPlatformFCM,
+		PushToken:    "device-token",
+	}, testNotification)
+
+	require.NoError(t, result.Err)
+	assert.Equal(t, 2, result.Attempts)
+}
+
+func TestPushSenderAPNS(t *testing.T) {
+	key := newAPNSKey(t)
+	config := newAPNSStub(t, key, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/3/device/device-token", r.URL.Path)
+		assert.Equal(t, "com.pager.app", r.Header.Get("apns-topic"))
+		assert.Equal(t, "alert", r.Header.Get("apns-push-type"))
+
+		// The provider token must be a valid ES256 JWT signed by the key.
+		jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "bearer ")
+		require.True(t, ok)
+		parts := strings.Split(jwt, ".")
+		require.Len(t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		require.Len(t, signature, 64)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.True(t, ecdsa.Verify(&key.PublicKey, digest[:],
+			new(big.Int).SetBytes(signature[:32]),
+			new(big.Int).SetBytes(signature[32:])))
+
+		var body struct {
+			APS struct {
+				Alert map[string]string `json:"alert"`
+			} `json:"aps"`
+			PageID string `json:"pageId"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "Structure fire", body.APS.Alert["title"])
+		assert.Equal(t, "page-1", body.PageID)
+	})
+
+	result := newTestPushSender(t, config).Send(context.Background(), models.Endpoint{
+		PushPlatform: models.PushPlatformAPNS,
+		PushToken:    "device-token",
+	}, testNotification)
+
+	require.NoError(t, result.Err)
+	assert.Equal(t, 1, result.Attempts)
+}
+
+func TestPushSenderAPNSInvalidToken(t *testing.T) {
+	tests := map[string]struct {
+		status int
+		reason string
+	}{
+		"bad device token": {
+			status: http.StatusBadRequest,
+			reason: "BadDeviceToken",
+		},
+		"unregistered": {
+			status: http.StatusGone,
+			reason: "Unregistered",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := newAPNSStub(t, newAPNSKey(t), func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(`{"reason":"` + tc.reason + `"}`))
+			})
+
+			result := newTestPushSender(t, config).Send(context.Background(), models.Endpoint{
+				PushPlatform: models.PushPlatformAPNS,
+				PushToken:    "stale-token",
+			}, testNotification)
+
+			assert.ErrorIs(t, result.Err, errEndpointInvalid)
+			assert.Equal(t, 1, result.Attempts)
+		})
+	}
+}
+
+func TestPushSenderUnconfiguredPlatform(t *testing.T) {
+	result := newTestPushSender(t, Config{}).Send(context.Background(), models.Endpoint{
+		PushPlatform: models.PushPlatformFCM,
+		PushToken:    "device-token",
+	}, testNotification)
+
+	assert.Error(t, result.Err)
+	assert.NotErrorIs(t, result.Err, errEndpointInvalid)
+}
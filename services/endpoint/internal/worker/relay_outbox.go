@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+
+	lambdaevents "github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/outbox"
+)
+
+// RelayOutbox publishes the events the app stages in the endpoint table's outbox,
+// as read from the table's stream.
+func RelayOutbox(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) func(ctx context.Context, event lambdaevents.DynamoDBEvent) (lambdaevents.DynamoDBEventResponse, error) {
+	return outbox.NewRelay(logger, dynamoClient, publisher, config.EndpointTableName, config.OutboxRecordTTL).Process
+}
+
+// SweepOutbox publishes the events left pending in the endpoint table's outbox, for
+// example because the stream dropped them after running out of retries. It is
+// run on a schedule.
+func SweepOutbox(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) func(ctx context.Context, event lambdaevents.EventBridgeEvent) error {
+	relay := outbox.NewRelay(logger, dynamoClient, publisher, config.EndpointTableName, config.OutboxRecordTTL)
+	return func(ctx context.Context, event lambdaevents.EventBridgeEvent) error {
+		return relay.Sweep(ctx, config.OutboxSweepAge)
+	}
+}
@@ -2,34 +2,33 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
 )
 
-func resolveEndpoint(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) func(ctx context.Context, snsRecord events.SNSEntity, retryCount int) error {
-	type message struct {
-		AgencyID         string `json:"agencyId"`
-		RegistrationCode string `json:"registrationCode"`
-	}
-
-	logAndHandleError := eventProcessorErrorHandler(config, logger, snsClient, evtEndpointResolutionFailed)
+// resolutionFailedExpired is the reason given on endpoint.resolution.failed
+// when the registration code has expired.
+const resolutionFailedExpired = "EXPIRED"
 
-	return func(ctx context.Context, snsRecord events.SNSEntity, retryCount int) error {
-		var message message
-
-		if err := json.Unmarshal([]byte(snsRecord.Message), &message); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to resolve endpoint from registration code", message, err)
+// resolveEndpoint looks up the endpoint a registration code belongs to. An
+// endpoint registered by its owner is resolved straight away, otherwise a
+// pending approval is recorded and the registration is resolved once the owner
+// approves it.
+func resolveEndpoint(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.EndpointResolve](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to resolve endpoint from registration code: %w", err)
 		}
 
 		resolveEndpointResult, err := dynamoClient.Query(ctx, &dynamodb.QueryInput{
@@ -41,7 +40,7 @@ func resolveEndpoint(config Config, logger *slog.Logger, dynamoClient *dynamodb.
 			},
 			ExpressionAttributeValues: map[string]types.AttributeValue{
 				":pk": &types.AttributeValueMemberS{
-					Value: fmt.Sprintf("rc#%s", message.RegistrationCode),
+					Value: fmt.Sprintf("rc#%s", models.NormalizeRegistrationCode(message.RegistrationCode)),
 				},
 				":sk": &types.AttributeValueMemberS{
 					Value: "registrationcode",
@@ -50,44 +49,103 @@ func resolveEndpoint(config Config, logger *slog.Logger, dynamoClient *dynamodb.
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to resolve endpoint from registration code", message, err)
+			return fmt.Errorf("failed to resolve endpoint from registration code: %w", err)
 		}
 
 		if len(resolveEndpointResult.Items) == 0 {
-			return logAndHandleError(ctx, retryCount, "failed to resolve endpoint from registration code", message, errors.New("registration code doesn't exist"))
+			return errors.New("registration code doesn't exist")
 		}
 
 		var rc models.RegistrationCode
 
 		if err := attributevalue.UnmarshalMap(resolveEndpointResult.Items[0], &rc); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to resolve endpoint from registration code", message, err)
+			return fmt.Errorf("failed to resolve endpoint from registration code: %w", err)
 		}
 
-		messageBody, err := json.Marshal(struct {
-			RegistrationCode string `json:"registrationCode"`
-			AgencyID         string `json:"agencyId"`
-			EndpointID       string `json:"endpointId"`
-		}{
-			RegistrationCode: message.RegistrationCode,
-			AgencyID:         message.AgencyID,
-			EndpointID:       rc.EndpointID,
-		})
+		// An expired code won't become valid by retrying, fail the resolution
+		// straight away so the agency can mark the registration expired.
+		if rc.Expired(time.Now()) {
+			logger.WarnContext(
+				ctx,
+				"registration code expired",
+				slog.String("agencyId", message.AgencyID),
+				slog.String("endpointId", rc.EndpointID))
 
-		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to resolve endpoint from registration code", message, err)
+			if err := publisher.Publish(ctx, events.TypeEndpointResolutionFailed, events.EndpointResolutionFailed{
+				RegistrationCode: message.RegistrationCode,
+				AgencyID:         message.AgencyID,
+				Reason:           resolutionFailedExpired,
+			}); err != nil {
+				return fmt.Errorf("failed to resolve endpoint from registration code: %w", err)
+			}
+
+			return nil
 		}
 
-		if _, err := snsClient.Publish(ctx, &sns.PublishInput{
-			TopicArn: aws.String(config.EventsTopicARN),
-			Message:  aws.String(string(messageBody)),
-			MessageAttributes: map[string]snstypes.MessageAttributeValue{
-				"type": {
-					DataType:    aws.String("String"),
-					StringValue: aws.String(evtEndpointResolved),
+		if message.RequestedBy != rc.UserID {
+			now := time.Now()
+
+			approvalAV, err := attributevalue.MarshalMap(models.Approval{
+				KeyFields: models.KeyFields{
+					PK:   fmt.Sprintf("endpoint#%s", rc.EndpointID),
+					SK:   fmt.Sprintf("approval#%s", message.AgencyID),
+					Type: models.EntityTypeApproval,
 				},
-			},
+				AuditableFields:  models.NewAuditableFields(message.RequestedBy, now),
+				Status:           models.ApprovalStatusPending,
+				AgencyID:         message.AgencyID,
+				RegistrationCode: message.RegistrationCode,
+				RequestedBy:      message.RequestedBy,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to resolve endpoint from registration code: %w", err)
+			}
+
+			// A repeated request must not reopen an approval the owner has
+			// already decided, only a pending approval is replaced.
+			_, err = dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:           aws.String(config.EndpointTableName),
+				Item:                approvalAV,
+				ConditionExpression: aws.String("attribute_not_exists(#pk) OR #status = :pending"),
+				ExpressionAttributeNames: map[string]string{
+					"#pk":     "pk",
+					"#status": "status",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":pending": &types.AttributeValueMemberS{Value: models.ApprovalStatusPending},
+				},
+			})
+
+			var conditionFailed *types.ConditionalCheckFailedException
+			if errors.As(err, &conditionFailed) {
+				logger.InfoContext(
+					ctx,
+					"registration already decided by owner",
+					slog.String("agencyId", message.AgencyID),
+					slog.String("endpointId", rc.EndpointID))
+
+				return nil
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to resolve endpoint from registration code: %w", err)
+			}
+
+			logger.InfoContext(
+				ctx,
+				"registration awaiting owner approval",
+				slog.String("agencyId", message.AgencyID),
+				slog.String("endpointId", rc.EndpointID))
+
+			return nil
+		}
+
+		if err := publisher.Publish(ctx, events.TypeEndpointResolved, events.EndpointResolved{
+			RegistrationCode: message.RegistrationCode,
+			AgencyID:         message.AgencyID,
+			EndpointID:       rc.EndpointID,
 		}); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to resolve endpoint from registration code", message, err)
+			return fmt.Errorf("failed to resolve endpoint from registration code: %w", err)
 		}
 
 		return nil
@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRegistrationCodeTable returns a table holding the registration code.
+func newRegistrationCodeTable(t *testing.T, rc models.RegistrationCode) *fakeDynamoDB {
+	t.Helper()
+
+	item, err := attributevalue.MarshalMap(rc)
+	require.NoError(t, err)
+
+	return &fakeDynamoDB{
+		query: func(params *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}
+}
+
+func resolveRequest(t *testing.T, requestedBy string) events.Envelope {
+	return newEnvelope(t, events.TypeEndpointResolve, events.EndpointResolve{
+		RegistrationCode: "ABCD2345",
+		AgencyID:         "agency-1",
+		RequestedBy:      requestedBy,
+	})
+}
+
+func TestResolveEndpointOwner(t *testing.T) {
+	var (
+		client = newRegistrationCodeTable(t, models.RegistrationCode{EndpointID: "endpoint-1", UserID: "owner"})
+		sns    = new(fakeSNS)
+	)
+
+	handler := resolveEndpoint(Config{}, discardLogger, client, events.NewPublisher(sns, "topic", "endpoint"))
+	require.NoError(t, handler(context.Background(), resolveRequest(t, "owner")))
+
+	assert.Empty(t, client.puts)
+	assert.Equal(t, []events.Type{events.TypeEndpointResolved}, sns.types())
+}
+
+func TestResolveEndpointAwaitsApproval(t *testing.T) {
+	var (
+		client = newRegistrationCodeTable(t, models.RegistrationCode{EndpointID: "endpoint-1", UserID: "owner"})
+		sns    = new(fakeSNS)
+	)
+
+	handler := resolveEndpoint(Config{}, discardLogger, client, events.NewPublisher(sns, "topic", "endpoint"))
+	require.NoError(t, handler(context.Background(), resolveRequest(t, "writer")))
+
+	assert.Empty(t, sns.envelopes)
+	require.Len(t, client.puts, 1)
+
+	put := client.puts[0]
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "endpoint#endpoint-1"}, put.Item["pk"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "approval#agency-1"}, put.Item["sk"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: models.ApprovalStatusPending}, put.Item["status"])
+	assert.Equal(t, "attribute_not_exists(#pk) OR #status = :pending", aws.ToString(put.ConditionExpression))
+}
+
+func TestResolveEndpointAlreadyDecided(t *testing.T) {
+	var (
+		client = newRegistrationCodeTable(t, models.RegistrationCode{EndpointID: "endpoint-1", UserID: "owner"})
+		sns    = new(fakeSNS)
+	)
+
+	// The owner approved or declined the approval before the request was
+	// delivered again.
+	client.putItem = func(params *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+
+	handler := resolveEndpoint(Config{}, discardLogger, client, events.NewPublisher(sns, "topic", "endpoint"))
+	require.NoError(t, handler(context.Background(), resolveRequest(t, "writer")))
+
+	assert.Len(t, client.puts, 1)
+	assert.Empty(t, sns.envelopes)
+}
+
+func TestResolveEndpointExpired(t *testing.T) {
+	var (
+		client = newRegistrationCodeTable(t, models.RegistrationCode{
+			EndpointID: "endpoint-1",
+			UserID:     "owner",
+			Expires:    time.Now().Add(-time.Minute),
+		})
+		sns = new(fakeSNS)
+	)
+
+	handler := resolveEndpoint(Config{}, discardLogger, client, events.NewPublisher(sns, "topic", "endpoint"))
+	require.NoError(t, handler(context.Background(), resolveRequest(t, "writer")))
+
+	// The resolution fails without waiting for approval.
+	assert.Empty(t, client.puts)
+	require.Len(t, sns.envelopes, 1)
+	assert.Equal(t, events.TypeEndpointResolutionFailed, sns.envelopes[0].Type)
+	assert.JSONEq(t, `{"registrationCode": "ABCD2345", "agencyId": "agency-1", "reason": "EXPIRED"}`, string(sns.envelopes[0].Payload))
+}
+
+func TestResolveEndpointNormalizesCode(t *testing.T) {
+	var (
+		client = newRegistrationCodeTable(t, models.RegistrationCode{EndpointID: "endpoint-1", UserID: "owner"})
+		sns    = new(fakeSNS)
+	)
+
+	handler := resolveEndpoint(Config{}, discardLogger, client, events.NewPublisher(sns, "topic", "endpoint"))
+	require.NoError(t, handler(context.Background(), newEnvelope(t, events.TypeEndpointResolve, events.EndpointResolve{
+		RegistrationCode: "abcd-2345",
+		AgencyID:         "agency-1",
+		RequestedBy:      "owner",
+	})))
+
+	require.Len(t, client.queries, 1)
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "rc#ABCD2345"}, client.queries[0].ExpressionAttributeValues[":pk"])
+}
+
+func TestResolveEndpointUnknownCode(t *testing.T) {
+	handler := resolveEndpoint(Config{}, discardLogger, new(fakeDynamoDB), events.NewPublisher(new(fakeSNS), "topic", "endpoint"))
+	assert.Error(t, handler(context.Background(), resolveRequest(t, "owner")))
+}
@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+// errEndpointInvalid is returned by a Sender when the provider has reported the
+// endpoint as permanently undeliverable, for example an unregistered push
+// token. Endpoints that fail with this error are disabled.
+var errEndpointInvalid = errors.New("endpoint is no longer valid")
+
+// notification is the content of a page delivered to an endpoint.
+type notification struct {
+	Title      string    `json:"title"`
+	Notes      string    `json:"notes,omitempty"`
+	Location   *location `json:"location,omitempty"`
+	PageID     string    `json:"pageId"`
+	EndpointID string    `json:"endpointId"`
+}
+
+// location is where a page is for. Latitude and longitude are in decimal
+// degrees and are zero when the location is only described.
+type location struct {
+	Description string  `json:"description"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Type        string  `json:"type"`
+}
+
+// MapURL returns a link to the location on a map, or an empty string if the
+// location has no coordinates.
+func (l *location) MapURL() string {
+	if l == nil || (l.Latitude == 0 && l.Longitude == 0) {
+		return ""
+	}
+	return fmt.Sprintf("https://www.google.com/maps/search/?api=1&query=%s,%s",
+		strconv.FormatFloat(l.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(l.Longitude, 'f', -1, 64))
+}
+
+// Sender delivers a notification to a single endpoint. There is one Sender for
+// each EndpointType.
+type Sender interface {
+	Send(ctx context.Context, endpoint models.Endpoint, notification notification) deliveryResult
+}
+
+// deliveryResult describes the outcome of delivering a payload to an endpoint,
+// including every attempt made. ProviderMessageIDs holds the identifiers the
+// provider assigned to the messages it accepted, if it assigns any.
+type deliveryResult struct {
+	Attempts           int
+	StatusCode         int
+	Latency            time.Duration
+	ProviderMessageIDs []string
+	Err                error
+}
+
+// retryPolicy controls how a delivery is retried.
+type retryPolicy struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// backoff returns the delay before the given retry using exponential backoff
+// with full jitter.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	ceiling := p.BaseBackoff << retry
+	if ceiling <= 0 || ceiling > p.MaxBackoff {
+		ceiling = p.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling)
+}
+
+// attemptFunc makes a single delivery attempt and reports whether a failure is
+// worth retrying.
+type attemptFunc func(ctx context.Context) (retryable bool, statusCode int, err error)
+
+// withRetries makes attempts according to the retry policy until one succeeds,
+// fails permanently or the attempts are exhausted.
+func withRetries(ctx context.Context, policy retryPolicy, attempt attemptFunc) deliveryResult {
+	var (
+		result deliveryResult
+		start  = time.Now()
+	)
+
+	for i := 0; i < max(policy.MaxAttempts, 1); i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+				result.Latency = time.Since(start)
+				return result
+			case <-time.After(policy.backoff(i - 1)):
+			}
+		}
+
+		result.Attempts++
+
+		retryable, statusCode, err := attempt(ctx)
+		result.StatusCode = statusCode
+		result.Err = err
+
+		if err == nil || !retryable {
+			break
+		}
+	}
+
+	result.Latency = time.Since(start)
+
+	return result
+}
+
+// newRetryPolicy builds the retry policy used by every Sender from config.
+func newRetryPolicy(config Config) retryPolicy {
+	return retryPolicy{
+		MaxAttempts: config.DeliveryMaxAttempts,
+		BaseBackoff: config.DeliveryBaseBackoff,
+		MaxBackoff:  config.DeliveryMaxBackoff,
+	}
+}
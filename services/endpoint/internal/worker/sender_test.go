@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := retryPolicy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	// The ceiling doubles from the base backoff until it reaches the maximum,
+	// including once shifting the base backoff overflows.
+	for retry := range 80 {
+		for range 20 {
+			backoff := policy.backoff(retry)
+			assert.GreaterOrEqual(t, backoff, time.Duration(0))
+			assert.Less(t, backoff, policy.MaxBackoff)
+			if retry == 0 {
+				assert.Less(t, backoff, policy.BaseBackoff)
+			}
+		}
+	}
+
+	assert.Zero(t, retryPolicy{}.backoff(3))
+}
+
+func TestWithRetriesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result := withRetries(ctx, retryPolicy{MaxAttempts: 3, BaseBackoff: time.Hour, MaxBackoff: time.Hour}, func(ctx context.Context) (bool, int, error) {
+		cancel()
+		return true, 503, errors.New("unavailable")
+	})
+
+	// The backoff is abandoned when the context is done.
+	assert.Equal(t, 1, result.Attempts)
+	assert.ErrorIs(t, result.Err, context.Canceled)
+}
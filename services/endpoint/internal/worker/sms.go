@@ -0,0 +1,271 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+// invalidRecipientCodes are the Twilio error codes returned when a message can
+// never be delivered to the recipient, such as an invalid or landline number or
+// a recipient who has replied STOP.
+var invalidRecipientCodes = []int{21211, 21610, 21614}
+
+// smsSender delivers notifications to SMS endpoints through a Twilio
+// compatible REST API.
+type smsSender struct {
+	client      *http.Client
+	baseURL     string
+	accountSID  string
+	authToken   string
+	from        string
+	maxSegments int
+	policy      retryPolicy
+}
+
+// NewSMSSender returns the Sender for SMS endpoints.
+func NewSMSSender(config Config, client *http.Client) Sender {
+	return &smsSender{
+		client:      client,
+		baseURL:     strings.TrimSuffix(config.SMSBaseURL, "/"),
+		accountSID:  config.SMSAccountSID,
+		authToken:   config.SMSAuthToken,
+		from:        config.SMSFromNumber,
+		maxSegments: config.SMSMaxSegments,
+		policy:      newRetryPolicy(config),
+	}
+}
+
+// Send delivers the notification as one or more messages. Each segment is
+// retried on its own, so retrying a segment doesn't resend the ones before it.
+// A segment that still fails fails the delivery, and the segments that were
+// accepted are sent again if the page is redelivered.
+func (s *smsSender) Send(ctx context.Context, endpoint models.Endpoint, notification notification) deliveryResult {
+	if s.accountSID == "" {
+		return deliveryResult{Err: errors.New("sms is not configured")}
+	}
+
+	var (
+		result deliveryResult
+		start  = time.Now()
+	)
+
+	for _, segment := range smsSegments(smsText(notification), s.maxSegments) {
+		var sid string
+
+		segmentResult := withRetries(ctx, s.policy, func(ctx context.Context) (bool, int, error) {
+			var (
+				retryable  bool
+				statusCode int
+				err        error
+			)
+			sid, retryable, statusCode, err = s.send(ctx, endpoint.PhoneNumber, segment)
+			return retryable, statusCode, err
+		})
+
+		result.Attempts += segmentResult.Attempts
+		result.StatusCode = segmentResult.StatusCode
+
+		if segmentResult.Err != nil {
+			result.Err = segmentResult.Err
+			break
+		}
+
+		result.ProviderMessageIDs = append(result.ProviderMessageIDs, sid)
+	}
+
+	result.Latency = time.Since(start)
+
+	return result
+}
+
+// send makes a single attempt to create a message, returning its SID.
+func (s *smsSender) send(ctx context.Context, to, text string) (string, bool, int, error) {
+	form := url.Values{
+		"To":   {to},
+		"From": {s.from},
+		"Body": {text},
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.baseURL, url.PathEscape(s.accountSID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", false, 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.accountSID, s.authToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", true, 0, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		SID     string `json:"sid"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return body.SID, false, resp.StatusCode, nil
+	}
+
+	if slices.Contains(invalidRecipientCodes, body.Code) {
+		return "", false, resp.StatusCode, fmt.Errorf("%w: sms: %d %s", errEndpointInvalid, body.Code, body.Message)
+	}
+
+	return "", retryableStatus(resp.StatusCode), resp.StatusCode, fmt.Errorf("sms: unexpected status code %d: %d %s", resp.StatusCode, body.Code, body.Message)
+}
+
+// smsText is the text of the SMS sent for a notification.
+func smsText(notification notification) string {
+	text := strings.TrimSpace(notification.Title)
+	if notes := strings.TrimSpace(notification.Notes); notes != "" {
+		text += "\n" + notes
+	}
+	return text
+}
+
+const (
+	// gsm7Basic is the GSM 03.38 basic character set. Text made only of these
+	// and the extended characters is sent as GSM-7, anything else forces UCS-2.
+	gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	// gsm7Extended characters take two septets, an escape and the character.
+	gsm7Extended = "\f^{}\\[~]|€"
+
+	gsm7SegmentLength = 160
+	ucs2SegmentLength = 70
+
+	truncationSuffix = "..."
+)
+
+// smsSegments splits text into segments that each fit in a single SMS. When
+// more than one segment is needed each is prefixed with its position, e.g.
+// "(1/3) ", and breaks are made between words where possible. Text that would
+// need more than maxSegments is truncated.
+func smsSegments(text string, maxSegments int) []string {
+	maxSegments = max(maxSegments, 1)
+
+	gsm7 := isGSM7(text)
+
+	segmentLength := ucs2SegmentLength
+	if gsm7 {
+		segmentLength = gsm7SegmentLength
+	}
+
+	cost := func(r rune) int {
+		if gsm7 {
+			if strings.ContainsRune(gsm7Extended, r) {
+				return 2
+			}
+			return 1
+		}
+		// Characters outside the BMP are sent as a UTF-16 surrogate pair.
+		if r > 0xFFFF {
+			return 2
+		}
+		return 1
+	}
+
+	runes := []rune(text)
+
+	if runesCost(runes, cost) <= segmentLength {
+		return []string{text}
+	}
+
+	// The prefix grows with the number of segments, so reserve room for it
+	// based on how many digits the segment count needs.
+	var chunks [][]rune
+	for digits := 1; ; digits++ {
+		capacity := segmentLength - len("(/) ") - 2*digits
+		chunks = chunkRunes(runes, capacity, cost)
+
+		if len(chunks) > maxSegments {
+			chunks = chunks[:maxSegments]
+			last := chunks[maxSegments-1]
+			for len(last) > 0 && runesCost(last, cost)+len(truncationSuffix) > capacity {
+				last = last[:len(last)-1]
+			}
+			chunks[maxSegments-1] = append(slices.Clone(last), []rune(truncationSuffix)...)
+		}
+
+		if len(fmt.Sprint(len(chunks))) <= digits {
+			break
+		}
+	}
+
+	segments := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		segments[i] = fmt.Sprintf("(%d/%d) %s", i+1, len(chunks), strings.TrimSpace(string(chunk)))
+	}
+
+	return segments
+}
+
+// chunkRunes greedily splits runes into chunks costing at most capacity,
+// breaking at the last whitespace in a chunk when there is one.
+func chunkRunes(runes []rune, capacity int, cost func(rune) int) [][]rune {
+	var chunks [][]rune
+
+	for len(runes) > 0 {
+		var (
+			used  int
+			end   int
+			space = -1
+		)
+
+		for end < len(runes) && used+cost(runes[end]) <= capacity {
+			used += cost(runes[end])
+			if unicode.IsSpace(runes[end]) {
+				space = end
+			}
+			end++
+		}
+
+		if end < len(runes) && space > 0 {
+			end = space + 1
+		}
+
+		// Always make progress, even if a single character exceeds capacity.
+		if end == 0 {
+			end = 1
+		}
+
+		chunks = append(chunks, runes[:end])
+		runes = runes[end:]
+	}
+
+	return chunks
+}
+
+func runesCost(runes []rune, cost func(rune) int) int {
+	var total int
+	for _, r := range runes {
+		total += cost(r)
+	}
+	return total
+}
+
+// isGSM7 reports whether text can be encoded with the GSM-7 alphabet.
+func isGSM7(text string) bool {
+	for _, r := range text {
+		if !strings.ContainsRune(gsm7Basic, r) && !strings.ContainsRune(gsm7Extended, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,204 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTwilio records the messages created through it. Requests are answered by
+// respond, which defaults to accepting every message.
+type fakeTwilio struct {
+	mu       sync.Mutex
+	messages []string
+	respond  func(w http.ResponseWriter, n int) bool
+}
+
+func newFakeTwilio(t *testing.T, fake *fakeTwilio) Config {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "secret", pass)
+		assert.Equal(t, "+15005550006", r.FormValue("From"))
+		assert.Equal(t, "+13035550123", r.FormValue("To"))
+
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+
+		n := len(fake.messages)
+		if fake.respond != nil && fake.respond(w, n) {
+			return
+		}
+
+		fake.messages = append(fake.messages, r.FormValue("Body"))
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"sid":    fmt.Sprintf("SM%d", n+1),
+			"status": "queued",
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return Config{
+		DeliveryMaxAttempts: 3,
+		SMSBaseURL:          server.URL,
+		SMSAccountSID:       "AC123",
+		SMSAuthToken:        "secret",
+		SMSFromNumber:       "+15005550006",
+		SMSMaxSegments:      4,
+	}
+}
+
+var smsEndpoint = models.Endpoint{
+	EndpointType: models.EndpointTypeSMS,
+	PhoneNumber:  "+13035550123",
+}
+
+func TestSMSSenderSingleSegment(t *testing.T) {
+	fake := &fakeTwilio{}
+	sender := NewSMSSender(newFakeTwilio(t, fake), http.DefaultClient)
+
+	result := sender.Send(context.Background(), smsEndpoint, notification{
+		Title: "Structure fire",
+		Notes: "Main St and 3rd",
+	})
+
+	require.NoError(t, result.Err)
+	assert.Equal(t, []string{"SM1"}, result.ProviderMessageIDs)
+	assert.Equal(t, []string{"Structure fire\nMain St and 3rd"}, fake.messages)
+}
+
+func TestSMSSenderMultipleSegments(t *testing.T) {
+	fake := &fakeTwilio{}
+	sender := NewSMSSender(newFakeTwilio(t, fake), http.DefaultClient)
+
+	result := sender.Send(context.Background(), smsEndpoint, notification{
+		Title: "Lost hiker",
+		Notes: strings.Repeat("Last seen near the trailhead heading north. ", 6),
+	})
+
+	require.NoError(t, result.Err)
+	assert.Equal(t, []string{"SM1", "SM2"}, result.ProviderMessageIDs)
+	require.Len(t, fake.messages, 2)
+	assert.True(t, strings.HasPrefix(fake.messages[0], "(1/2) Lost hiker"))
+	assert.True(t, strings.HasPrefix(fake.messages[1], "(2/2) "))
+}
+
+func TestSMSSenderRetriesSegment(t *testing.T) {
+	var failed bool
+	fake := &fakeTwilio{
+		// Fail the second segment once.
+		respond: func(w http.ResponseWriter, n int) bool {
+			if n == 1 && !failed {
+				failed = true
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return true
+			}
+			return false
+		},
+	}
+	sender := NewSMSSender(newFakeTwilio(t, fake), http.DefaultClient)
+
+	result := sender.Send(context.Background(), smsEndpoint, notification{
+		Title: "Lost hiker",
+		Notes: strings.Repeat("Last seen near the trailhead heading north. ", 6),
+	})
+
+	require.NoError(t, result.Err)
+	assert.Equal(t, 3, result.Attempts)
+	// The first segment is not sent twice.
+	assert.Len(t, fake.messages, 2)
+}
+
+func TestSMSSenderInvalidRecipient(t *testing.T) {
+	fake := &fakeTwilio{
+		respond: func(w http.ResponseWriter, n int) bool {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":21211,"message":"The 'To' number is not a valid phone number."}`))
+			return true
+		},
+	}
+	sender := NewSMSSender(newFakeTwilio(t, fake), http.DefaultClient)
+
+	result := sender.Send(context.Background(), smsEndpoint, notification{Title: "Structure fire"})
+
+	assert.ErrorIs(t, result.Err, errEndpointInvalid)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Empty(t, result.ProviderMessageIDs)
+}
+
+func TestSMSSegments(t *testing.T) {
+	tests := map[string]struct {
+		text          string
+		maxSegments   int
+		segments      int
+		segmentLength int
+	}{
+		"fits in one gsm-7 segment": {
+			text:          strings.Repeat("a", 160),
+			maxSegments:   4,
+			segments:      1,
+			segmentLength: 160,
+		},
+		"splits gsm-7": {
+			text:          strings.Repeat("word ", 64),
+			maxSegments:   4,
+			segments:      3,
+			segmentLength: 160,
+		},
+		"extended characters count double": {
+			text:          strings.Repeat("{", 81),
+			maxSegments:   4,
+			segments:      2,
+			segmentLength: 160,
+		},
+		"splits ucs-2": {
+			text:          strings.Repeat("ü漢 ", 40),
+			maxSegments:   4,
+			segments:      2,
+			segmentLength: 70,
+		},
+		"truncates past max segments": {
+			text:          strings.Repeat("word ", 200),
+			maxSegments:   2,
+			segments:      2,
+			segmentLength: 160,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			segments := smsSegments(tc.text, tc.maxSegments)
+
+			require.Len(t, segments, tc.segments)
+			for i, segment := range segments {
+				length := utf8.RuneCountInString(segment)
+				if isGSM7(segment) {
+					length += strings.Count(segment, "{")
+				}
+				assert.LessOrEqual(t, length, tc.segmentLength)
+				if tc.segments > 1 {
+					assert.True(t, strings.HasPrefix(segment, fmt.Sprintf("(%d/%d) ", i+1, tc.segments)))
+				}
+			}
+		})
+	}
+
+	truncated := smsSegments(strings.Repeat("word ", 200), 2)
+	assert.True(t, strings.HasSuffix(truncated[1], "..."))
+}
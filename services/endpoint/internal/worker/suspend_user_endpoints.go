@@ -0,0 +1,184 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+// suspendUserEndpoints suspends every endpoint owned by a user when they are
+// deactivated, and lifts the suspension when they are reactivated.
+//
+// Deactivating and reactivating in quick succession can deliver the events out
+// of order, so the user's status is recorded first and only replaced by a later
+// change. Each endpoint is updated only while the recorded status is still the
+// one being applied.
+func suspendUserEndpoints(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.UserDeactivated](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to suspend user endpoints: %w", err)
+		}
+
+		statusModified := &types.AttributeValueMemberN{
+			Value: strconv.FormatInt(message.Modified.UnixNano(), 10),
+		}
+
+		stateAV, err := attributevalue.MarshalMap(models.UserState{
+			KeyFields: models.KeyFields{
+				PK:   fmt.Sprintf("user#%s", message.UserID),
+				SK:   "status",
+				Type: models.EntityTypeUserState,
+			},
+			AuditableFields: models.NewAuditableFields(message.ModifiedBy, message.Modified),
+			Status:          message.Status,
+			StatusModified:  message.Modified.UnixNano(),
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal user status: %w", err)
+		}
+
+		_, err = dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(config.EndpointTableName),
+			Item:                stateAV,
+			ConditionExpression: aws.String("attribute_not_exists(#pk) OR #statusModified <= :statusModified"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk":             "pk",
+				"#statusModified": "statusModified",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":statusModified": statusModified,
+			},
+		})
+
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			logger.InfoContext(ctx, "ignoring stale user status", slog.String("userId", message.UserID))
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to put user status: %w", err)
+		}
+
+		paginator := dynamodb.NewQueryPaginator(dynamoClient, &dynamodb.QueryInput{
+			TableName:              aws.String(config.EndpointTableName),
+			KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk": "pk",
+				"#sk": "sk",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("user#%s", message.UserID),
+				},
+				":sk": &types.AttributeValueMemberS{Value: "endpoint#"},
+			},
+		})
+
+		var owners []models.Owner
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to suspend user endpoints: %w", err)
+			}
+
+			var results []models.Owner
+			if err := attributevalue.UnmarshalListOfMaps(page.Items, &results); err != nil {
+				return fmt.Errorf("failed to suspend user endpoints: %w", err)
+			}
+
+			owners = append(owners, results...)
+		}
+
+		updateExpression := "SET #suspended = :suspended, #modified = :modified, #modifiedBy = :modifiedBy"
+		values := map[string]types.AttributeValue{
+			":suspended":  &types.AttributeValueMemberBOOL{Value: true},
+			":modified":   &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339Nano)},
+			":modifiedBy": &types.AttributeValueMemberS{Value: "system"},
+		}
+		if message.Status != identity.StatusInactive {
+			updateExpression = "SET #modified = :modified, #modifiedBy = :modifiedBy REMOVE #suspended"
+			delete(values, ":suspended")
+		}
+
+		for _, owner := range owners {
+			endpointID := strings.TrimPrefix(owner.SK, "endpoint#")
+
+			_, err := dynamoClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{
+						ConditionCheck: &types.ConditionCheck{
+							TableName: aws.String(config.EndpointTableName),
+							Key: map[string]types.AttributeValue{
+								"pk": stateAV["pk"],
+								"sk": stateAV["sk"],
+							},
+							ConditionExpression: aws.String("#statusModified = :statusModified"),
+							ExpressionAttributeNames: map[string]string{
+								"#statusModified": "statusModified",
+							},
+							ExpressionAttributeValues: map[string]types.AttributeValue{
+								":statusModified": statusModified,
+							},
+						},
+					},
+					{
+						Update: &types.Update{
+							TableName: aws.String(config.EndpointTableName),
+							Key: map[string]types.AttributeValue{
+								"pk": &types.AttributeValueMemberS{
+									Value: fmt.Sprintf("endpoint#%s", endpointID),
+								},
+								"sk": &types.AttributeValueMemberS{
+									Value: "meta",
+								},
+							},
+							// Don't recreate an endpoint that has since been deleted.
+							ConditionExpression: aws.String("attribute_exists(#pk)"),
+							UpdateExpression:    aws.String(updateExpression),
+							ExpressionAttributeNames: map[string]string{
+								"#pk":         "pk",
+								"#suspended":  "suspended",
+								"#modified":   "modified",
+								"#modifiedBy": "modifiedBy",
+							},
+							ExpressionAttributeValues: values,
+						},
+					},
+				},
+			})
+
+			// A later status change has been recorded, the endpoints are left
+			// to it.
+			if conditionFailedAt(err, 0) {
+				logger.InfoContext(ctx, "user status changed while suspending endpoints", slog.String("userId", message.UserID))
+				return nil
+			}
+
+			if conditionFailedAt(err, 1) {
+				continue
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to suspend user endpoints for endpoint %s: %w", endpointID, err)
+			}
+		}
+
+		return nil
+	}
+}
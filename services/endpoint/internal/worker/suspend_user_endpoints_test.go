@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var userStatusModified = time.Date(2025, 1, 2, 3, 4, 5, 600, time.UTC)
+
+// newOwnerTable returns a table holding the user's ownership of endpoint-1 and
+// endpoint-2.
+func newOwnerTable(t *testing.T) *fakeDynamoDB {
+	t.Helper()
+
+	var items []map[string]types.AttributeValue
+	for _, endpointID := range []string{"endpoint-1", "endpoint-2"} {
+		item, err := attributevalue.MarshalMap(models.Owner{
+			Type:      models.EntityTypeOwner,
+			KeyFields: models.KeyFields{PK: "user#user-1", SK: "endpoint#" + endpointID},
+		})
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+
+	return &fakeDynamoDB{
+		query: func(params *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{Items: items}, nil
+		},
+	}
+}
+
+func userStatusEvent(t *testing.T, status identity.Status) events.Envelope {
+	eventType := events.TypeUserReactivated
+	if status == identity.StatusInactive {
+		eventType = events.TypeUserDeactivated
+	}
+
+	return newEnvelope(t, eventType, events.UserDeactivated{
+		UserID:     "user-1",
+		Status:     status,
+		Modified:   userStatusModified,
+		ModifiedBy: "admin",
+	})
+}
+
+func TestSuspendUserEndpoints(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           identity.Status
+		updateExpression string
+	}{
+		{
+			name:             "deactivated",
+			status:           identity.StatusInactive,
+			updateExpression: "SET #suspended = :suspended, #modified = :modified, #modifiedBy = :modifiedBy",
+		},
+		{
+			name:             "reactivated",
+			status:           identity.StatusActive,
+			updateExpression: "SET #modified = :modified, #modifiedBy = :modifiedBy REMOVE #suspended",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newOwnerTable(t)
+
+			handler := suspendUserEndpoints(Config{}, discardLogger, client)
+			require.NoError(t, handler(context.Background(), userStatusEvent(t, tt.status)))
+
+			statusModified := &types.AttributeValueMemberN{Value: strconv.FormatInt(userStatusModified.UnixNano(), 10)}
+
+			// The status is recorded unless a later one has been.
+			require.Len(t, client.puts, 1)
+			put := client.puts[0]
+			assert.Equal(t, "attribute_not_exists(#pk) OR #statusModified <= :statusModified", aws.ToString(put.ConditionExpression))
+			assert.Equal(t, statusModified, put.ExpressionAttributeValues[":statusModified"])
+
+			var state models.UserState
+			require.NoError(t, attributevalue.UnmarshalMap(put.Item, &state))
+			assert.Equal(t, "user#user-1", state.PK)
+			assert.Equal(t, "status", state.SK)
+			assert.Equal(t, tt.status, state.Status)
+			assert.Equal(t, userStatusModified.UnixNano(), state.StatusModified)
+
+			// Each endpoint is updated while the status is still the one
+			// recorded.
+			require.Len(t, client.transacts, 2)
+			for i, endpointID := range []string{"endpoint-1", "endpoint-2"} {
+				items := client.transacts[i].TransactItems
+				require.Len(t, items, 2)
+
+				check := items[0].ConditionCheck
+				require.NotNil(t, check)
+				assert.Equal(t, &types.AttributeValueMemberS{Value: "user#user-1"}, check.Key["pk"])
+				assert.Equal(t, &types.AttributeValueMemberS{Value: "status"}, check.Key["sk"])
+				assert.Equal(t, "#statusModified = :statusModified", aws.ToString(check.ConditionExpression))
+				assert.Equal(t, statusModified, check.ExpressionAttributeValues[":statusModified"])
+
+				update := items[1].Update
+				require.NotNil(t, update)
+				assert.Equal(t, &types.AttributeValueMemberS{Value: "endpoint#" + endpointID}, update.Key["pk"])
+				assert.Equal(t, tt.updateExpression, aws.ToString(update.UpdateExpression))
+			}
+		})
+	}
+}
+
+func TestSuspendUserEndpointsStale(t *testing.T) {
+	client := newOwnerTable(t)
+
+	// A later status has been recorded.
+	client.putItem = func(params *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+
+	handler := suspendUserEndpoints(Config{}, discardLogger, client)
+	require.NoError(t, handler(context.Background(), userStatusEvent(t, identity.StatusInactive)))
+
+	assert.Empty(t, client.queries)
+	assert.Empty(t, client.transacts)
+}
+
+func TestSuspendUserEndpointsSuperseded(t *testing.T) {
+	client := newOwnerTable(t)
+
+	// A later status is recorded while the endpoints are being updated.
+	client.transactWriteItems = func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+		return nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")}},
+		}
+	}
+
+	handler := suspendUserEndpoints(Config{}, discardLogger, client)
+	require.NoError(t, handler(context.Background(), userStatusEvent(t, identity.StatusInactive)))
+
+	assert.Len(t, client.transacts, 1)
+}
+
+func TestSuspendUserEndpointsDeletedEndpoint(t *testing.T) {
+	client := newOwnerTable(t)
+
+	// The endpoints were deleted after they were read.
+	client.transactWriteItems = func(params *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+		return nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("None")}, {Code: aws.String("ConditionalCheckFailed")}},
+		}
+	}
+
+	handler := suspendUserEndpoints(Config{}, discardLogger, client)
+	require.NoError(t, handler(context.Background(), userStatusEvent(t, identity.StatusInactive)))
+
+	assert.Len(t, client.transacts, 2)
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncAgencyStatus(t *testing.T) {
+	var (
+		client   = new(fakeDynamoDB)
+		modified = time.Date(2025, 1, 2, 3, 4, 5, 600, time.UTC)
+	)
+
+	handler := syncAgencyStatus(Config{}, discardLogger, client)
+	require.NoError(t, handler(context.Background(), newEnvelope(t, events.TypeAgencyDeactivated, events.AgencyDeactivated{
+		AgencyID:   "agency-1",
+		Status:     models.AgencyStatusInactive,
+		Modified:   modified,
+		ModifiedBy: "admin",
+	})))
+
+	require.Len(t, client.puts, 1)
+	put := client.puts[0]
+
+	// The status time is compared as a number, formatted times don't sort.
+	assert.Equal(t,
+		"attribute_not_exists(#pk) OR attribute_not_exists(#statusModified) OR #statusModified <= :statusModified",
+		aws.ToString(put.ConditionExpression))
+	assert.Equal(t,
+		&types.AttributeValueMemberN{Value: strconv.FormatInt(modified.UnixNano(), 10)},
+		put.ExpressionAttributeValues[":statusModified"])
+
+	var state models.AgencyState
+	require.NoError(t, attributevalue.UnmarshalMap(put.Item, &state))
+	assert.Equal(t, "agency#agency-1", state.PK)
+	assert.Equal(t, "status", state.SK)
+	assert.Equal(t, models.AgencyStatusInactive, state.Status)
+	assert.Equal(t, modified.UnixNano(), state.StatusModified)
+}
+
+func TestSyncAgencyStatusStale(t *testing.T) {
+	client := &fakeDynamoDB{
+		putItem: func(params *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{}
+		},
+	}
+
+	handler := syncAgencyStatus(Config{}, discardLogger, client)
+	assert.NoError(t, handler(context.Background(), newEnvelope(t, events.TypeAgencyReactivated, events.AgencyDeactivated{
+		AgencyID: "agency-1",
+		Status:   models.AgencyStatusActive,
+		Modified: time.Now(),
+	})))
+}
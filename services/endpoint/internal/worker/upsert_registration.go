@@ -2,35 +2,25 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
 )
 
-func upsertRegistration(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) func(ctx context.Context, snsRecord events.SNSEntity, retryCount int) error {
-	type message struct {
-		AgencyID   string `json:"agencyId"`
-		EndpointID string `json:"endpointId"`
-	}
-
-	logAndHandleError := eventProcessorErrorHandler(config, logger, snsClient, evtRegistrationUpsertFailed)
-
-	return func(ctx context.Context, snsRecord events.SNSEntity, retryCount int) error {
-		var message message
-
-		if err := json.Unmarshal([]byte(snsRecord.Message), &message); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert endpoint registration", message, err)
+func upsertRegistration(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, publisher *events.Publisher) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.AgencyRegistrationCreated](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to upsert endpoint registration: %w", err)
 		}
 
 		queryEndpointResult, err := dynamoClient.Query(ctx, &dynamodb.QueryInput{
@@ -51,17 +41,17 @@ func upsertRegistration(config Config, logger *slog.Logger, dynamoClient *dynamo
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert endpoint registration", message, err)
+			return fmt.Errorf("failed to upsert endpoint registration: %w", err)
 		}
 
 		if len(queryEndpointResult.Items) == 0 {
-			return logAndHandleError(ctx, retryCount, "failed to upsert endpoint registration", message, errors.New("endpoint doesn't exist"))
+			return errors.New("endpoint doesn't exist")
 		}
 
 		var endpoint models.Endpoint
 
 		if err := attributevalue.UnmarshalMap(queryEndpointResult.Items[0], &endpoint); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert endpoint registration", message, err)
+			return fmt.Errorf("failed to upsert endpoint registration: %w", err)
 		}
 
 		if endpoint.Registrations == nil {
@@ -71,7 +61,7 @@ func upsertRegistration(config Config, logger *slog.Logger, dynamoClient *dynamo
 
 		registrations, err := attributevalue.MarshalMap(endpoint.Registrations)
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert endpoint registration", message, err)
+			return fmt.Errorf("failed to upsert endpoint registration: %w", err)
 		}
 
 		membership, err := attributevalue.MarshalMap(models.Registration{
@@ -83,9 +73,10 @@ func upsertRegistration(config Config, logger *slog.Logger, dynamoClient *dynamo
 			Type:            models.EntityTypeRegistration,
 			EndpointType:    endpoint.EndpointType,
 			URL:             endpoint.URL,
+			Disabled:        endpoint.Disabled,
 		})
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert endpoint registration", message, err)
+			return fmt.Errorf("failed to upsert endpoint registration: %w", err)
 		}
 
 		membershipInverse, err := attributevalue.MarshalMap(models.Registration{
@@ -97,9 +88,10 @@ func upsertRegistration(config Config, logger *slog.Logger, dynamoClient *dynamo
 			Type:            models.EntityTypeRegistration,
 			EndpointType:    endpoint.EndpointType,
 			URL:             endpoint.URL,
+			Disabled:        endpoint.Disabled,
 		})
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert endpoint registration", message, err)
+			return fmt.Errorf("failed to upsert endpoint registration: %w", err)
 		}
 
 		if _, err := dynamoClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
@@ -140,32 +132,14 @@ func upsertRegistration(config Config, logger *slog.Logger, dynamoClient *dynamo
 				},
 			},
 		}); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert endpoint registration", message, err)
+			return fmt.Errorf("failed to upsert endpoint registration: %w", err)
 		}
 
-		messageBody, err := json.Marshal(struct {
-			AgencyID   string `json:"agencyId"`
-			EndpointID string `json:"endpointId"`
-		}{
+		if err := publisher.Publish(ctx, events.TypeEndpointRegistrationUpserted, events.EndpointRegistrationUpserted{
 			AgencyID:   message.AgencyID,
 			EndpointID: message.EndpointID,
-		})
-
-		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert endpoint registration", message, err)
-		}
-
-		if _, err = snsClient.Publish(ctx, &sns.PublishInput{
-			TopicArn: aws.String(config.EventsTopicARN),
-			Message:  aws.String(string(messageBody)),
-			MessageAttributes: map[string]snstypes.MessageAttributeValue{
-				"type": {
-					DataType:    aws.String("String"),
-					StringValue: aws.String(evtRegistrationUpserted),
-				},
-			},
 		}); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert endpoint registration", message, err)
+			return fmt.Errorf("failed to upsert endpoint registration: %w", err)
 		}
 
 		return nil
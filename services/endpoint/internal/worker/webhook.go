@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/pkg/webhook"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+)
+
+// webhookSender delivers notifications to WEBHOOK endpoints with a signed POST.
+type webhookSender struct {
+	client *http.Client
+	policy retryPolicy
+}
+
+// NewWebhookSender returns the Sender for WEBHOOK endpoints.
+func NewWebhookSender(config Config, client *http.Client) Sender {
+	return &webhookSender{
+		client: client,
+		policy: newRetryPolicy(config),
+	}
+}
+
+// Send POSTs the notification to the endpoint URL, retrying network errors,
+// 429 and 5xx responses. Any other non-2xx response is treated as a permanent
+// failure. Each attempt is signed so the signature timestamp reflects when it
+// was sent.
+func (s *webhookSender) Send(ctx context.Context, endpoint models.Endpoint, notification notification) deliveryResult {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return deliveryResult{Err: err}
+	}
+
+	secrets := endpoint.SigningSecrets(time.Now())
+
+	return withRetries(ctx, s.policy, func(ctx context.Context) (bool, int, error) {
+		return s.post(ctx, endpoint.URL, payload, secrets)
+	})
+}
+
+func (s *webhookSender) post(ctx context.Context, url string, payload []byte, secrets []string) (bool, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(secrets) > 0 {
+		req.Header.Set(webhook.SignatureHeader, webhook.SignatureHeaderValue(time.Now(), payload, secrets...))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return false, resp.StatusCode, nil
+	}
+
+	return retryableStatus(resp.StatusCode), resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+// retryableStatus reports whether a response status is worth retrying.
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
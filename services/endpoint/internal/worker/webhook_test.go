@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/pkg/webhook"
+	"github.com/jsmithdenverdev/pager/services/endpoint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWebhookServer returns the URL of a server that answers each delivery with
+// the status returned by respond for the delivery's number, starting at 1.
+func newWebhookServer(t *testing.T, respond func(n int) int) string {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := webhook.VerifyRequest(r, webhook.DefaultTolerance, "secret")
+		assert.NoError(t, err)
+		w.WriteHeader(respond(int(calls.Add(1))))
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+var webhookRetryConfig = Config{
+	DeliveryMaxAttempts: 3,
+	DeliveryBaseBackoff: time.Millisecond,
+	DeliveryMaxBackoff:  time.Millisecond,
+}
+
+func TestWebhookSender(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		attempts   int
+		statusCode int
+		err        bool
+	}{
+		{
+			name:       "delivered",
+			statuses:   []int{http.StatusNoContent},
+			attempts:   1,
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "retried after server error",
+			statuses:   []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			attempts:   3,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "retried after rate limit",
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			attempts:   2,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "attempts exhausted",
+			statuses:   []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+			attempts:   3,
+			statusCode: http.StatusInternalServerError,
+			err:        true,
+		},
+		{
+			name:       "rejected",
+			statuses:   []int{http.StatusGone, http.StatusOK},
+			attempts:   1,
+			statusCode: http.StatusGone,
+			err:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := newWebhookServer(t, func(n int) int { return tt.statuses[n-1] })
+
+			result := NewWebhookSender(webhookRetryConfig, http.DefaultClient).Send(context.Background(), models.Endpoint{
+				EndpointType:  models.EndpointTypeWebhook,
+				URL:           url,
+				SigningSecret: "secret",
+			}, testNotification)
+
+			assert.Equal(t, tt.attempts, result.Attempts)
+			assert.Equal(t, tt.statusCode, result.StatusCode)
+			if tt.err {
+				require.Error(t, result.Err)
+				return
+			}
+			require.NoError(t, result.Err)
+		})
+	}
+}
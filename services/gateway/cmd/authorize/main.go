@@ -40,7 +40,7 @@ func run(ctx context.Context, stdout io.Writer, getenv func(string) string) erro
 
 	dynamodb := dynamodb.NewFromConfig(awsconf)
 
-	lambda.StartWithOptions(app.Authorize(conf, logger, dynamodb))
+	lambda.StartWithOptions(app.Authorize(conf, logger, dynamodb, stdout))
 
 	return nil
 }
@@ -0,0 +1,183 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+)
+
+// apiKeyPrefix starts every API key, tokens without it are JWTs.
+const apiKeyPrefix = "pgr_"
+
+// apiKeyLastUsedInterval is how often a key's last used time is recorded, so a
+// busy integration doesn't write on every request.
+const apiKeyLastUsedInterval = time.Minute
+
+// apiKeyRoute is a request an API key can be allowed to make, a method on one
+// of the gateway's route keys.
+type apiKeyRoute struct {
+	method   string
+	routeKey string
+}
+
+// apiKeyScopeRoutes are the requests each API key scope allows. Keys can't make
+// any other request whatever the synthesized principal's memberships allow.
+var apiKeyScopeRoutes = map[string][]apiKeyRoute{
+	"pages:create": {
+		{method: "POST", routeKey: "ANY /pages"},
+	},
+	"pages:read": {
+		{method: "GET", routeKey: "ANY /pages"},
+		{method: "GET", routeKey: "ANY /pages/{proxy+}"},
+	},
+}
+
+// apiKey is the lookup row of an agency's API key, created by the agency
+// service.
+type apiKey struct {
+	PK        string    `dynamodbav:"pk"`
+	AgencyID  string    `dynamodbav:"agencyId"`
+	Name      string    `dynamodbav:"name"`
+	Scopes    []string  `dynamodbav:"scopes"`
+	Hash      string    `dynamodbav:"hash"`
+	Created   time.Time `dynamodbav:"created"`
+	CreatedBy string    `dynamodbav:"createdBy"`
+}
+
+// ID returns the key's ID.
+func (k apiKey) ID() string {
+	return strings.TrimPrefix(k.PK, "apikey#")
+}
+
+// allows reports whether the key's scopes allow a request.
+func (k apiKey) allows(method, routeKey string) bool {
+	for _, scope := range k.Scopes {
+		if slices.Contains(apiKeyScopeRoutes[scope], apiKeyRoute{method: method, routeKey: routeKey}) {
+			return true
+		}
+	}
+	return false
+}
+
+// principal returns the user the key acts as: a member of only the key's
+// agency, a writer if the key can create pages.
+func (k apiKey) principal() identity.User {
+	role := identity.RoleReader
+	if slices.Contains(k.Scopes, "pages:create") {
+		role = identity.RoleWriter
+	}
+
+	return identity.User{
+		ID:     fmt.Sprintf("apikey|%s", k.ID()),
+		Name:   k.Name,
+		Status: identity.StatusActive,
+		Memberships: map[string]identity.Role{
+			k.AgencyID: role,
+		},
+		Created:    k.Created,
+		Modified:   k.Created,
+		CreatedBy:  k.CreatedBy,
+		ModifiedBy: k.CreatedBy,
+	}
+}
+
+// isAPIKey reports whether a bearer token is an API key rather than a JWT.
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+// getAPIKey returns the API key matching token, or nil if the key doesn't
+// exist or has been revoked. Keys are pgr_<keyId>_<secret>.
+func getAPIKey(ctx context.Context, config Config, client dynamoDBAPI, token string) (*apiKey, error) {
+	keyID, _, ok := strings.Cut(strings.TrimPrefix(token, apiKeyPrefix), "_")
+	if !ok || keyID == "" {
+		return nil, errors.New("malformed api key")
+	}
+
+	result, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(config.AgencyTableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("apikey#%s", keyID),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: "meta",
+			},
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var key apiKey
+	if err := attributevalue.UnmarshalMap(result.Item, &key); err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256([]byte(token))
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(hash[:])), []byte(key.Hash)) != 1 {
+		return nil, nil
+	}
+
+	return &key, nil
+}
+
+// recordAPIKeyUse sets the last used time on the agency's row of the key
+// unless it was recorded within apiKeyLastUsedInterval.
+func recordAPIKeyUse(ctx context.Context, config Config, client dynamoDBAPI, key apiKey, now time.Time) error {
+	lastUsed, err := attributevalue.Marshal(now.UTC())
+	if err != nil {
+		return err
+	}
+
+	threshold, err := attributevalue.Marshal(now.UTC().Add(-apiKeyLastUsedInterval))
+	if err != nil {
+		return err
+	}
+
+	_, err = client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(config.AgencyTableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("agency#%s", key.AgencyID),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("apikey#%s", key.ID()),
+			},
+		},
+		UpdateExpression:    aws.String("SET #lastUsed = :lastUsed"),
+		ConditionExpression: aws.String("attribute_exists(#pk) AND (attribute_not_exists(#lastUsed) OR #lastUsed < :threshold)"),
+		ExpressionAttributeNames: map[string]string{
+			"#pk":       "pk",
+			"#lastUsed": "lastUsed",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":lastUsed":  lastUsed,
+			":threshold": threshold,
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyAllows(t *testing.T) {
+	key := apiKey{PK: "apikey#key-1", AgencyID: "agency-1", Scopes: []string{"pages:create"}}
+
+	assert.True(t, key.allows("POST", "ANY /pages"))
+	assert.False(t, key.allows("GET", "ANY /pages"))
+	assert.False(t, key.allows("POST", "ANY /pages/{proxy+}"))
+	assert.False(t, key.allows("POST", "ANY /agencies/{proxy+}"))
+
+	key.Scopes = []string{"pages:read"}
+
+	assert.True(t, key.allows("GET", "ANY /pages"))
+	assert.True(t, key.allows("GET", "ANY /pages/{proxy+}"))
+	assert.False(t, key.allows("POST", "ANY /pages"))
+
+	key.Scopes = []string{"unknown"}
+
+	assert.False(t, key.allows("POST", "ANY /pages"))
+}
+
+func TestAPIKeyPrincipal(t *testing.T) {
+	key := apiKey{PK: "apikey#key-1", AgencyID: "agency-1", Name: "CAD", Scopes: []string{"pages:create"}}
+
+	principal := key.principal()
+	assert.Equal(t, "apikey|key-1", principal.ID)
+	assert.Equal(t, identity.StatusActive, principal.Status)
+	assert.Equal(t, map[string]identity.Role{"agency-1": identity.RoleWriter}, principal.Memberships)
+	assert.Empty(t, principal.Entitlements)
+
+	key.Scopes = []string{"pages:read"}
+	assert.Equal(t, map[string]identity.Role{"agency-1": identity.RoleReader}, key.principal().Memberships)
+}
+
+func TestIsAPIKey(t *testing.T) {
+	assert.True(t, isAPIKey("pgr_key-1_secret"))
+	assert.False(t, isAPIKey("eyJhbGciOiJSUzI1NiJ9.e30.sig"))
+}
@@ -2,11 +2,13 @@ package app
 
 import (
 	"context"
-	"crypto/rsa"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -17,11 +19,27 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jsmithdenverdev/pager/pkg/identity"
-	"github.com/lestrrat-go/jwx/jwk"
 )
 
-func Authorize(config Config, logger *slog.Logger, client *dynamodb.Client) func(context.Context, events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
-	return func(ctx context.Context, request events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+// Authorize returns the handler for the gateway's authorizer. Tokens are
+// accepted from the config's trusted issuers, as are agency API keys limited to
+// the requests their scopes allow. Each issuer's JWKS and recently
+// authorized users are cached for the lifetime of the process, see jwksCache
+// and userCache. Cache metrics are written to metricsWriter.
+func Authorize(config Config, logger *slog.Logger, client dynamoDBAPI, metricsWriter io.Writer) func(context.Context, events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayCustomAuthorizerResponse, error) {
+	var (
+		metrics = newMetrics(metricsWriter)
+		issuers = newTrustedIssuers(config, metrics, http.DefaultClient)
+		users   = newUserCache(config.UserCacheTTL, metrics)
+	)
+
+	return func(ctx context.Context, request events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayCustomAuthorizerResponse, error) {
+		defer func() {
+			if err := metrics.flush(); err != nil {
+				logger.ErrorContext(ctx, "failed to write metrics", slog.String("error", err.Error()))
+			}
+		}()
+
 		response := events.APIGatewayCustomAuthorizerResponse{
 			PrincipalID: "Anonymous",
 			PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
@@ -42,76 +60,66 @@ func Authorize(config Config, logger *slog.Logger, client *dynamodb.Client) func
 			return response, nil
 		}
 
-		jwks, err := jwk.Fetch(ctx, fmt.Sprintf("https://%s/.well-known/jwks.json", config.Auth0Domain))
-		if err != nil {
-			logger.ErrorContext(ctx, "failed to fetch jwks", slog.String("error", err.Error()))
-			return response, nil
-		}
+		var user identity.User
 
-		claims, err := verifyToken(token, config.Auth0Audience, config.Auth0Domain, jwks)
-		if err != nil {
-			logger.ErrorContext(ctx, "failed to verify token", slog.String("error", err.Error()))
-			return response, nil
-		}
+		if isAPIKey(token) {
+			key, err := getAPIKey(ctx, config, client, token)
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to get api key", slog.String("error", err.Error()))
+				return response, nil
+			}
 
-		sub, ok := claims["sub"].(string)
-		if !ok {
-			logger.ErrorContext(ctx, "failed to get sub claim", slog.String("error", "sub claim is not a string"))
-			return response, nil
-		}
+			if key == nil {
+				logger.ErrorContext(ctx, "api key not found")
+				return response, nil
+			}
 
-		userRecord, err := client.GetItem(ctx, &dynamodb.GetItemInput{
-			TableName: aws.String(config.UserTableName),
-			Key: map[string]types.AttributeValue{
-				"pk": &types.AttributeValueMemberS{
-					Value: fmt.Sprintf("user#%s", sub),
-				},
-				"sk": &types.AttributeValueMemberS{
-					Value: "meta",
-				},
-			},
-		})
+			if !key.allows(request.RequestContext.HTTP.Method, request.RouteKey) {
+				logger.ErrorContext(ctx, "api key not allowed", slog.String("keyId", key.ID()), slog.String("method", request.RequestContext.HTTP.Method), slog.String("routeKey", request.RouteKey))
+				return response, nil
+			}
 
-		if err != nil {
-			logger.ErrorContext(ctx, "failed to get user record", slog.String("error", err.Error()))
-			return response, nil
-		}
+			// Failing to record use shouldn't stop an integration paging.
+			if err := recordAPIKeyUse(ctx, config, client, *key, time.Now()); err != nil {
+				logger.ErrorContext(ctx, "failed to record api key use", slog.String("keyId", key.ID()), slog.String("error", err.Error()))
+			}
 
-		if userRecord.Item == nil {
-			logger.ErrorContext(ctx, "user not found", slog.String("sub", sub))
-			return response, nil
-		}
+			user = key.principal()
 
-		var userRow struct {
-			PK           string                   `dynamodbav:"pk"`
-			SK           string                   `dynamodbav:"sk"`
-			Email        string                   `dynamodbav:"email"`
-			Status       identity.Status          `dynamodbav:"status"`
-			Name         string                   `dynamodbav:"name"`
-			Entitlements []identity.Entitlement   `dynamodbav:"entitlements"`
-			Memberships  map[string]identity.Role `dynamodbav:"memberships"`
-			Created      time.Time                `dynamodbav:"created"`
-			Modified     time.Time                `dynamodbav:"modified"`
-			CreatedBy    string                   `dynamodbav:"createdBy"`
-			ModifiedBy   string                   `dynamodbav:"modifiedBy"`
-		}
+			// The decision only holds for this route.
+			response.PolicyDocument.Statement[0].Resource = []string{request.RouteArn}
+		} else {
+			userID, _, err := verifyToken(ctx, token, issuers)
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to verify token", slog.String("error", err.Error()))
+				return response, nil
+			}
 
-		if err := attributevalue.UnmarshalMap(userRecord.Item, &userRow); err != nil {
-			logger.ErrorContext(ctx, "failed to unmarshal user record", slog.String("error", err.Error()))
-			return response, nil
-		}
+			cached, ok := users.get(userID)
+			if !ok {
+				found, err := getUser(ctx, config, client, userID)
+				if err != nil {
+					logger.ErrorContext(ctx, "failed to get user record", slog.String("error", err.Error()))
+					return response, nil
+				}
+
+				if found == nil {
+					logger.ErrorContext(ctx, "user not found", slog.String("userId", userID))
+					return response, nil
+				}
 
-		user := identity.User{
-			ID:           strings.Split(userRow.PK, "#")[1],
-			Email:        userRow.Email,
-			Status:       userRow.Status,
-			Name:         userRow.Name,
-			Entitlements: userRow.Entitlements,
-			Memberships:  userRow.Memberships,
-			Created:      userRow.Created,
-			Modified:     userRow.Modified,
-			CreatedBy:    userRow.CreatedBy,
-			ModifiedBy:   userRow.ModifiedBy,
+				cached = *found
+				users.put(userID, cached)
+			}
+
+			// Users without a status were created before statuses were recorded
+			// and are active.
+			if cached.Status == identity.StatusInactive {
+				logger.ErrorContext(ctx, "user is inactive", slog.String("userId", userID))
+				return response, nil
+			}
+
+			user = cached
 		}
 
 		userJSON, err := json.Marshal(user)
@@ -120,19 +128,73 @@ func Authorize(config Config, logger *slog.Logger, client *dynamodb.Client) func
 			return response, nil
 		}
 
-		response.PrincipalID = sub
+		response.PrincipalID = user.ID
 		response.PolicyDocument.Statement[0].Effect = "Allow"
 		response.Context = map[string]any{
-			"userid":   sub,
+			"userid":   user.ID,
 			"userinfo": string(userJSON),
 		}
 
-		slog.DebugContext(ctx, "authorized user", slog.String("sub", sub), slog.Any("user", user))
+		slog.DebugContext(ctx, "authorized user", slog.String("userId", user.ID), slog.Any("user", user))
 
 		return response, nil
 	}
 }
 
+// getUser reads the user with the given ID, returning nil if there isn't one.
+func getUser(ctx context.Context, config Config, client dynamoDBAPI, userID string) (*identity.User, error) {
+	userRecord, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(config.UserTableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("user#%s", userID),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: "meta",
+			},
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if userRecord.Item == nil {
+		return nil, nil
+	}
+
+	var userRow struct {
+		PK           string                   `dynamodbav:"pk"`
+		SK           string                   `dynamodbav:"sk"`
+		Email        string                   `dynamodbav:"email"`
+		Status       identity.Status          `dynamodbav:"status"`
+		Name         string                   `dynamodbav:"name"`
+		Entitlements []identity.Entitlement   `dynamodbav:"entitlements"`
+		Memberships  map[string]identity.Role `dynamodbav:"memberships"`
+		Created      time.Time                `dynamodbav:"created"`
+		Modified     time.Time                `dynamodbav:"modified"`
+		CreatedBy    string                   `dynamodbav:"createdBy"`
+		ModifiedBy   string                   `dynamodbav:"modifiedBy"`
+	}
+
+	if err := attributevalue.UnmarshalMap(userRecord.Item, &userRow); err != nil {
+		return nil, err
+	}
+
+	return &identity.User{
+		ID:           strings.Split(userRow.PK, "#")[1],
+		Email:        userRow.Email,
+		Status:       userRow.Status,
+		Name:         userRow.Name,
+		Entitlements: userRow.Entitlements,
+		Memberships:  userRow.Memberships,
+		Created:      userRow.Created,
+		Modified:     userRow.Modified,
+		CreatedBy:    userRow.CreatedBy,
+		ModifiedBy:   userRow.ModifiedBy,
+	}, nil
+}
+
 func getTokenFromHeader(authHeader string) string {
 	parts := strings.Split(authHeader, " ")
 	if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
@@ -141,74 +203,108 @@ func getTokenFromHeader(authHeader string) string {
 	return ""
 }
 
-// verifyToken verifies the JWT using the JWKS and returns the claims if valid
-func verifyToken(tokenString string, audience string, auth0Domain string, jwks jwk.Set) (jwt.MapClaims, error) {
-	parsedToken, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Verify the signing method is RSA
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// trustedIssuer is an issuer tokens are accepted from along with the cache of
+// its signing keys.
+type trustedIssuer struct {
+	Issuer
+	jwks *jwksCache
+}
+
+// newTrustedIssuers returns the config's trusted issuers keyed by their iss
+// claim.
+func newTrustedIssuers(config Config, metrics *metrics, client *http.Client) map[string]*trustedIssuer {
+	issuers := make(map[string]*trustedIssuer)
+	for _, issuer := range config.TrustedIssuers() {
+		issuers[issuer.Issuer] = &trustedIssuer{
+			Issuer: issuer,
+			jwks:   newJWKSCache(issuer, config, metrics, client),
 		}
+	}
+	return issuers
+}
+
+// verifyToken verifies the JWT against the trusted issuer named by its iss
+// claim and returns the pager user ID and claims if valid.
+func verifyToken(ctx context.Context, tokenString string, issuers map[string]*trustedIssuer) (string, jwt.MapClaims, error) {
+	// The issuer is read before verifying so the token can be checked with
+	// that issuer's keys and rules.
+	unverified, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return "", jwt.MapClaims{}, fmt.Errorf("error parsing token: %w", err)
+	}
+
+	iss, err := unverified.Claims.GetIssuer()
+	if err != nil {
+		return "", jwt.MapClaims{}, fmt.Errorf("invalid issuer: %w", err)
+	}
+
+	issuer, ok := issuers[iss]
+	if !ok {
+		return "", jwt.MapClaims{}, fmt.Errorf("untrusted issuer: %s", iss)
+	}
 
+	parsedToken, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// Get the kid from the header
 		kid, ok := token.Header["kid"].(string)
 		if !ok {
 			return nil, errors.New("no kid present in the token header")
 		}
 
-		// Find the key in the JWKS that matches the kid
-		key, found := jwks.LookupKeyID(kid)
-		if !found {
-			return nil, fmt.Errorf("unable to find key with kid: %s", kid)
+		// Find the key in the issuer's JWKS that matches the kid
+		key, err := issuer.jwks.key(ctx, kid)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find key with kid: %s: %w", kid, err)
 		}
 
-		var pubkey rsa.PublicKey
+		// A key restricted to one algorithm can't verify tokens signed with
+		// another.
+		if alg := key.Algorithm(); alg != "" && alg != token.Method.Alg() {
+			return nil, fmt.Errorf("key %s is for %s not %s", kid, alg, token.Method.Alg())
+		}
+
+		// The raw key is an *rsa.PublicKey or *ecdsa.PublicKey, the signing
+		// method rejects a key of the wrong type.
+		var pubkey interface{}
 		if err := key.Raw(&pubkey); err != nil {
-			return nil, fmt.Errorf("unable to parse RSA public key: %w", err)
+			return nil, fmt.Errorf("unable to parse public key: %w", err)
 		}
 
-		return &pubkey, nil
-	})
+		return pubkey, nil
+	}, jwt.WithValidMethods(issuer.Algorithms), jwt.WithIssuer(issuer.Issuer.Issuer))
 	if err != nil {
-		return jwt.MapClaims{}, fmt.Errorf("error parsing token: %w", err)
+		return "", jwt.MapClaims{}, fmt.Errorf("error parsing token: %w", err)
 	}
 
-	// Validate token claims (audience and issuer)
-	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok && parsedToken.Valid {
-		err := validateClaims(claims, auth0Domain, audience)
-		if err != nil {
-			return jwt.MapClaims{}, err
-		}
-		return claims, nil
-	} else {
-		return jwt.MapClaims{}, errors.New("invalid token")
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok || !parsedToken.Valid {
+		return "", jwt.MapClaims{}, errors.New("invalid token")
 	}
-}
 
-func validateClaims(claims jwt.MapClaims, auth0Domain, audience string) error {
-	iss := fmt.Sprintf("https://%s/", auth0Domain)
-	if claims["iss"] != iss {
-		return fmt.Errorf("invalid issuer: %v", claims["iss"])
+	if err := validateClaims(claims, issuer.Issuer); err != nil {
+		return "", jwt.MapClaims{}, err
 	}
 
-	audClaimSlice, ok := claims["aud"].([]interface{})
-	if !ok {
-		return fmt.Errorf("invalid audience: %s", "could not convert aud to interface slice")
+	userID, ok := claims[issuer.UserIDClaim].(string)
+	if !ok || userID == "" {
+		return "", jwt.MapClaims{}, fmt.Errorf("invalid %s claim: %v", issuer.UserIDClaim, claims[issuer.UserIDClaim])
 	}
 
-	var audMatch bool
-	for _, aud := range audClaimSlice {
-		if aud, ok := aud.(string); ok {
-			if aud == audience {
-				audMatch = true
-				break
-			}
-		}
+	return userID, claims, nil
+}
 
+// validateClaims checks the token is for one of the issuer's audiences. The
+// aud claim may be a string or an array.
+func validateClaims(claims jwt.MapClaims, issuer Issuer) error {
+	audiences, err := claims.GetAudience()
+	if err != nil {
+		return fmt.Errorf("invalid audience: %w", err)
 	}
 
-	if !audMatch {
-		return fmt.Errorf("invalid audience: %v", claims["aud"])
+	for _, aud := range audiences {
+		if slices.Contains(issuer.Audiences, aud) {
+			return nil
+		}
 	}
 
-	return nil
+	return fmt.Errorf("invalid audience: %v", claims["aud"])
 }
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserTable answers every GetItem with its item.
+type fakeUserTable struct {
+	item map[string]types.AttributeValue
+}
+
+func (f *fakeUserTable) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f.item}, nil
+}
+
+func (f *fakeUserTable) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func TestAuthorizeUserStatus(t *testing.T) {
+	var (
+		server  = newJWKSServer(t)
+		private = server.addKey(t, "key-1")
+		config  = testConfig
+	)
+
+	config.Issuers = Issuers{server.issuer()}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub": "user-1",
+		"iss": server.issuer().Issuer,
+		"aud": "pager",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(private)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		status *types.AttributeValueMemberS
+		effect string
+	}{
+		{name: "active", status: &types.AttributeValueMemberS{Value: identity.StatusActive}, effect: "Allow"},
+		{name: "inactive", status: &types.AttributeValueMemberS{Value: identity.StatusInactive}, effect: "Deny"},
+		// Users created before statuses were recorded are active.
+		{name: "no status", effect: "Allow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &fakeUserTable{item: map[string]types.AttributeValue{
+				"pk":    &types.AttributeValueMemberS{Value: "user#user-1"},
+				"sk":    &types.AttributeValueMemberS{Value: "meta"},
+				"email": &types.AttributeValueMemberS{Value: "user@pager.com"},
+			}}
+			if tt.status != nil {
+				table.item["status"] = tt.status
+			}
+
+			authorize := Authorize(config, slog.New(slog.NewTextHandler(io.Discard, nil)), table, io.Discard)
+
+			response, err := authorize(context.Background(), events.APIGatewayV2CustomAuthorizerV2Request{
+				Headers: map[string]string{"authorization": "Bearer " + signed},
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.effect, response.PolicyDocument.Statement[0].Effect)
+			if tt.effect == "Deny" {
+				assert.Empty(t, response.Context)
+			}
+		})
+	}
+}
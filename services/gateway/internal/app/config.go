@@ -1,6 +1,9 @@
 package app
 
-import "log/slog"
+import (
+	"log/slog"
+	"time"
+)
 
 type Config struct {
 	Auth0Domain     string     `env:"AUTH0_DOMAIN"`
@@ -8,4 +11,15 @@ type Config struct {
 	UserTableName   string     `env:"USER_TABLE_NAME"`
 	AgencyTableName string     `env:"AGENCY_TABLE_NAME"`
 	LogLevel        slog.Level `env:"LOG_LEVEL" default:"ERROR"`
+	// Issuers are trusted in addition to Auth0, see Config.TrustedIssuers.
+	Issuers Issuers `env:"TRUSTED_ISSUERS"`
+	// JWKSRefreshInterval is how long the JWKS is used before it is fetched
+	// again, JWKSMinRefreshInterval is the least time between fetches and
+	// JWKSMaxStale is how long the JWKS keeps being used while fetches fail.
+	JWKSRefreshInterval    time.Duration `env:"JWKS_REFRESH_INTERVAL" envDefault:"1h"`
+	JWKSMinRefreshInterval time.Duration `env:"JWKS_MIN_REFRESH_INTERVAL" envDefault:"30s"`
+	JWKSMaxStale           time.Duration `env:"JWKS_MAX_STALE" envDefault:"24h"`
+	// UserCacheTTL is how long an authorized user is cached, and so how long
+	// changes to a user can take to be enforced.
+	UserCacheTTL time.Duration `env:"USER_CACHE_TTL" envDefault:"30s"`
 }
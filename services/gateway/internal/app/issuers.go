@@ -0,0 +1,113 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// supportedAlgorithms are the signing algorithms an issuer can be trusted for.
+var supportedAlgorithms = []string{"RS256", "ES256"}
+
+// Issuer is an identity provider whose tokens the gateway accepts.
+type Issuer struct {
+	// Issuer is the token's expected iss claim, it must match the issuer in
+	// the discovery document.
+	Issuer string `json:"issuer"`
+	// DiscoveryURL is the issuer's OpenID configuration document, the JWKS
+	// is found through it.
+	DiscoveryURL string `json:"discoveryUrl"`
+	// Audiences are the aud values accepted from the issuer, a token must
+	// have at least one of them.
+	Audiences []string `json:"audiences"`
+	// Algorithms are the signing algorithms accepted from the issuer.
+	// Defaults to RS256.
+	Algorithms []string `json:"algorithms"`
+	// UserIDClaim is the claim holding the pager user ID. Defaults to sub.
+	UserIDClaim string `json:"userIdClaim"`
+}
+
+// Issuers is a list of issuers read from a JSON array, such as
+//
+//	[{"issuer": "https://sso.county.gov/realms/pager",
+//	  "discoveryUrl": "https://sso.county.gov/realms/pager/.well-known/openid-configuration",
+//	  "audiences": ["pager"],
+//	  "algorithms": ["RS256", "ES256"],
+//	  "userIdClaim": "pager_user_id"}]
+type Issuers []Issuer
+
+// UnmarshalText parses and validates the issuers, filling in defaults.
+func (i *Issuers) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*i = nil
+		return nil
+	}
+
+	var issuers []Issuer
+	if err := json.Unmarshal(text, &issuers); err != nil {
+		return fmt.Errorf("failed to parse issuers: %w", err)
+	}
+
+	for n := range issuers {
+		if err := issuers[n].validate(); err != nil {
+			return fmt.Errorf("invalid issuer %d: %w", n, err)
+		}
+	}
+
+	*i = issuers
+
+	return nil
+}
+
+// validate checks the issuer can be used to verify tokens and fills in
+// defaults for the optional fields.
+func (i *Issuer) validate() error {
+	var problems []error
+
+	if i.Issuer == "" {
+		problems = append(problems, errors.New("issuer is required"))
+	}
+
+	if i.DiscoveryURL == "" {
+		problems = append(problems, errors.New("discoveryUrl is required"))
+	}
+
+	if len(i.Audiences) == 0 {
+		problems = append(problems, errors.New("at least one audience is required"))
+	}
+
+	if len(i.Algorithms) == 0 {
+		i.Algorithms = []string{"RS256"}
+	}
+
+	for _, alg := range i.Algorithms {
+		if !slices.Contains(supportedAlgorithms, alg) {
+			problems = append(problems, fmt.Errorf("unsupported algorithm: %s", alg))
+		}
+	}
+
+	if i.UserIDClaim == "" {
+		i.UserIDClaim = "sub"
+	}
+
+	return errors.Join(problems...)
+}
+
+// TrustedIssuers returns every issuer tokens are accepted from: Auth0, when a
+// domain is configured, followed by the configured Issuers.
+func (c Config) TrustedIssuers() []Issuer {
+	var issuers []Issuer
+
+	if c.Auth0Domain != "" {
+		issuers = append(issuers, Issuer{
+			Issuer:       fmt.Sprintf("https://%s/", c.Auth0Domain),
+			DiscoveryURL: fmt.Sprintf("https://%s/.well-known/openid-configuration", c.Auth0Domain),
+			Audiences:    []string{c.Auth0Audience},
+			Algorithms:   []string{"RS256"},
+			UserIDClaim:  "sub",
+		})
+	}
+
+	return append(issuers, c.Issuers...)
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuersUnmarshalText(t *testing.T) {
+	t.Run("fills in defaults", func(t *testing.T) {
+		var issuers Issuers
+		err := issuers.UnmarshalText([]byte(`[{
+			"issuer": "https://sso.county.gov/realms/pager",
+			"discoveryUrl": "https://sso.county.gov/realms/pager/.well-known/openid-configuration",
+			"audiences": ["pager"]
+		}]`))
+		require.NoError(t, err)
+
+		assert.Equal(t, Issuers{{
+			Issuer:       "https://sso.county.gov/realms/pager",
+			DiscoveryURL: "https://sso.county.gov/realms/pager/.well-known/openid-configuration",
+			Audiences:    []string{"pager"},
+			Algorithms:   []string{"RS256"},
+			UserIDClaim:  "sub",
+		}}, issuers)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var issuers Issuers
+		require.NoError(t, issuers.UnmarshalText(nil))
+		assert.Empty(t, issuers)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var issuers Issuers
+		err := issuers.UnmarshalText([]byte(`[{"algorithms": ["HS256"]}]`))
+		assert.ErrorContains(t, err, "issuer is required")
+		assert.ErrorContains(t, err, "discoveryUrl is required")
+		assert.ErrorContains(t, err, "at least one audience is required")
+		assert.ErrorContains(t, err, "unsupported algorithm: HS256")
+	})
+}
+
+func TestConfigTrustedIssuers(t *testing.T) {
+	keycloak := Issuer{
+		Issuer:       "https://sso.county.gov/realms/pager",
+		DiscoveryURL: "https://sso.county.gov/realms/pager/.well-known/openid-configuration",
+		Audiences:    []string{"pager"},
+		Algorithms:   []string{"ES256"},
+		UserIDClaim:  "pager_user_id",
+	}
+
+	config := Config{
+		Auth0Domain:   "pager.us.auth0.com",
+		Auth0Audience: "pager",
+		Issuers:       Issuers{keycloak},
+	}
+
+	assert.Equal(t, []Issuer{
+		{
+			Issuer:       "https://pager.us.auth0.com/",
+			DiscoveryURL: "https://pager.us.auth0.com/.well-known/openid-configuration",
+			Audiences:    []string{"pager"},
+			Algorithms:   []string{"RS256"},
+			UserIDClaim:  "sub",
+		},
+		keycloak,
+	}, config.TrustedIssuers())
+}
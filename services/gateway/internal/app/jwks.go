@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// errKeyNotFound is returned when no key in the JWKS matches a token's kid.
+var errKeyNotFound = errors.New("key not found")
+
+// jwksCache holds the JSON Web Key Set used to verify an issuer's tokens for
+// the lifetime of the process. The set's location is read from the issuer's
+// discovery document on the first refresh.
+//
+// The set is refreshed once it is older than refreshInterval, and early when a
+// token names a kid the set doesn't have so rotated keys are picked up
+// without waiting. Refreshes are at most once per minRefreshInterval so a
+// flood of tokens with unknown kids can't hammer the issuer. If a refresh
+// fails the previous set keeps being used until it is older than maxStale,
+// an issuer outage shouldn't lock everyone out.
+//
+// Refreshes happen while handling a request rather than in the background,
+// Lambda freezes the process between invocations.
+type jwksCache struct {
+	issuer             string
+	discoveryURL       string
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	maxStale           time.Duration
+	client             *http.Client
+	metrics            *metrics
+	now                func() time.Time
+
+	mu          sync.Mutex
+	jwksURL     string
+	set         jwk.Set
+	fetched     time.Time
+	lastAttempt time.Time
+}
+
+// newJWKSCache returns a cache for the JWKS of the given issuer. Nothing is
+// fetched until the first key is requested.
+func newJWKSCache(issuer Issuer, config Config, metrics *metrics, client *http.Client) *jwksCache {
+	return &jwksCache{
+		issuer:             issuer.Issuer,
+		discoveryURL:       issuer.DiscoveryURL,
+		refreshInterval:    config.JWKSRefreshInterval,
+		minRefreshInterval: config.JWKSMinRefreshInterval,
+		maxStale:           config.JWKSMaxStale,
+		client:             client,
+		metrics:            metrics,
+		now:                time.Now,
+	}
+}
+
+// key returns the key with the given kid, refreshing the set if it is due or
+// doesn't contain the kid.
+func (c *jwksCache) key(ctx context.Context, kid string) (jwk.Key, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	if c.set == nil || now.Sub(c.fetched) >= c.refreshInterval {
+		err := c.refresh(ctx, now)
+		if !c.usable(now) {
+			if err == nil {
+				err = errors.New("no usable jwks")
+			}
+			return nil, err
+		}
+	}
+
+	if key, ok := c.set.LookupKeyID(kid); ok {
+		return key, nil
+	}
+
+	// The issuer may have rotated its keys since the set was fetched.
+	err := c.refresh(ctx, now)
+
+	if key, ok := c.set.LookupKeyID(kid); ok {
+		return key, nil
+	}
+
+	return nil, errors.Join(fmt.Errorf("%w: %s", errKeyNotFound, kid), err)
+}
+
+// refresh fetches the set unless it was last attempted within
+// minRefreshInterval. The previous set is kept if the fetch fails.
+func (c *jwksCache) refresh(ctx context.Context, now time.Time) error {
+	if !c.lastAttempt.IsZero() && now.Sub(c.lastAttempt) < c.minRefreshInterval {
+		return nil
+	}
+	c.lastAttempt = now
+
+	if c.jwksURL == "" {
+		jwksURL, err := c.discover(ctx)
+		if err != nil {
+			c.metrics.count(metricJWKSRefreshErrors)
+			return err
+		}
+		c.jwksURL = jwksURL
+	}
+
+	set, err := jwk.Fetch(ctx, c.jwksURL, jwk.WithHTTPClient(c.client))
+	if err != nil {
+		c.metrics.count(metricJWKSRefreshErrors)
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	c.metrics.count(metricJWKSRefreshes)
+	c.set = set
+	c.fetched = now
+
+	return nil
+}
+
+// discover reads the JWKS location from the issuer's discovery document.
+func (c *jwksCache) discover(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.discoveryURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create discovery request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch discovery document: %s", res.Status)
+	}
+
+	var document struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&document); err != nil {
+		return "", fmt.Errorf("failed to decode discovery document: %w", err)
+	}
+
+	if document.Issuer != c.issuer {
+		return "", fmt.Errorf("discovery document issuer %q does not match %q", document.Issuer, c.issuer)
+	}
+
+	if document.JWKSURI == "" {
+		return "", errors.New("discovery document has no jwks_uri")
+	}
+
+	return document.JWKSURI, nil
+}
+
+// usable reports whether the cached set can still be used after a failed
+// refresh.
+func (c *jwksCache) usable(now time.Time) bool {
+	return c.set != nil && now.Sub(c.fetched) < c.maxStale
+}
@@ -0,0 +1,436 @@
+package app
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwksServer serves a discovery document and JWKS over HTTP and counts how
+// many times the JWKS is fetched.
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	keys     map[string]crypto.Signer
+	failing  bool
+	requests int
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{keys: map[string]crypto.Signer{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"issuer":   s.issuer().Issuer,
+			"jwks_uri": s.URL + "/jwks",
+		}))
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests++
+
+		if s.failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var keys []jwk.Key
+		for kid, private := range s.keys {
+			key, err := jwk.New(private.Public())
+			require.NoError(t, err)
+			require.NoError(t, key.Set(jwk.KeyIDKey, kid))
+			keys = append(keys, key)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{"keys": keys}))
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// issuer returns an issuer trusting the server's keys.
+func (s *jwksServer) issuer() Issuer {
+	return Issuer{
+		Issuer:       s.URL + "/",
+		DiscoveryURL: s.URL + "/.well-known/openid-configuration",
+		Audiences:    []string{"pager"},
+		Algorithms:   []string{"RS256"},
+		UserIDClaim:  "sub",
+	}
+}
+
+// addKey generates an RSA key with the given kid and adds it to the served
+// set.
+func (s *jwksServer) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = private
+
+	return private
+}
+
+// addECKey generates a P-256 key with the given kid and adds it to the served
+// set.
+func (s *jwksServer) addECKey(t *testing.T, kid string) *ecdsa.PrivateKey {
+	t.Helper()
+
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = private
+
+	return private
+}
+
+func (s *jwksServer) setFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+func (s *jwksServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// testClock is a clock the test moves forward by hand.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+var testConfig = Config{
+	JWKSRefreshInterval:    time.Hour,
+	JWKSMinRefreshInterval: 30 * time.Second,
+	JWKSMaxStale:           24 * time.Hour,
+	UserCacheTTL:           30 * time.Second,
+}
+
+func newTestJWKSCache(server *jwksServer, clock *testClock) *jwksCache {
+	cache := newJWKSCache(server.issuer(), testConfig, newMetrics(&discard{}), server.Client())
+	cache.now = clock.Now
+	return cache
+}
+
+// discard is an io.Writer that drops what is written to it.
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestJWKSCacheReusesSet(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		server = newJWKSServer(t)
+		clock  = &testClock{now: time.Now()}
+		cache  = newTestJWKSCache(server, clock)
+	)
+
+	server.addKey(t, "key-1")
+
+	for range 3 {
+		key, err := cache.key(ctx, "key-1")
+		require.NoError(t, err)
+		assert.Equal(t, "key-1", key.KeyID())
+	}
+
+	assert.Equal(t, 1, server.requestCount())
+}
+
+func TestJWKSCacheRefreshesAfterInterval(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		server = newJWKSServer(t)
+		clock  = &testClock{now: time.Now()}
+		cache  = newTestJWKSCache(server, clock)
+	)
+
+	server.addKey(t, "key-1")
+
+	_, err := cache.key(ctx, "key-1")
+	require.NoError(t, err)
+
+	clock.advance(testConfig.JWKSRefreshInterval)
+
+	_, err = cache.key(ctx, "key-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, server.requestCount())
+}
+
+func TestJWKSCacheRefreshesOnUnknownKid(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		server = newJWKSServer(t)
+		clock  = &testClock{now: time.Now()}
+		cache  = newTestJWKSCache(server, clock)
+	)
+
+	server.addKey(t, "key-1")
+
+	_, err := cache.key(ctx, "key-1")
+	require.NoError(t, err)
+
+	// The issuer rotates in a new key.
+	server.addKey(t, "key-2")
+	clock.advance(testConfig.JWKSMinRefreshInterval)
+
+	key, err := cache.key(ctx, "key-2")
+	require.NoError(t, err)
+	assert.Equal(t, "key-2", key.KeyID())
+	assert.Equal(t, 2, server.requestCount())
+}
+
+func TestJWKSCacheLimitsUnknownKidRefreshes(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		server = newJWKSServer(t)
+		clock  = &testClock{now: time.Now()}
+		cache  = newTestJWKSCache(server, clock)
+	)
+
+	server.addKey(t, "key-1")
+
+	_, err := cache.key(ctx, "key-1")
+	require.NoError(t, err)
+
+	for range 5 {
+		_, err := cache.key(ctx, "unknown")
+		assert.ErrorIs(t, err, errKeyNotFound)
+	}
+
+	// The first fetch was just made, so none of the misses could refresh.
+	assert.Equal(t, 1, server.requestCount())
+
+	clock.advance(testConfig.JWKSMinRefreshInterval)
+
+	_, err = cache.key(ctx, "unknown")
+	assert.ErrorIs(t, err, errKeyNotFound)
+	assert.Equal(t, 2, server.requestCount())
+}
+
+func TestJWKSCacheServesStaleSetOnError(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		server = newJWKSServer(t)
+		clock  = &testClock{now: time.Now()}
+		cache  = newTestJWKSCache(server, clock)
+	)
+
+	server.addKey(t, "key-1")
+
+	_, err := cache.key(ctx, "key-1")
+	require.NoError(t, err)
+
+	server.setFailing(true)
+	clock.advance(testConfig.JWKSRefreshInterval)
+
+	key, err := cache.key(ctx, "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", key.KeyID())
+	assert.Equal(t, 2, server.requestCount())
+
+	// Failed refreshes aren't retried on every request.
+	_, err = cache.key(ctx, "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.requestCount())
+
+	clock.advance(testConfig.JWKSMaxStale)
+
+	_, err = cache.key(ctx, "key-1")
+	assert.Error(t, err)
+}
+
+func TestJWKSCacheFailsWithoutSet(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		server = newJWKSServer(t)
+		clock  = &testClock{now: time.Now()}
+		cache  = newTestJWKSCache(server, clock)
+	)
+
+	server.setFailing(true)
+
+	_, err := cache.key(ctx, "key-1")
+	assert.Error(t, err)
+
+	// A failed first fetch is retried once the minimum interval has passed.
+	server.setFailing(false)
+	server.addKey(t, "key-1")
+	clock.advance(testConfig.JWKSMinRefreshInterval)
+
+	_, err = cache.key(ctx, "key-1")
+	assert.NoError(t, err)
+}
+
+func TestJWKSCacheRejectsMismatchedDiscoveryIssuer(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		server = newJWKSServer(t)
+		issuer = server.issuer()
+	)
+
+	server.addKey(t, "key-1")
+	issuer.Issuer = "https://other.example.com/"
+
+	cache := newJWKSCache(issuer, testConfig, newMetrics(&discard{}), server.Client())
+
+	_, err := cache.key(ctx, "key-1")
+	assert.ErrorContains(t, err, "does not match")
+	assert.Zero(t, server.requestCount())
+}
+
+func TestVerifyToken(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		server  = newJWKSServer(t)
+		issuer  = server.issuer()
+		private = server.addKey(t, "rsa-1")
+		ecKey   = server.addECKey(t, "ec-1")
+	)
+
+	issuer.Algorithms = []string{"RS256", "ES256"}
+	issuer.UserIDClaim = "pager_user_id"
+
+	// A second issuer sharing the server's keys but only trusted for RS256.
+	rsaOnly := issuer
+	rsaOnly.Issuer = "https://rsa-only.example.com/"
+	rsaOnly.Algorithms = []string{"RS256"}
+
+	newTrusted := func(issuer Issuer) *trustedIssuer {
+		jwks := newJWKSCache(server.issuer(), testConfig, newMetrics(&discard{}), server.Client())
+		return &trustedIssuer{Issuer: issuer, jwks: jwks}
+	}
+
+	issuers := map[string]*trustedIssuer{
+		issuer.Issuer:  newTrusted(issuer),
+		rsaOnly.Issuer: newTrusted(rsaOnly),
+	}
+
+	sign := func(t *testing.T, method jwt.SigningMethod, kid string, key crypto.Signer, claims jwt.MapClaims) string {
+		t.Helper()
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"sub":           "keycloak|1",
+			"pager_user_id": "user-1",
+			"iss":           issuer.Issuer,
+			"aud":           []string{"account", "pager"},
+			"exp":           time.Now().Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			claims[k] = v
+		}
+		return claims
+	}
+
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		token  string
+		userID string
+		err    string
+	}{
+		{
+			name:   "rs256 with aud array",
+			token:  sign(t, jwt.SigningMethodRS256, "rsa-1", private, claims(nil)),
+			userID: "user-1",
+		},
+		{
+			name:   "es256 with aud string",
+			token:  sign(t, jwt.SigningMethodES256, "ec-1", ecKey, claims(jwt.MapClaims{"aud": "pager"})),
+			userID: "user-1",
+		},
+		{
+			name:  "algorithm not allowed for issuer",
+			token: sign(t, jwt.SigningMethodES256, "ec-1", ecKey, claims(jwt.MapClaims{"iss": rsaOnly.Issuer})),
+			err:   "signing method ES256 is invalid",
+		},
+		{
+			name:  "untrusted issuer",
+			token: sign(t, jwt.SigningMethodRS256, "rsa-1", private, claims(jwt.MapClaims{"iss": "https://evil.example.com/"})),
+			err:   "untrusted issuer",
+		},
+		{
+			name:  "wrong audience",
+			token: sign(t, jwt.SigningMethodRS256, "rsa-1", private, claims(jwt.MapClaims{"aud": "other"})),
+			err:   "invalid audience",
+		},
+		{
+			name:  "missing user id claim",
+			token: sign(t, jwt.SigningMethodRS256, "rsa-1", private, claims(jwt.MapClaims{"pager_user_id": nil})),
+			err:   "invalid pager_user_id claim",
+		},
+		{
+			name:  "unknown key",
+			token: sign(t, jwt.SigningMethodRS256, "rsa-2", otherRSA, claims(nil)),
+			err:   errKeyNotFound.Error(),
+		},
+		{
+			name:  "wrong signature",
+			token: sign(t, jwt.SigningMethodRS256, "rsa-1", otherRSA, claims(nil)),
+			err:   "verification error",
+		},
+		{
+			name:  "wrong key type for kid",
+			token: sign(t, jwt.SigningMethodRS256, "ec-1", private, claims(nil)),
+			err:   "error parsing token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, _, err := verifyToken(ctx, tt.token, issuers)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.userID, userID)
+		})
+	}
+}
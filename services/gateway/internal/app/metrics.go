@@ -0,0 +1,91 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// metricsNamespace is the CloudWatch namespace the authorizer's metrics are
+// published under.
+const metricsNamespace = "Pager/Gateway"
+
+const (
+	metricUserCacheHits     = "UserCacheHits"
+	metricUserCacheMisses   = "UserCacheMisses"
+	metricJWKSRefreshes     = "JWKSRefreshes"
+	metricJWKSRefreshErrors = "JWKSRefreshErrors"
+)
+
+// metrics counts events while handling a request and writes them out in
+// CloudWatch embedded metric format, which CloudWatch turns into metrics when
+// the line reaches the function's logs. Metrics are written separately from
+// the logger so they don't depend on the log level.
+type metrics struct {
+	w   io.Writer
+	now func() time.Time
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// newMetrics returns metrics that are flushed to w.
+func newMetrics(w io.Writer) *metrics {
+	return &metrics{
+		w:      w,
+		now:    time.Now,
+		counts: make(map[string]int),
+	}
+}
+
+// count adds one to the named metric.
+func (m *metrics) count(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.counts[name]++
+}
+
+// flush writes the counts recorded since the last flush and resets them.
+// Nothing is written if nothing was counted.
+func (m *metrics) flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.counts) == 0 {
+		return nil
+	}
+
+	type metricDefinition struct {
+		Name string `json:"Name"`
+		Unit string `json:"Unit"`
+	}
+
+	var (
+		definitions []metricDefinition
+		line        = map[string]any{}
+	)
+
+	for _, name := range slices.Sorted(maps.Keys(m.counts)) {
+		definitions = append(definitions, metricDefinition{Name: name, Unit: "Count"})
+		line[name] = m.counts[name]
+	}
+
+	line["_aws"] = map[string]any{
+		"Timestamp": m.now().UnixMilli(),
+		"CloudWatchMetrics": []map[string]any{
+			{
+				"Namespace":  metricsNamespace,
+				"Dimensions": [][]string{{}},
+				"Metrics":    definitions,
+			},
+		},
+	}
+
+	m.counts = make(map[string]int)
+
+	return json.NewEncoder(m.w).Encode(line)
+}
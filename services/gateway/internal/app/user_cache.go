@@ -0,0 +1,88 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+)
+
+// maxCachedUsers bounds the memory used by userCache. When it is full expired
+// entries are dropped, and if none have expired the cache starts over.
+const maxCachedUsers = 1000
+
+// userCache holds recently authorized users by their pager user ID, read from
+// the claim their token's issuer maps to it, so a burst of requests from the
+// same user, such as during a callout, reads their record once. Entries live for ttl, which is also how long a change to a user such
+// as deactivation can take to be enforced.
+type userCache struct {
+	ttl     time.Duration
+	metrics *metrics
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]userCacheEntry
+}
+
+type userCacheEntry struct {
+	user    identity.User
+	expires time.Time
+}
+
+// newUserCache returns an empty cache whose entries live for ttl. A ttl of
+// zero disables caching.
+func newUserCache(ttl time.Duration, metrics *metrics) *userCache {
+	return &userCache{
+		ttl:     ttl,
+		metrics: metrics,
+		now:     time.Now,
+		entries: make(map[string]userCacheEntry),
+	}
+}
+
+// get returns the cached user for userID, if there is one that hasn't expired.
+func (c *userCache) get(userID string) (identity.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if ok && c.now().Before(entry.expires) {
+		c.metrics.count(metricUserCacheHits)
+		return entry.user, true
+	}
+
+	if ok {
+		delete(c.entries, userID)
+	}
+
+	c.metrics.count(metricUserCacheMisses)
+	return identity.User{}, false
+}
+
+// put caches user under userID.
+func (c *userCache) put(userID string, user identity.User) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	if len(c.entries) >= maxCachedUsers {
+		for key, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= maxCachedUsers {
+			c.entries = make(map[string]userCacheEntry)
+		}
+	}
+
+	c.entries[userID] = userCacheEntry{
+		user:    user,
+		expires: now.Add(c.ttl),
+	}
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserCache(t *testing.T) {
+	var (
+		clock   = &testClock{now: time.Now()}
+		metrics = newMetrics(&discard{})
+		cache   = newUserCache(30*time.Second, metrics)
+		user    = identity.User{ID: "user-1", Email: "user@pager.com"}
+	)
+
+	cache.now = clock.Now
+
+	_, ok := cache.get("user-1")
+	assert.False(t, ok)
+
+	cache.put("user-1", user)
+
+	cached, ok := cache.get("user-1")
+	assert.True(t, ok)
+	assert.Equal(t, user, cached)
+
+	clock.advance(30 * time.Second)
+
+	_, ok = cache.get("user-1")
+	assert.False(t, ok)
+
+	assert.Equal(t, map[string]int{
+		metricUserCacheHits:   1,
+		metricUserCacheMisses: 2,
+	}, metrics.counts)
+}
+
+func TestUserCacheDisabled(t *testing.T) {
+	cache := newUserCache(0, newMetrics(&discard{}))
+
+	cache.put("user-1", identity.User{ID: "user-1"})
+
+	_, ok := cache.get("user-1")
+	assert.False(t, ok)
+}
+
+func TestUserCacheBounded(t *testing.T) {
+	var (
+		clock = &testClock{now: time.Now()}
+		cache = newUserCache(30*time.Second, newMetrics(&discard{}))
+	)
+
+	cache.now = clock.Now
+
+	for i := range maxCachedUsers {
+		cache.put(string(rune(i)), identity.User{})
+	}
+	require.Len(t, cache.entries, maxCachedUsers)
+
+	// Expired entries make room for new ones.
+	clock.advance(30 * time.Second)
+	cache.put("user-1", identity.User{ID: "user-1"})
+	assert.Len(t, cache.entries, 1)
+}
+
+func TestMetricsFlush(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		metrics = newMetrics(&buf)
+	)
+
+	metrics.now = func() time.Time { return time.UnixMilli(1700000000000) }
+
+	// Nothing is written when nothing was counted.
+	require.NoError(t, metrics.flush())
+	assert.Zero(t, buf.Len())
+
+	metrics.count(metricUserCacheMisses)
+	metrics.count(metricUserCacheHits)
+	metrics.count(metricUserCacheHits)
+	require.NoError(t, metrics.flush())
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+
+	assert.JSONEq(t, `{
+		"UserCacheHits": 2,
+		"UserCacheMisses": 1,
+		"_aws": {
+			"Timestamp": 1700000000000,
+			"CloudWatchMetrics": [{
+				"Namespace": "Pager/Gateway",
+				"Dimensions": [[]],
+				"Metrics": [
+					{"Name": "UserCacheHits", "Unit": "Count"},
+					{"Name": "UserCacheMisses", "Unit": "Count"}
+				]
+			}]
+		}
+	}`, buf.String())
+
+	// Counts are reset after a flush.
+	buf.Reset()
+	require.NoError(t, metrics.flush())
+	assert.Zero(t, buf.Len())
+}
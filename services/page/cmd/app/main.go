@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/caarlos0/env/v11"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/services/page/internal/app"
 )
 
@@ -37,8 +38,9 @@ func run(ctx context.Context) error {
 
 	dynamoClient := dynamodb.NewFromConfig(awsconf)
 	snsClient := sns.NewFromConfig(awsconf)
+	publisher := events.NewPublisher(snsClient, conf.EventsTopicARN, "page")
 
-	handler := app.NewServer(conf, logger, dynamoClient, snsClient)
+	handler := app.NewServer(conf, logger, dynamoClient, publisher)
 
 	lambda.Start(awsapigatewayv2handler.NewLambdaHandler(handler))
 
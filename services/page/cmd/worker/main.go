@@ -9,7 +9,6 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/caarlos0/env/v11"
 	"github.com/jsmithdenverdev/pager/services/page/internal/worker"
 )
@@ -37,9 +36,8 @@ func run(ctx context.Context) error {
 	}
 
 	dynamoClient := dynamodb.NewFromConfig(awsconf)
-	snsClient := sns.NewFromConfig(awsconf)
 
-	lambda.Start(worker.ProcessEvents(conf, logger, dynamoClient, snsClient))
+	lambda.Start(worker.ProcessEvents(conf, logger, dynamoClient))
 
 	return nil
 }
@@ -13,7 +13,11 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/sns v1.34.4
 	github.com/caarlos0/env/v11 v11.3.1
 	github.com/google/uuid v1.6.0
+	github.com/jsmithdenverdev/pager/pkg/consumer v1.0.0
+	github.com/jsmithdenverdev/pager/pkg/events v1.0.0
 	github.com/jsmithdenverdev/pager/pkg/identity v1.6.0
+	github.com/jsmithdenverdev/pager/pkg/outbox v1.0.0
+	github.com/stretchr/testify v1.10.0
 )
 
 require (
@@ -30,4 +34,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.30.1 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sts v1.33.19 // indirect
 	github.com/aws/smithy-go v1.22.2 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -1,23 +1,26 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/google/uuid"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/pkg/outbox"
 	"github.com/jsmithdenverdev/pager/services/page/internal/models"
 )
 
-func createPage(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) http.Handler {
+func createPage(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var (
 			user        identity.User
@@ -45,6 +48,8 @@ func createPage(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client,
 			return
 		}
 
+		req.Agencies = uniqueAgencies(req.Agencies)
+
 		// A user must be a writer in all agencies they attempt to send a page to
 		// otherwise they'll get a 403.
 		for _, agency := range req.Agencies {
@@ -54,9 +59,35 @@ func createPage(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client,
 			}
 		}
 
-		id := uuid.New().String()
+		// Inactive agencies can't be paged.
+		inactive, err := inactiveAgencies(r.Context(), conf, dynamoClient, req.Agencies)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get agency status", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		problems = make(map[string]string)
+		if len(inactive) > 0 {
+			problems["agencies"] = fmt.Sprintf("inactive agencies: %s", strings.Join(inactive, ", "))
+		}
+
+		if len(problems) > 0 {
+			w.WriteHeader(http.StatusConflict)
+			if err := json.NewEncoder(w).Encode(problems); err != nil {
+				logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+
+		var (
+			now = time.Now()
+			id  = uuid.New().String()
+		)
 
-		dynamoInput, err := attributevalue.MarshalMap(models.Page{
+		pageAV, err := attributevalue.MarshalMap(models.Page{
 			PK:     fmt.Sprintf("page#%s", id),
 			SK:     "meta",
 			Type:   models.EntityTypePage,
@@ -69,8 +100,9 @@ func createPage(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client,
 				Longitude:   req.Location.Longitude,
 				Type:        req.Location.Type,
 			},
-			Created:    time.Now(),
-			Modified:   time.Now(),
+			Agencies:   req.Agencies,
+			Created:    now,
+			Modified:   now,
 			CreatedBy:  user.ID,
 			ModifiedBy: user.ID,
 		})
@@ -80,52 +112,85 @@ func createPage(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client,
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		_, err = dynamoClient.PutItem(r.Context(), &dynamodb.PutItemInput{
-			TableName: aws.String(conf.PageTableName),
-			Item:      dynamoInput,
-		})
 
-		if err != nil {
-			logger.ErrorContext(r.Context(), "failed to put page", slog.Any("error", err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+		transactItems := []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName: aws.String(conf.PageTableName),
+					Item:      pageAV,
+				},
+			},
+		}
+
+		// Index the page under each agency it was sent to so pages can be listed
+		// by agency.
+		for _, agency := range req.Agencies {
+			agencyPageAV, err := attributevalue.MarshalMap(models.AgencyPage{
+				PK:        fmt.Sprintf("agency#%s", agency),
+				SK:        models.AgencyPageSK(now, id),
+				Type:      models.EntityTypeAgencyPage,
+				PageID:    id,
+				Created:   now,
+				CreatedBy: user.ID,
+			})
+
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to marshal agency page", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			transactItems = append(transactItems, types.TransactWriteItem{
+				Put: &types.Put{
+					TableName: aws.String(conf.PageTableName),
+					Item:      agencyPageAV,
+				},
+			})
 		}
 
+		// Delivery events are staged in the outbox with the page so a page is
+		// never stored without being delivered.
 		if req.Notify {
 			for _, agency := range req.Agencies {
-				messageBody, err := json.Marshal(struct {
-					Title    string `json:"title"`
-					PageID   string `json:"pageId"`
-					AgencyID string `json:"agencyId"`
-				}{
-					Title:    req.Title,
+				envelope, err := publisher.Envelope(r.Context(), events.TypeEndpointDeliver, events.EndpointDeliver{
+					Title: req.Title,
+					Notes: req.Notes,
+					Location: &events.Location{
+						Description: req.Location.Description,
+						Latitude:    req.Location.Latitude,
+						Longitude:   req.Location.Longitude,
+						Type:        req.Location.Type,
+					},
 					PageID:   id,
 					AgencyID: agency,
 				})
-
 				if err != nil {
-					logger.ErrorContext(r.Context(), "failed to marshal SNS message", slog.Any("error", err))
+					logger.ErrorContext(r.Context(), "failed to create event", slog.Any("error", err))
 					w.WriteHeader(http.StatusInternalServerError)
 					return
 				}
 
-				if _, err = snsClient.Publish(r.Context(), &sns.PublishInput{
-					TopicArn: aws.String(conf.EventsTopicARN),
-					Message:  aws.String(string(messageBody)),
-					MessageAttributes: map[string]snstypes.MessageAttributeValue{
-						"type": {
-							DataType:    aws.String("String"),
-							StringValue: aws.String("endpoint.deliver"),
-						},
-					},
-				}); err != nil {
-					logger.ErrorContext(r.Context(), "failed publish to SNS", slog.Any("error", err))
+				outboxItem, err := outbox.Put(conf.PageTableName, envelope, now)
+				if err != nil {
+					logger.ErrorContext(r.Context(), "failed to create event", slog.Any("error", err))
 					w.WriteHeader(http.StatusInternalServerError)
 					return
 				}
+
+				transactItems = append(transactItems, outboxItem)
 			}
 		}
 
+		_, err = dynamoClient.TransactWriteItems(r.Context(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: transactItems,
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to transact write page entities", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		w.WriteHeader(http.StatusCreated)
 		if err = encode(w, r, int(http.StatusCreated), createPageResponse{ID: id}); err != nil {
 			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
@@ -134,3 +199,55 @@ func createPage(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client,
 		}
 	})
 }
+
+// getItemAPI is the part of the DynamoDB client used to read agency statuses.
+type getItemAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+}
+
+// inactiveAgencies returns the IDs of the agencies that have been deactivated,
+// in the order given.
+func inactiveAgencies(ctx context.Context, conf Config, dynamoClient getItemAPI, agencyIDs []string) ([]string, error) {
+	var inactive []string
+	for _, agencyID := range agencyIDs {
+		ok, err := agencyInactive(ctx, conf, dynamoClient, agencyID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			inactive = append(inactive, agencyID)
+		}
+	}
+	return inactive, nil
+}
+
+// agencyInactive reports whether an agency has been deactivated. Agencies this
+// service hasn't seen a status change for are active.
+func agencyInactive(ctx context.Context, conf Config, dynamoClient getItemAPI, agencyID string) (bool, error) {
+	result, err := dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(conf.PageTableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{
+				Value: fmt.Sprintf("agency#%s", agencyID),
+			},
+			"sk": &types.AttributeValueMemberS{
+				Value: "status",
+			},
+		},
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	if result.Item == nil {
+		return false, nil
+	}
+
+	var state models.AgencyState
+	if err := attributevalue.UnmarshalMap(result.Item, &state); err != nil {
+		return false, err
+	}
+
+	return state.Status == models.AgencyStatusInactive, nil
+}
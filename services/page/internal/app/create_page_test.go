@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgencyStates answers GetItem with the agency status held for the pk.
+type fakeAgencyStates map[string]map[string]types.AttributeValue
+
+func (f fakeAgencyStates) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f[params.Key["pk"].(*types.AttributeValueMemberS).Value]}, nil
+}
+
+func TestInactiveAgencies(t *testing.T) {
+	states := make(fakeAgencyStates)
+	for agencyID, status := range map[string]models.AgencyStatus{
+		"agency-1": models.AgencyStatusInactive,
+		"agency-2": models.AgencyStatusActive,
+		"agency-3": models.AgencyStatusInactive,
+	} {
+		item, err := attributevalue.MarshalMap(models.AgencyState{
+			PK:     "agency#" + agencyID,
+			SK:     "status",
+			Type:   models.EntityTypeAgencyState,
+			Status: status,
+		})
+		require.NoError(t, err)
+		states["agency#"+agencyID] = item
+	}
+
+	// agency-4 has never changed status.
+	inactive, err := inactiveAgencies(context.Background(), Config{}, states, []string{"agency-1", "agency-2", "agency-3", "agency-4"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"agency-1", "agency-3"}, inactive)
+
+	inactive, err = inactiveAgencies(context.Background(), Config{}, states, []string{"agency-2", "agency-4"})
+	require.NoError(t, err)
+	assert.Empty(t, inactive)
+}
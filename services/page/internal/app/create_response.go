@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+)
+
+// createResponse records the calling users response to a page, replacing any
+// earlier response they made but keeping when they first responded. The
+// calling user must have a membership in at least one of the agencies the page
+// was sent to.
+func createResponse(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			pageid      = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		req, problems, err := decodeValid[createResponseRequest](r)
+		if err != nil {
+			if len(problems) > 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				if err := json.NewEncoder(w).Encode(problems); err != nil {
+					logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		result, err := dynamoClient.GetItem(r.Context(), &dynamodb.GetItemInput{
+			TableName: aws.String(conf.PageTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("page#%s", pageid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get page", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var page models.Page
+		if err := attributevalue.UnmarshalMap(result.Item, &page); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal page record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Only members of an agency that was paged can respond. Platform admins
+		// are not responders so the entitlement is not considered here.
+		var member bool
+		for _, agency := range page.Agencies {
+			if _, ok := user.Memberships[agency]; ok {
+				member = true
+				break
+			}
+		}
+
+		if !member {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		now := time.Now()
+
+		response, err := putResponse(r.Context(), conf, dynamoClient, models.Response{
+			PK:         fmt.Sprintf("page#%s", pageid),
+			SK:         fmt.Sprintf("response#%s", user.ID),
+			Type:       models.EntityTypeResponse,
+			Status:     req.Status,
+			ETA:        req.ETA,
+			UserName:   user.Name,
+			Modified:   now,
+			ModifiedBy: user.ID,
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to put response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := publisher.Publish(r.Context(), events.TypePageResponseRecorded, events.PageResponseRecorded{
+			PageID:   pageid,
+			UserID:   user.ID,
+			Agencies: page.Agencies,
+			Status:   req.Status,
+			ETA:      req.ETA,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err = encode(w, r, http.StatusCreated, toResponseResponse(response)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// updateItemAPI is the part of the DynamoDB client used by putResponse.
+type updateItemAPI interface {
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// putResponse writes a response, replacing the status, ETA and modification of
+// any earlier response by the same user while keeping when they first
+// responded. response.Created and response.CreatedBy are ignored, the first
+// response takes them from Modified and ModifiedBy. The stored response is
+// returned.
+func putResponse(ctx context.Context, conf Config, client updateItemAPI, response models.Response) (models.Response, error) {
+	modifiedAV, err := attributevalue.Marshal(response.Modified)
+	if err != nil {
+		return models.Response{}, err
+	}
+
+	var (
+		updateExpression = "SET #type = :type, #status = :status, #userName = :userName, " +
+			"#modified = :modified, #modifiedBy = :modifiedBy, " +
+			"#created = if_not_exists(#created, :modified), #createdBy = if_not_exists(#createdBy, :modifiedBy)"
+		names = map[string]string{
+			"#type":       "type",
+			"#status":     "status",
+			"#userName":   "userName",
+			"#modified":   "modified",
+			"#modifiedBy": "modifiedBy",
+			"#created":    "created",
+			"#createdBy":  "createdBy",
+			"#eta":        "eta",
+		}
+		values = map[string]types.AttributeValue{
+			":type":       &types.AttributeValueMemberS{Value: string(response.Type)},
+			":status":     &types.AttributeValueMemberS{Value: response.Status},
+			":userName":   &types.AttributeValueMemberS{Value: response.UserName},
+			":modified":   modifiedAV,
+			":modifiedBy": &types.AttributeValueMemberS{Value: response.ModifiedBy},
+		}
+	)
+
+	// A response without an ETA clears the ETA of the earlier response.
+	if response.ETA != nil {
+		etaAV, err := attributevalue.Marshal(*response.ETA)
+		if err != nil {
+			return models.Response{}, err
+		}
+		updateExpression += ", #eta = :eta"
+		values[":eta"] = etaAV
+	} else {
+		updateExpression += " REMOVE #eta"
+	}
+
+	result, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(conf.PageTableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: response.PK},
+			"sk": &types.AttributeValueMemberS{Value: response.SK},
+		},
+		UpdateExpression:          aws.String(updateExpression),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ReturnValues:              types.ReturnValueAllNew,
+	})
+	if err != nil {
+		return models.Response{}, err
+	}
+
+	var stored models.Response
+	if err := attributevalue.UnmarshalMap(result.Attributes, &stored); err != nil {
+		return models.Response{}, err
+	}
+
+	return stored, nil
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUpdateItem records the updates made to it and answers each with the
+// attributes it holds.
+type fakeUpdateItem struct {
+	attributes map[string]types.AttributeValue
+	updates    []*dynamodb.UpdateItemInput
+}
+
+func (f *fakeUpdateItem) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.updates = append(f.updates, params)
+	return &dynamodb.UpdateItemOutput{Attributes: f.attributes}, nil
+}
+
+func TestPutResponse(t *testing.T) {
+	var (
+		first  = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		second = first.Add(5 * time.Minute)
+		eta    = first.Add(10 * time.Minute)
+	)
+
+	tests := []struct {
+		name       string
+		eta        *time.Time
+		expression string
+	}{
+		{
+			name: "eta",
+			eta:  &eta,
+			expression: "SET #type = :type, #status = :status, #userName = :userName, " +
+				"#modified = :modified, #modifiedBy = :modifiedBy, " +
+				"#created = if_not_exists(#created, :modified), #createdBy = if_not_exists(#createdBy, :modifiedBy), " +
+				"#eta = :eta",
+		},
+		{
+			// A response without an ETA clears the ETA of the earlier
+			// response.
+			name: "no eta",
+			expression: "SET #type = :type, #status = :status, #userName = :userName, " +
+				"#modified = :modified, #modifiedBy = :modifiedBy, " +
+				"#created = if_not_exists(#created, :modified), #createdBy = if_not_exists(#createdBy, :modifiedBy) " +
+				"REMOVE #eta",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The earlier response is returned with the update applied, keeping
+			// when the user first responded.
+			want := models.Response{
+				PK:         "page#page-1",
+				SK:         "response#user-1",
+				Type:       models.EntityTypeResponse,
+				Status:     models.ResponseStatusOnScene,
+				ETA:        tt.eta,
+				UserName:   "User",
+				Created:    first,
+				Modified:   second,
+				CreatedBy:  "user-1",
+				ModifiedBy: "user-1",
+			}
+			attributes, err := attributevalue.MarshalMap(want)
+			require.NoError(t, err)
+			client := &fakeUpdateItem{attributes: attributes}
+
+			stored, err := putResponse(context.Background(), Config{PageTableName: "table"}, client, models.Response{
+				PK:         "page#page-1",
+				SK:         "response#user-1",
+				Type:       models.EntityTypeResponse,
+				Status:     models.ResponseStatusOnScene,
+				ETA:        tt.eta,
+				UserName:   "User",
+				Modified:   second,
+				ModifiedBy: "user-1",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, want, stored)
+
+			require.Len(t, client.updates, 1)
+			update := client.updates[0]
+			assert.Equal(t, "table", aws.ToString(update.TableName))
+			assert.Equal(t, tt.expression, aws.ToString(update.UpdateExpression))
+			assert.Equal(t, types.ReturnValueAllNew, update.ReturnValues)
+
+			modified, err := attributevalue.Marshal(second)
+			require.NoError(t, err)
+			assert.Equal(t, modified, update.ExpressionAttributeValues[":modified"])
+			assert.Equal(t, &types.AttributeValueMemberS{Value: "user-1"}, update.ExpressionAttributeValues[":modifiedBy"])
+
+			if tt.eta == nil {
+				assert.NotContains(t, update.ExpressionAttributeValues, ":eta")
+				return
+			}
+			etaAV, err := attributevalue.Marshal(eta)
+			require.NoError(t, err)
+			assert.Equal(t, etaAV, update.ExpressionAttributeValues[":eta"])
+		})
+	}
+}
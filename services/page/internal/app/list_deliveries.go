@@ -0,0 +1,156 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+)
+
+// listDeliveries returns the endpoints a page was delivered to along with the
+// outcome of each delivery. An endpointType query parameter narrows the results
+// to a single type of endpoint, e.g. EMAIL.
+// The calling user must be able to read the page.
+func listDeliveries(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			err          error
+			user         identity.User
+			first        = 10
+			firstStr     = r.URL.Query().Get("first")
+			cursor       = r.URL.Query().Get("cursor")
+			endpointType = r.URL.Query().Get("endpointType")
+			userinfostr  = r.Header.Get("x-pager-userinfo")
+			pageid       = r.PathValue("id")
+		)
+
+		if firstStr != "" {
+			first, err = strconv.Atoi(firstStr)
+			if err != nil || first < 1 || first > maxListPages {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		result, err := dynamoClient.GetItem(r.Context(), &dynamodb.GetItemInput{
+			TableName: aws.String(conf.PageTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("page#%s", pageid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get page", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var page models.Page
+		if err := attributevalue.UnmarshalMap(result.Item, &page); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal page record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if !canReadPage(user, page) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		queryInput := &dynamodb.QueryInput{
+			TableName:              aws.String(conf.PageTableName),
+			Limit:                  aws.Int32(int32(first)),
+			KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk": "pk",
+				"#sk": "sk",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("page#%s", pageid),
+				},
+				":sk": &types.AttributeValueMemberS{
+					Value: "delivery#",
+				},
+			},
+		}
+
+		if endpointType != "" {
+			queryInput.FilterExpression = aws.String("#endpointType = :endpointType")
+			queryInput.ExpressionAttributeNames["#endpointType"] = "endpointType"
+			queryInput.ExpressionAttributeValues[":endpointType"] = &types.AttributeValueMemberS{
+				Value: endpointType,
+			}
+		}
+
+		if cursor != "" {
+			queryInput.ExclusiveStartKey = map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("page#%s", pageid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("delivery#%s", cursor),
+				},
+			}
+		}
+
+		queryResult, err := dynamoClient.Query(r.Context(), queryInput)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to query deliveries", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := &listResponse[deliveryResponse]{
+			Results: make([]deliveryResponse, 0, len(queryResult.Items)),
+		}
+
+		for _, item := range queryResult.Items {
+			var delivery models.Delivery
+			if err := attributevalue.UnmarshalMap(item, &delivery); err != nil {
+				logger.ErrorContext(r.Context(), "failed to unmarshal delivery record", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			response.Results = append(response.Results, toDeliveryResponse(delivery))
+		}
+
+		// With a filter a page of results can be short, or even empty, while
+		// more deliveries remain.
+		if queryResult.LastEvaluatedKey != nil {
+			response.NextCursor = strings.Split(queryResult.LastEvaluatedKey["sk"].(*types.AttributeValueMemberS).Value, "#")[1]
+			response.HasNextPage = true
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
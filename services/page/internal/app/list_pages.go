@@ -0,0 +1,249 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+)
+
+// maxListPages is the largest page size accepted by listPages. Page records are
+// loaded with a single BatchGetItem which accepts at most 100 keys.
+const maxListPages = 100
+
+// listPages returns a list of pages sent to the agencies the calling user is a
+// member of, each agency's pages newest first. An agencyId query parameter
+// narrows the results to a single agency.
+//
+// Agencies are walked in ID order, so the cursor encodes both the agency and
+// the last page returned for it, see formatCursor.
+func listPages(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			err         error
+			user        identity.User
+			first       = 10
+			firstStr    = r.URL.Query().Get("first")
+			cursor      = r.URL.Query().Get("cursor")
+			agencyid    = r.URL.Query().Get("agencyId")
+			userinfostr = r.Header.Get("x-pager-userinfo")
+		)
+
+		if firstStr != "" {
+			first, err = strconv.Atoi(firstStr)
+			if err != nil || first < 1 || first > maxListPages {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		agencies := slices.Sorted(maps.Keys(user.Memberships))
+		if agencyid != "" {
+			if _, ok := user.Memberships[agencyid]; !ok {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			agencies = []string{agencyid}
+		}
+
+		var (
+			start      int
+			startAfter string
+		)
+
+		if cursor != "" {
+			cursorAgency, cursorPage, ok := parseCursor(cursor)
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			start = slices.Index(agencies, cursorAgency)
+			if start < 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			startAfter = cursorPage
+		}
+
+		var (
+			agencyPages []models.AgencyPage
+			response    = new(listResponse[pageResponse])
+		)
+
+		for i := start; i < len(agencies) && len(agencyPages) < first; i++ {
+			queryInput := &dynamodb.QueryInput{
+				TableName:              aws.String(conf.PageTableName),
+				Limit:                  aws.Int32(int32(first - len(agencyPages))),
+				ScanIndexForward:       aws.Bool(false),
+				KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+				ExpressionAttributeNames: map[string]string{
+					"#pk": "pk",
+					"#sk": "sk",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":pk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("agency#%s", agencies[i]),
+					},
+					":sk": &types.AttributeValueMemberS{Value: "page#"},
+				},
+			}
+
+			if i == start && startAfter != "" {
+				queryInput.ExclusiveStartKey = map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("agency#%s", agencies[i]),
+					},
+					"sk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("page#%s", startAfter),
+					},
+				}
+			}
+
+			result, err := dynamoClient.Query(r.Context(), queryInput)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to query agency pages", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			for _, item := range result.Items {
+				var agencyPage models.AgencyPage
+				if err := attributevalue.UnmarshalMap(item, &agencyPage); err != nil {
+					logger.ErrorContext(r.Context(), "failed to unmarshal agency page record", slog.Any("error", err))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				agencyPages = append(agencyPages, agencyPage)
+			}
+
+			if result.LastEvaluatedKey != nil {
+				response.NextCursor = formatCursor(
+					agencies[i],
+					result.LastEvaluatedKey["sk"].(*types.AttributeValueMemberS).Value)
+				response.HasNextPage = true
+				break
+			}
+
+			// The page is full but this agency is exhausted, continue from the
+			// start of the next agency.
+			if len(agencyPages) == first && i+1 < len(agencies) {
+				response.NextCursor = formatCursor(agencies[i+1], "")
+				response.HasNextPage = true
+			}
+		}
+
+		pages, err := batchGetPages(r.Context(), conf, dynamoClient, agencyPages)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to batch get pages", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		for _, page := range pages {
+			response.Results = append(response.Results, toPageResponse(page))
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// formatCursor returns the cursor continuing after the agency page with the
+// given sort key, as <agencyId>:<created>#<pageId>. An empty sort key starts
+// from the agency's newest page.
+func formatCursor(agencyID string, sk string) string {
+	return fmt.Sprintf("%s:%s", agencyID, strings.TrimPrefix(sk, "page#"))
+}
+
+// parseCursor splits a cursor made by formatCursor into the agency and the
+// sort key to continue after, without its page# prefix. The creation time in
+// the sort key contains colons, so the cursor is split on the first one.
+func parseCursor(cursor string) (string, string, bool) {
+	agencyID, startAfter, ok := strings.Cut(cursor, ":")
+	if !ok || agencyID == "" {
+		return "", "", false
+	}
+	return agencyID, startAfter, true
+}
+
+// batchGetPages loads the page records referenced by a list of agency pages,
+// preserving their order. A page sent to more than one agency is only
+// returned once.
+func batchGetPages(ctx context.Context, conf Config, dynamoClient *dynamodb.Client, agencyPages []models.AgencyPage) ([]models.Page, error) {
+	var (
+		keys  []map[string]types.AttributeValue
+		order []string
+	)
+
+	for _, agencyPage := range agencyPages {
+		pk := fmt.Sprintf("page#%s", agencyPage.PageID)
+		if slices.Contains(order, pk) {
+			continue
+		}
+		order = append(order, pk)
+		keys = append(keys, map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: "meta"},
+		})
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pagesByPK := make(map[string]models.Page)
+	requestItems := map[string]types.KeysAndAttributes{
+		conf.PageTableName: {Keys: keys},
+	}
+
+	// BatchGetItem may return unprocessed keys when throttled, keep requesting
+	// until every key has been read.
+	for len(requestItems) > 0 {
+		result, err := dynamoClient.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: requestItems,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range result.Responses[conf.PageTableName] {
+			var page models.Page
+			if err := attributevalue.UnmarshalMap(item, &page); err != nil {
+				return nil, err
+			}
+			pagesByPK[page.PK] = page
+		}
+
+		requestItems = result.UnprocessedKeys
+	}
+
+	pages := make([]models.Page, 0, len(order))
+	for _, pk := range order {
+		if page, ok := pagesByPK[pk]; ok {
+			pages = append(pages, page)
+		}
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgencyPageSKOrder(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Sort keys are ordered by creation time whatever the page IDs, including
+	// times whose fractional seconds have trailing zeros.
+	sks := []string{
+		models.AgencyPageSK(created.Add(120*time.Millisecond), "00000000"),
+		models.AgencyPageSK(created, "ffffffff"),
+		models.AgencyPageSK(created.Add(100*time.Millisecond), "aaaaaaaa"),
+		models.AgencyPageSK(created.Add(time.Hour).In(time.FixedZone("MST", -7*60*60)), "11111111"),
+	}
+
+	assert.Equal(t, []string{
+		"page#2025-01-02T03:04:05.000000000Z#ffffffff",
+		"page#2025-01-02T03:04:05.100000000Z#aaaaaaaa",
+		"page#2025-01-02T03:04:05.120000000Z#00000000",
+		"page#2025-01-02T04:04:05.000000000Z#11111111",
+	}, slices.Sorted(slices.Values(sks)))
+}
+
+func TestCursor(t *testing.T) {
+	sk := models.AgencyPageSK(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), "page-1")
+
+	cursor := formatCursor("agency-1", sk)
+	assert.Equal(t, "agency-1:2025-01-02T03:04:05.000000000Z#page-1", cursor)
+
+	agencyID, startAfter, ok := parseCursor(cursor)
+	assert.True(t, ok)
+	assert.Equal(t, "agency-1", agencyID)
+	assert.Equal(t, sk, "page#"+startAfter)
+
+	agencyID, startAfter, ok = parseCursor(formatCursor("agency-2", ""))
+	assert.True(t, ok)
+	assert.Equal(t, "agency-2", agencyID)
+	assert.Empty(t, startAfter)
+
+	for _, cursor := range []string{"", "agency-1", ":page-1"} {
+		_, _, ok := parseCursor(cursor)
+		assert.False(t, ok, cursor)
+	}
+}
@@ -1,6 +1,14 @@
 package app
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+)
 
 type createPageRequest struct {
 	Agencies []string `json:"agencies"`
@@ -15,6 +23,11 @@ type createPageRequest struct {
 	} `json:"location"`
 }
 
+// maxPageAgencies is the most agencies a page can be sent to. A page is written
+// in one transaction with an index item and a delivery event for each agency,
+// and DynamoDB transactions are limited to 100 items.
+const maxPageAgencies = 49
+
 func (r createPageRequest) valid(ctx context.Context) map[string]string {
 	problems := make(map[string]string)
 
@@ -22,6 +35,10 @@ func (r createPageRequest) valid(ctx context.Context) map[string]string {
 		problems["agencies"] = "must create page with at least one agency"
 	}
 
+	if len(uniqueAgencies(r.Agencies)) > maxPageAgencies {
+		problems["agencies"] = fmt.Sprintf("page can be sent to at most %d agencies", maxPageAgencies)
+	}
+
 	if r.Title == "" {
 		problems["title"] = "page must have a title"
 	}
@@ -29,10 +46,184 @@ func (r createPageRequest) valid(ctx context.Context) map[string]string {
 	return problems
 }
 
+// uniqueAgencies returns the agencies sorted with duplicates removed. A page is
+// indexed and delivered once per agency, and a transaction can't write the same
+// item twice.
+func uniqueAgencies(agencies []string) []string {
+	return slices.Compact(slices.Sorted(slices.Values(agencies)))
+}
+
 type createPageResponse struct {
 	ID string `json:"id"`
 }
 
+// pageResponse represents a single page by ID.
+type pageResponse struct {
+	ID         string           `json:"id"`
+	Agencies   []string         `json:"agencies"`
+	Title      string           `json:"title"`
+	Notes      string           `json:"notes"`
+	Notify     bool             `json:"notify"`
+	Location   locationResponse `json:"location"`
+	Deliveries deliverySummary  `json:"deliveries"`
+	Created    time.Time        `json:"created"`
+	Modified   time.Time        `json:"modified"`
+	CreatedBy  string           `json:"createdBy"`
+	ModifiedBy string           `json:"modifiedBy"`
+	Responses  *responseRollup  `json:"responses,omitempty"`
+}
+
+// locationResponse represents the location of a page.
+type locationResponse struct {
+	Description string  `json:"description"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Type        string  `json:"type"`
+}
+
+// deliverySummary summarizes the deliveries of a page to endpoints.
+type deliverySummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// toPageResponse converts a page to a response.
+func toPageResponse(page models.Page) pageResponse {
+	return pageResponse{
+		ID:       strings.Split(page.PK, "#")[1],
+		Agencies: page.Agencies,
+		Title:    page.Title,
+		Notes:    page.Notes,
+		Notify:   page.Notify,
+		Location: locationResponse{
+			Description: page.Location.Description,
+			Latitude:    page.Location.Latitude,
+			Longitude:   page.Location.Longitude,
+			Type:        page.Location.Type,
+		},
+		Deliveries: deliverySummary{
+			Total:     page.Deliveries.Total,
+			Succeeded: page.Deliveries.Succeeded,
+			Failed:    page.Deliveries.Failed,
+		},
+		Created:    page.Created,
+		Modified:   page.Modified,
+		CreatedBy:  page.CreatedBy,
+		ModifiedBy: page.ModifiedBy,
+	}
+}
+
+// createResponseRequest represents a responders answer to a page.
+type createResponseRequest struct {
+	Status models.ResponseStatus `json:"status"`
+	ETA    *time.Time            `json:"eta"`
+}
+
+// valid returns a map of validation problems for the request.
+func (r createResponseRequest) valid(ctx context.Context) map[string]string {
+	problems := make(map[string]string)
+
+	validStatuses := []models.ResponseStatus{
+		models.ResponseStatusResponding,
+		models.ResponseStatusNotAvailable,
+		models.ResponseStatusOnScene,
+	}
+
+	if !slices.Contains(validStatuses, r.Status) {
+		problems["status"] = fmt.Sprintf("status must be one of %s", strings.Join(validStatuses, ", "))
+	}
+
+	if r.ETA != nil && r.Status != models.ResponseStatusResponding {
+		problems["eta"] = fmt.Sprintf("eta may only be provided with a status of %s", models.ResponseStatusResponding)
+	}
+
+	return problems
+}
+
+// responseResponse represents a single responders answer to a page.
+type responseResponse struct {
+	PageID     string                `json:"pageId"`
+	UserID     string                `json:"userId"`
+	UserName   string                `json:"userName"`
+	Status     models.ResponseStatus `json:"status"`
+	ETA        *time.Time            `json:"eta,omitempty"`
+	Created    time.Time             `json:"created"`
+	Modified   time.Time             `json:"modified"`
+	CreatedBy  string                `json:"createdBy"`
+	ModifiedBy string                `json:"modifiedBy"`
+}
+
+// toResponseResponse converts a response to a response.
+func toResponseResponse(response models.Response) responseResponse {
+	return responseResponse{
+		PageID:     strings.Split(response.PK, "#")[1],
+		UserID:     strings.Split(response.SK, "#")[1],
+		UserName:   response.UserName,
+		Status:     response.Status,
+		ETA:        response.ETA,
+		Created:    response.Created,
+		Modified:   response.Modified,
+		CreatedBy:  response.CreatedBy,
+		ModifiedBy: response.ModifiedBy,
+	}
+}
+
+// responseRollup summarizes the responses to a page.
+type responseRollup struct {
+	Total   int                           `json:"total"`
+	Counts  map[models.ResponseStatus]int `json:"counts"`
+	Results []responseResponse            `json:"results"`
+}
+
+// toResponseRollup converts the responses to a page into a rollup.
+func toResponseRollup(responses []models.Response) *responseRollup {
+	rollup := &responseRollup{
+		Counts:  make(map[models.ResponseStatus]int),
+		Results: make([]responseResponse, 0, len(responses)),
+	}
+
+	for _, response := range responses {
+		rollup.Total++
+		rollup.Counts[response.Status]++
+		rollup.Results = append(rollup.Results, toResponseResponse(response))
+	}
+
+	return rollup
+}
+
+// deliveryResponse represents the delivery of a page to a single endpoint.
+type deliveryResponse struct {
+	EndpointID         string                `json:"endpointId"`
+	EndpointName       string                `json:"endpointName"`
+	EndpointType       string                `json:"endpointType"`
+	UserID             string                `json:"userId"`
+	AgencyID           string                `json:"agencyId"`
+	Status             models.DeliveryStatus `json:"status"`
+	StatusCode         int                   `json:"statusCode"`
+	Attempts           int                   `json:"attempts"`
+	ProviderMessageIDs []string              `json:"providerMessageIds,omitempty"`
+	Error              string                `json:"error,omitempty"`
+	AttemptedAt        time.Time             `json:"attemptedAt"`
+}
+
+// toDeliveryResponse converts a delivery to a response.
+func toDeliveryResponse(delivery models.Delivery) deliveryResponse {
+	return deliveryResponse{
+		EndpointID:         strings.Split(delivery.SK, "#")[1],
+		EndpointName:       delivery.EndpointName,
+		EndpointType:       delivery.EndpointType,
+		UserID:             delivery.UserID,
+		AgencyID:           delivery.AgencyID,
+		Status:             delivery.Status,
+		StatusCode:         delivery.StatusCode,
+		Attempts:           delivery.Attempts,
+		ProviderMessageIDs: delivery.ProviderMessageIDs,
+		Error:              delivery.Error,
+		AttemptedAt:        delivery.AttemptedAt,
+	}
+}
+
 // listResponse represents a list of items with pagination.
 type listResponse[T any] struct {
 	Results     []T    `json:"results"`
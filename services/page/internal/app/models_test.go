@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUniqueAgencies(t *testing.T) {
+	assert.Equal(t, []string{"agency-1", "agency-2"}, uniqueAgencies([]string{"agency-2", "agency-1", "agency-2"}))
+	assert.Empty(t, uniqueAgencies(nil))
+}
+
+func TestCreatePageRequestValid(t *testing.T) {
+	agencies := func(n int, duplicate bool) []string {
+		var agencies []string
+		for i := range n {
+			agencies = append(agencies, fmt.Sprintf("agency-%d", i))
+			if duplicate {
+				agencies = append(agencies, fmt.Sprintf("agency-%d", i))
+			}
+		}
+		return agencies
+	}
+
+	tests := []struct {
+		name     string
+		agencies []string
+		problem  bool
+	}{
+		{name: "one agency", agencies: agencies(1, false)},
+		{name: "duplicate agency", agencies: []string{"agency-1", "agency-1"}},
+		{name: "no agencies", problem: true},
+		{name: "most agencies", agencies: agencies(maxPageAgencies, false)},
+		{name: "most agencies duplicated", agencies: agencies(maxPageAgencies, true)},
+		{name: "too many agencies", agencies: agencies(maxPageAgencies+1, false), problem: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := createPageRequest{Title: "Fire", Agencies: tt.agencies}.valid(context.Background())
+
+			if tt.problem {
+				assert.Contains(t, problems, "agencies")
+			} else {
+				assert.Empty(t, problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+)
+
+// readPage returns a single page by ID.
+// The calling user must have a membership in at least one of the agencies the
+// page was sent to.
+func readPage(conf Config, logger *slog.Logger, dynamoClient *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			pageid      = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		result, err := dynamoClient.GetItem(r.Context(), &dynamodb.GetItemInput{
+			TableName: aws.String(conf.PageTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("page#%s", pageid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get page", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var page models.Page
+		if err := attributevalue.UnmarshalMap(result.Item, &page); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal page record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if !canReadPage(user, page) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var (
+			responses         []models.Response
+			exclusiveStartKey map[string]types.AttributeValue
+		)
+
+		// Read every response to the page to build the rollup. A page is answered
+		// by the members of a handful of agencies so this is a small partition.
+		for {
+			queryResult, err := dynamoClient.Query(r.Context(), &dynamodb.QueryInput{
+				TableName:              aws.String(conf.PageTableName),
+				KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+				ExpressionAttributeNames: map[string]string{
+					"#pk": "pk",
+					"#sk": "sk",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":pk": &types.AttributeValueMemberS{
+						Value: fmt.Sprintf("page#%s", pageid),
+					},
+					":sk": &types.AttributeValueMemberS{Value: "response#"},
+				},
+				ExclusiveStartKey: exclusiveStartKey,
+			})
+
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to query page responses", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			for _, item := range queryResult.Items {
+				var response models.Response
+				if err := attributevalue.UnmarshalMap(item, &response); err != nil {
+					logger.ErrorContext(r.Context(), "failed to unmarshal response record", slog.Any("error", err))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				responses = append(responses, response)
+			}
+
+			if queryResult.LastEvaluatedKey == nil {
+				break
+			}
+			exclusiveStartKey = queryResult.LastEvaluatedKey
+		}
+
+		response := toPageResponse(page)
+		response.Responses = toResponseRollup(responses)
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// canReadPage returns true if the user is a platform admin or a member of any
+// agency the page was sent to.
+func canReadPage(user identity.User, page models.Page) bool {
+	if slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+		return true
+	}
+
+	for _, agency := range page.Agencies {
+		if _, ok := user.Memberships[agency]; ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -6,9 +6,14 @@ import (
 	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 )
 
-func addRoutes(mux *http.ServeMux, config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) {
-	mux.Handle(fmt.Sprintf("POST /%s", config.Environment), createPage(config, logger, dynamoClient, snsClient))
+func addRoutes(mux *http.ServeMux, config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) {
+	mux.Handle(fmt.Sprintf("GET /%s", config.Environment), listPages(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("GET /%s/{id}", config.Environment), readPage(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("GET /%s/{id}/deliveries", config.Environment), listDeliveries(config, logger, dynamoClient))
+
+	mux.Handle(fmt.Sprintf("POST /%s", config.Environment), createPage(config, logger, dynamoClient, publisher))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/responses", config.Environment), createResponse(config, logger, dynamoClient, publisher))
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// agencyPageTimeLayout formats the creation time in an agency page's sort key.
+// It is fixed width so sort keys order the same as the times they hold.
+const agencyPageTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// AgencyPage records that a page was sent to an agency. The relationship is
+// encoded within the pk (agency#<id>) and sk (page#<created>#<id>) so the pages
+// for an agency can be queried, newest first, without scanning the table.
+type AgencyPage struct {
+	PK        string     `dynamodbav:"pk"`
+	SK        string     `dynamodbav:"sk"`
+	Type      EntityType `dynamodbav:"type"`
+	PageID    string     `dynamodbav:"pageId"`
+	Created   time.Time  `dynamodbav:"created"`
+	CreatedBy string     `dynamodbav:"createdBy"`
+}
+
+// AgencyPageSK returns the sort key of the agency page for a page created at
+// created.
+func AgencyPageSK(created time.Time, pageID string) string {
+	return fmt.Sprintf("page#%s#%s", created.UTC().Format(agencyPageTimeLayout), pageID)
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type AgencyStatus = string
+
+const (
+	AgencyStatusActive   AgencyStatus = "ACTIVE"
+	AgencyStatusInactive AgencyStatus = "INACTIVE"
+)
+
+// AgencyState is this service's copy of the status of an agency, kept in step
+// with the agency service by the agency.deactivated and agency.reactivated
+// events. It is stored under pk agency#<id> and sk status, alongside the pages
+// sent to the agency.
+type AgencyState struct {
+	PK         string       `dynamodbav:"pk"`
+	SK         string       `dynamodbav:"sk"`
+	Type       EntityType   `dynamodbav:"type"`
+	Status     AgencyStatus `dynamodbav:"status"`
+	Modified   time.Time    `dynamodbav:"modified"`
+	ModifiedBy string       `dynamodbav:"modifiedBy"`
+	// StatusModified is when the status changed in unix nanoseconds. Events
+	// delivered out of order are told apart by comparing it, which the
+	// formatted modified time can't be relied on for.
+	StatusModified int64 `dynamodbav:"statusModified"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type DeliveryStatus = string
+
+const (
+	DeliveryStatusSucceeded DeliveryStatus = "SUCCEEDED"
+	DeliveryStatusFailed    DeliveryStatus = "FAILED"
+)
+
+// Delivery represents the outcome of delivering a page to a single endpoint.
+// The endpoint is encoded within the sk (delivery#<endpointId>). Only the most
+// recent attempt for an endpoint is kept.
+type Delivery struct {
+	PK           string         `dynamodbav:"pk"`
+	SK           string         `dynamodbav:"sk"`
+	Type         EntityType     `dynamodbav:"type"`
+	Status       DeliveryStatus `dynamodbav:"status"`
+	AgencyID     string         `dynamodbav:"agencyId"`
+	EndpointType string         `dynamodbav:"endpointType"`
+	EndpointName string         `dynamodbav:"endpointName"`
+	UserID       string         `dynamodbav:"userId"`
+	StatusCode   int            `dynamodbav:"statusCode"`
+	LatencyMs    int64          `dynamodbav:"latencyMs"`
+	Error        string         `dynamodbav:"error"`
+	AttemptedAt  time.Time      `dynamodbav:"attemptedAt"`
+	// Attempts is how many times delivery was attempted before this outcome.
+	Attempts int `dynamodbav:"attempts"`
+	// ProviderMessageIDs are the identifiers assigned by the provider that
+	// delivered the page, such as the SIDs of each SMS segment.
+	ProviderMessageIDs []string  `dynamodbav:"providerMessageIds,omitempty"`
+	Created            time.Time `dynamodbav:"created"`
+	Modified           time.Time `dynamodbav:"modified"`
+	CreatedBy          string    `dynamodbav:"createdBy"`
+	ModifiedBy         string    `dynamodbav:"modifiedBy"`
+}
+
+// DeliverySummary aggregates the deliveries for a page.
+type DeliverySummary struct {
+	Total     int `dynamodbav:"total"`
+	Succeeded int `dynamodbav:"succeeded"`
+	Failed    int `dynamodbav:"failed"`
+}
@@ -3,5 +3,9 @@ package models
 type EntityType = string
 
 const (
-	EntityTypePage EntityType = "PAGE"
+	EntityTypePage        EntityType = "PAGE"
+	EntityTypeAgencyPage  EntityType = "AGENCY_PAGE"
+	EntityTypeResponse    EntityType = "RESPONSE"
+	EntityTypeDelivery    EntityType = "DELIVERY"
+	EntityTypeAgencyState EntityType = "AGENCY_STATE"
 )
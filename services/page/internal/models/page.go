@@ -4,17 +4,23 @@ import "time"
 
 // Page represents a Page in the database.
 type Page struct {
-	PK         string     `dynamodbav:"pk"`
-	SK         string     `dynamodbav:"sk"`
-	Type       EntityType `dynamodbav:"type"`
-	Title      string     `dynamodbav:"title"`
-	Notes      string     `dynamodbav:"notes"`
-	Notify     bool       `dynamodbav:"notify"`
-	Location   Location   `dynamodbav:"location"`
-	Created    time.Time  `dynamodbav:"created"`
-	Modified   time.Time  `dynamodbav:"modified"`
-	CreatedBy  string     `dynamodbav:"createdBy"`
-	ModifiedBy string     `dynamodbav:"modifiedBy"`
+	PK         string          `dynamodbav:"pk"`
+	SK         string          `dynamodbav:"sk"`
+	Type       EntityType      `dynamodbav:"type"`
+	Title      string          `dynamodbav:"title"`
+	Notes      string          `dynamodbav:"notes"`
+	Notify     bool            `dynamodbav:"notify"`
+	Location   Location        `dynamodbav:"location"`
+	Agencies   []string        `dynamodbav:"agencies"`
+	Deliveries DeliverySummary `dynamodbav:"deliveries"`
+	Created    time.Time       `dynamodbav:"created"`
+	Modified   time.Time       `dynamodbav:"modified"`
+	CreatedBy  string          `dynamodbav:"createdBy"`
+	ModifiedBy string          `dynamodbav:"modifiedBy"`
+
+	// DeliveriesVersion counts the writes of Deliveries, so a summary can only
+	// be replaced by one rebuilt from the version it replaces.
+	DeliveriesVersion int64 `dynamodbav:"deliveriesVersion"`
 }
 
 // Location represents a location for a page. The location may be a common name (e.g., "Kelso Ridge") or may be a
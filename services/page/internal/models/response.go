@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type ResponseStatus = string
+
+const (
+	ResponseStatusResponding   ResponseStatus = "RESPONDING"
+	ResponseStatusNotAvailable ResponseStatus = "NOT_AVAILABLE"
+	ResponseStatusOnScene      ResponseStatus = "ON_SCENE"
+)
+
+// Response represents a responders answer to a page. A user has a single
+// response per page which is updated each time they respond, Created records
+// when they first responded. The responding user is encoded within the sk
+// (response#<userId>).
+type Response struct {
+	PK         string         `dynamodbav:"pk"`
+	SK         string         `dynamodbav:"sk"`
+	Type       EntityType     `dynamodbav:"type"`
+	Status     ResponseStatus `dynamodbav:"status"`
+	ETA        *time.Time     `dynamodbav:"eta,omitempty"`
+	UserName   string         `dynamodbav:"userName"`
+	Created    time.Time      `dynamodbav:"created"`
+	Modified   time.Time      `dynamodbav:"modified"`
+	CreatedBy  string         `dynamodbav:"createdBy"`
+	ModifiedBy string         `dynamodbav:"modifiedBy"`
+}
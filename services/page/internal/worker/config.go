@@ -1,11 +1,18 @@
 package worker
 
-import "log/slog"
+import (
+	"log/slog"
+	"time"
+)
 
 type Config struct {
-	LogLevel        slog.Level `env:"LOG_LEVEL"`
-	Environment     string     `env:"ENVIRONMENT"`
-	PageTableName   string     `env:"PAGE_TABLE_NAME"`
-	EventsTopicARN  string     `env:"EVENTS_TOPIC_ARN"`
-	EventRetryCount int        `env:"EVENT_RETRY_COUNT"`
+	LogLevel         slog.Level    `env:"LOG_LEVEL"`
+	Environment      string        `env:"ENVIRONMENT"`
+	PageTableName    string        `env:"PAGE_TABLE_NAME"`
+	EventsTopicARN   string        `env:"EVENTS_TOPIC_ARN"`
+	EventRetryCount  int           `env:"EVENT_RETRY_COUNT"`
+	EventLedgerLease time.Duration `env:"EVENT_LEDGER_LEASE" envDefault:"30s"`
+	EventLedgerTTL   time.Duration `env:"EVENT_LEDGER_TTL" envDefault:"336h"`
+	OutboxRecordTTL  time.Duration `env:"OUTBOX_RECORD_TTL" envDefault:"168h"`
+	OutboxSweepAge   time.Duration `env:"OUTBOX_SWEEP_AGE" envDefault:"15m"`
 }
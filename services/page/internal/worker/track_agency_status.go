@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+)
+
+// trackAgencyStatus records the status of an agency when it is deactivated or
+// reactivated, so pages can't be created for an inactive agency.
+func trackAgencyStatus(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.AgencyDeactivated](envelope)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to unmarshal message", slog.Any("error", err))
+			return err
+		}
+
+		stateAV, err := attributevalue.MarshalMap(models.AgencyState{
+			PK:             fmt.Sprintf("agency#%s", message.AgencyID),
+			SK:             "status",
+			Type:           models.EntityTypeAgencyState,
+			Status:         message.Status,
+			Modified:       message.Modified,
+			ModifiedBy:     message.ModifiedBy,
+			StatusModified: message.Modified.UnixNano(),
+		})
+
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to marshal agency status", slog.Any("error", err))
+			return err
+		}
+
+		// Deactivating and reactivating in quick succession can deliver the
+		// events out of order, only replace the status with a later change.
+		// Statuses recorded without statusModified can't be compared and are
+		// replaced.
+		_, err = dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(config.PageTableName),
+			Item:                stateAV,
+			ConditionExpression: aws.String("attribute_not_exists(#pk) OR attribute_not_exists(#statusModified) OR #statusModified <= :statusModified"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk":             "pk",
+				"#statusModified": "statusModified",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":statusModified": stateAV["statusModified"],
+			},
+		})
+
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			logger.InfoContext(ctx, "ignoring stale agency status", slog.String("agencyId", message.AgencyID))
+			return nil
+		}
+
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to put agency status", slog.Any("error", err))
+			return err
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStatusTable records the items put to it. Puts fail their condition if
+// conditionFailed is set.
+type fakeStatusTable struct {
+	dynamoDBAPI
+
+	conditionFailed bool
+	puts            []*dynamodb.PutItemInput
+}
+
+func (f *fakeStatusTable) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.puts = append(f.puts, params)
+	if f.conditionFailed {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func agencyStatusEnvelope(t *testing.T, status models.AgencyStatus, modified time.Time) events.Envelope {
+	t.Helper()
+
+	eventType := events.TypeAgencyReactivated
+	if status == models.AgencyStatusInactive {
+		eventType = events.TypeAgencyDeactivated
+	}
+
+	envelope, err := events.NewPublisher(nil, "topic", "agency").Envelope(context.Background(), eventType, events.AgencyDeactivated{
+		AgencyID:   "agency-1",
+		Status:     status,
+		Modified:   modified,
+		ModifiedBy: "admin",
+	})
+	require.NoError(t, err)
+	return envelope
+}
+
+func TestTrackAgencyStatus(t *testing.T) {
+	var (
+		table    = new(fakeStatusTable)
+		handler  = trackAgencyStatus(Config{}, discardLogger, table)
+		modified = time.Date(2025, 1, 2, 3, 4, 5, 600, time.UTC)
+	)
+
+	require.NoError(t, handler(context.Background(), agencyStatusEnvelope(t, models.AgencyStatusInactive, modified)))
+
+	require.Len(t, table.puts, 1)
+	put := table.puts[0]
+
+	// The status time is compared as a number, formatted times don't sort.
+	assert.Equal(t,
+		"attribute_not_exists(#pk) OR attribute_not_exists(#statusModified) OR #statusModified <= :statusModified",
+		aws.ToString(put.ConditionExpression))
+	assert.Equal(t,
+		&types.AttributeValueMemberN{Value: strconv.FormatInt(modified.UnixNano(), 10)},
+		put.ExpressionAttributeValues[":statusModified"])
+
+	var state models.AgencyState
+	require.NoError(t, attributevalue.UnmarshalMap(put.Item, &state))
+	assert.Equal(t, models.AgencyState{
+		PK:             "agency#agency-1",
+		SK:             "status",
+		Type:           models.EntityTypeAgencyState,
+		Status:         models.AgencyStatusInactive,
+		Modified:       modified,
+		ModifiedBy:     "admin",
+		StatusModified: modified.UnixNano(),
+	}, state)
+}
+
+func TestTrackAgencyStatusStale(t *testing.T) {
+	table := &fakeStatusTable{conditionFailed: true}
+
+	handler := trackAgencyStatus(Config{}, discardLogger, table)
+	assert.NoError(t, handler(context.Background(), agencyStatusEnvelope(t, models.AgencyStatusActive, time.Now())))
+}
@@ -0,0 +1,238 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+)
+
+// trackDelivery records the outcome of a delivery attempt against the page and
+// refreshes the delivery summary on the page.
+func trackDelivery(ctx context.Context, config Config, logger *slog.Logger, dynamoClient dynamoDBAPI, envelope events.Envelope, status models.DeliveryStatus) error {
+	message, err := events.DecodePayload[events.EndpointDelivery](envelope)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to unmarshal message", slog.Any("error", err))
+		return err
+	}
+
+	now := time.Now()
+
+	deliveryAV, err := attributevalue.MarshalMap(models.Delivery{
+		PK:                 fmt.Sprintf("page#%s", message.PageID),
+		SK:                 fmt.Sprintf("delivery#%s", message.EndpointID),
+		Type:               models.EntityTypeDelivery,
+		Status:             status,
+		AgencyID:           message.AgencyID,
+		EndpointType:       message.EndpointType,
+		EndpointName:       message.EndpointName,
+		UserID:             message.UserID,
+		StatusCode:         message.StatusCode,
+		LatencyMs:          message.LatencyMs,
+		Error:              message.Error,
+		AttemptedAt:        message.AttemptedAt,
+		Attempts:           message.Attempts,
+		ProviderMessageIDs: message.ProviderMessageIDs,
+		Created:            now,
+		Modified:           now,
+		CreatedBy:          "system",
+		ModifiedBy:         "system",
+	})
+
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to marshal delivery", slog.Any("error", err))
+		return err
+	}
+
+	// Delivery events can arrive out of order, only replace a delivery record
+	// with a more recent attempt.
+	_, err = dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(config.PageTableName),
+		Item:                deliveryAV,
+		ConditionExpression: aws.String("attribute_not_exists(#pk) OR #attemptedAt <= :attemptedAt"),
+		ExpressionAttributeNames: map[string]string{
+			"#pk":          "pk",
+			"#attemptedAt": "attemptedAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":attemptedAt": deliveryAV["attemptedAt"],
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		logger.DebugContext(
+			ctx,
+			"skipping stale delivery",
+			slog.String("pageId", message.PageID),
+			slog.String("endpointId", message.EndpointID))
+		return nil
+	}
+
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to put delivery", slog.Any("error", err))
+		return err
+	}
+
+	// Deliveries for the same page are tracked concurrently, and each rebuilds
+	// the summary. A summary rebuilt before another delivery was recorded must
+	// not replace one rebuilt after it, so the summary is versioned and a write
+	// that lost the race rebuilds it again.
+	for attempt := 1; ; attempt++ {
+		err := updateDeliverySummary(ctx, config, dynamoClient, message.PageID)
+		if err == nil {
+			return nil
+		}
+
+		if !errors.As(err, &conditionFailed) || attempt == summaryAttempts {
+			logger.ErrorContext(ctx, "failed to update page delivery summary", slog.Any("error", err))
+			return err
+		}
+
+		logger.DebugContext(
+			ctx,
+			"delivery summary changed, rebuilding",
+			slog.String("pageId", message.PageID),
+			slog.Int("attempt", attempt))
+	}
+}
+
+// summaryAttempts is how many times the delivery summary is rebuilt when other
+// deliveries update it concurrently.
+const summaryAttempts = 5
+
+// updateDeliverySummary rebuilds the page's delivery summary and writes it if
+// the summary hasn't been written since it was read. A ConditionalCheckFailed
+// error means it has and the summary should be rebuilt.
+func updateDeliverySummary(ctx context.Context, config Config, dynamoClient dynamoDBAPI, pageID string) error {
+	key := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{
+			Value: fmt.Sprintf("page#%s", pageID),
+		},
+		"sk": &types.AttributeValueMemberS{
+			Value: "meta",
+		},
+	}
+
+	// The version is read before the deliveries so any delivery recorded after
+	// the query is tracked by a handler that will see a newer version.
+	result, err := dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(config.PageTableName),
+		Key:                  key,
+		ConsistentRead:       aws.Bool(true),
+		ProjectionExpression: aws.String("#pk, #deliveriesVersion"),
+		ExpressionAttributeNames: map[string]string{
+			"#pk":                "pk",
+			"#deliveriesVersion": "deliveriesVersion",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get page: %w", err)
+	}
+
+	if result.Item == nil {
+		return fmt.Errorf("page %s doesn't exist", pageID)
+	}
+
+	var page models.Page
+	if err := attributevalue.UnmarshalMap(result.Item, &page); err != nil {
+		return fmt.Errorf("failed to unmarshal page: %w", err)
+	}
+
+	summary, err := summarizeDeliveries(ctx, config, dynamoClient, pageID)
+	if err != nil {
+		return fmt.Errorf("failed to summarize deliveries: %w", err)
+	}
+
+	summaryAV, err := attributevalue.MarshalMap(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery summary: %w", err)
+	}
+
+	_, err = dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(config.PageTableName),
+		Key:                 key,
+		ConditionExpression: aws.String("attribute_exists(#pk) AND (attribute_not_exists(#deliveriesVersion) OR #deliveriesVersion = :version)"),
+		UpdateExpression:    aws.String("SET #deliveries = :deliveries, #deliveriesVersion = :nextVersion"),
+		ExpressionAttributeNames: map[string]string{
+			"#pk":                "pk",
+			"#deliveries":        "deliveries",
+			"#deliveriesVersion": "deliveriesVersion",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":deliveries": &types.AttributeValueMemberM{
+				Value: summaryAV,
+			},
+			":version":     &types.AttributeValueMemberN{Value: strconv.FormatInt(page.DeliveriesVersion, 10)},
+			":nextVersion": &types.AttributeValueMemberN{Value: strconv.FormatInt(page.DeliveriesVersion+1, 10)},
+		},
+	})
+
+	return err
+}
+
+// summarizeDeliveries counts the delivery records for a page. The summary is
+// rebuilt from the records rather than incremented so that redelivered events
+// can't skew the counts.
+func summarizeDeliveries(ctx context.Context, config Config, dynamoClient dynamoDBAPI, pageID string) (models.DeliverySummary, error) {
+	var (
+		summary           models.DeliverySummary
+		exclusiveStartKey map[string]types.AttributeValue
+	)
+
+	for {
+		result, err := dynamoClient.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(config.PageTableName),
+			ConsistentRead:         aws.Bool(true),
+			KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+			ProjectionExpression:   aws.String("#status"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk":     "pk",
+				"#sk":     "sk",
+				"#status": "status",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("page#%s", pageID),
+				},
+				":sk": &types.AttributeValueMemberS{
+					Value: "delivery#",
+				},
+			},
+			ExclusiveStartKey: exclusiveStartKey,
+		})
+
+		if err != nil {
+			return summary, err
+		}
+
+		for _, item := range result.Items {
+			var delivery models.Delivery
+			if err := attributevalue.UnmarshalMap(item, &delivery); err != nil {
+				return summary, err
+			}
+
+			summary.Total++
+			switch delivery.Status {
+			case models.DeliveryStatusSucceeded:
+				summary.Succeeded++
+			case models.DeliveryStatusFailed:
+				summary.Failed++
+			}
+		}
+
+		if result.LastEvaluatedKey == nil {
+			return summary, nil
+		}
+		exclusiveStartKey = result.LastEvaluatedKey
+	}
+}
@@ -0,0 +1,153 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePageTable holds a page and its delivery records in memory. Delivery
+// records are put unconditionally and queried by sk prefix. The summary update
+// succeeds only if the page's deliveriesVersion is still the :version the
+// update was built from, as its condition requires.
+type fakePageTable struct {
+	page       map[string]types.AttributeValue
+	deliveries map[string]map[string]types.AttributeValue
+
+	// afterQuery is called after the deliveries are read for a query, before
+	// they are returned.
+	afterQuery func()
+
+	updates []*dynamodb.UpdateItemInput
+}
+
+func newFakePageTable(t *testing.T) *fakePageTable {
+	t.Helper()
+
+	page, err := attributevalue.MarshalMap(models.Page{PK: "page#page-1", SK: "meta", Type: models.EntityTypePage})
+	require.NoError(t, err)
+
+	return &fakePageTable{
+		page:       page,
+		deliveries: make(map[string]map[string]types.AttributeValue),
+	}
+}
+
+func (f *fakePageTable) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f.page}, nil
+}
+
+func (f *fakePageTable) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.deliveries[params.Item["sk"].(*types.AttributeValueMemberS).Value] = params.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakePageTable) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	prefix := params.ExpressionAttributeValues[":sk"].(*types.AttributeValueMemberS).Value
+
+	var items []map[string]types.AttributeValue
+	for sk, item := range f.deliveries {
+		if strings.HasPrefix(sk, prefix) {
+			items = append(items, item)
+		}
+	}
+
+	if afterQuery := f.afterQuery; afterQuery != nil {
+		f.afterQuery = nil
+		afterQuery()
+	}
+
+	return &dynamodb.QueryOutput{Items: items}, nil
+}
+
+func (f *fakePageTable) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.updates = append(f.updates, params)
+
+	version := &types.AttributeValueMemberN{Value: "0"}
+	if v, ok := f.page["deliveriesVersion"]; ok {
+		version = v.(*types.AttributeValueMemberN)
+	}
+	if params.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN).Value != version.Value {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+
+	f.page["deliveries"] = params.ExpressionAttributeValues[":deliveries"]
+	f.page["deliveriesVersion"] = params.ExpressionAttributeValues[":nextVersion"]
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (f *fakePageTable) summary(t *testing.T) models.DeliverySummary {
+	t.Helper()
+
+	var page models.Page
+	require.NoError(t, attributevalue.UnmarshalMap(f.page, &page))
+	return page.Deliveries
+}
+
+func deliveryEnvelope(t *testing.T, eventType events.Type, endpointID string) events.Envelope {
+	t.Helper()
+
+	envelope, err := events.NewPublisher(nil, "topic", "endpoint").Envelope(context.Background(), eventType, events.EndpointDelivery{
+		PageID:      "page-1",
+		AgencyID:    "agency-1",
+		EndpointID:  endpointID,
+		AttemptedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	return envelope
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestTrackDelivery(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		table     = newFakePageTable(t)
+		succeeded = trackSuccessfulDelivery(Config{}, discardLogger, table)
+	)
+
+	require.NoError(t, succeeded(ctx, deliveryEnvelope(t, events.TypeEndpointDeliverySucceeded, "endpoint-1")))
+
+	assert.Equal(t, models.DeliverySummary{Total: 1, Succeeded: 1}, table.summary(t))
+	require.Len(t, table.updates, 1)
+	assert.Equal(t,
+		"attribute_exists(#pk) AND (attribute_not_exists(#deliveriesVersion) OR #deliveriesVersion = :version)",
+		aws.ToString(table.updates[0].ConditionExpression))
+}
+
+func TestTrackDeliveryInterleaved(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		table     = newFakePageTable(t)
+		succeeded = trackSuccessfulDelivery(Config{}, discardLogger, table)
+		failed    = trackFailedDelivery(Config{}, discardLogger, table)
+	)
+
+	// The failed delivery is tracked in full after the successful delivery has
+	// read the deliveries, but before it writes its summary. The successful
+	// delivery's summary is missing the failed delivery, so it must not be
+	// written.
+	table.afterQuery = func() {
+		require.NoError(t, failed(ctx, deliveryEnvelope(t, events.TypeEndpointDeliveryFailed, "endpoint-2")))
+	}
+
+	require.NoError(t, succeeded(ctx, deliveryEnvelope(t, events.TypeEndpointDeliverySucceeded, "endpoint-1")))
+
+	assert.Equal(t, models.DeliverySummary{Total: 2, Succeeded: 1, Failed: 1}, table.summary(t))
+
+	// The failed delivery's write, the successful delivery's rejected write
+	// and its rebuilt write.
+	assert.Len(t, table.updates, 3)
+}
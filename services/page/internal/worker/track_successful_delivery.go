@@ -2,13 +2,17 @@ package worker
 
 import (
 	"context"
-	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"log/slog"
+
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/page/internal/models"
 )
 
-func trackSuccessfulDelivery(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client) func(context.Context, events.SNSEntity, int) error {
-	return func(ctx context.Context, entity events.SNSEntity, i int) error {
-		return nil
+// trackSuccessfulDelivery records a successful delivery of a page to an
+// endpoint.
+func trackSuccessfulDelivery(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		return trackDelivery(ctx, config, logger, dynamoClient, envelope, models.DeliveryStatusSucceeded)
 	}
 }
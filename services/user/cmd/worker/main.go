@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/caarlos0/env/v11"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/services/user/internal/worker"
 )
 
@@ -39,6 +40,7 @@ func run(ctx context.Context) error {
 
 	dynamoClient := dynamodb.NewFromConfig(awsconf)
 	snsClient := sns.NewFromConfig(awsconf)
+	publisher := events.NewPublisher(snsClient, conf.EventsTopicARN, "user")
 
 	// Initialize a new client using a domain, client ID and client secret.
 	authAPI, err := authentication.New(
@@ -51,7 +53,7 @@ func run(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize the auth0 authentication API client: %w", err)
 	}
 
-	lambda.Start(worker.ProcessEvents(conf, logger, dynamoClient, snsClient, authAPI))
+	lambda.Start(worker.ProcessEvents(conf, logger, dynamoClient, publisher, authAPI))
 
 	return nil
 }
@@ -0,0 +1,17 @@
+package app
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+)
+
+func NewServer(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) http.Handler {
+	mux := http.NewServeMux()
+
+	addRoutes(mux, config, logger, dynamoClient, publisher)
+
+	return mux
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/user/internal/models"
+)
+
+// listMyEndpoints returns the endpoints owned by the calling user. These are
+// the copies kept up to date by endpoint events, the endpoint service has the
+// full details of each endpoint.
+func listMyEndpoints(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			err         error
+			user        identity.User
+			first       = 10
+			firstStr    = r.URL.Query().Get("first")
+			cursor      = r.URL.Query().Get("cursor")
+			userinfostr = r.Header.Get("x-pager-userinfo")
+		)
+
+		if firstStr != "" {
+			first, err = strconv.Atoi(firstStr)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		queryInput := &dynamodb.QueryInput{
+			TableName:              aws.String(config.UserTableName),
+			Limit:                  aws.Int32(int32(first)),
+			KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :sk)"),
+			// Skip the tombstones left by deleted endpoints.
+			FilterExpression: aws.String("#type = :type"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk":   "pk",
+				"#sk":   "sk",
+				"#type": "type",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("user#%s", user.ID),
+				},
+				":sk":   &types.AttributeValueMemberS{Value: "endpoint#"},
+				":type": &types.AttributeValueMemberS{Value: string(models.EntityTypeEndpoint)},
+			},
+		}
+
+		if cursor != "" {
+			queryInput.ExclusiveStartKey = map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("user#%s", user.ID),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("endpoint#%s", cursor),
+				},
+			}
+		}
+
+		result, err := client.Query(r.Context(), queryInput)
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to query endpoints", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var endpoints []models.Endpoint
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &endpoints); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal endpoint records", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := new(listResponse[endpointResponse])
+
+		for _, endpoint := range endpoints {
+			response.Results = append(response.Results, endpointResponse{
+				ID:           strings.TrimPrefix(endpoint.SK, "endpoint#"),
+				Name:         endpoint.Name,
+				EndpointType: endpoint.EndpointType,
+				Enabled:      endpoint.Enabled,
+				Created:      endpoint.Created,
+				Modified:     endpoint.Modified,
+			})
+		}
+
+		if result.LastEvaluatedKey != nil {
+			response.NextCursor = strings.TrimPrefix(result.LastEvaluatedKey["sk"].(*types.AttributeValueMemberS).Value, "endpoint#")
+			response.HasNextPage = true
+		}
+
+		if err := encode(w, r, http.StatusOK, response); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+)
+
+// listMyMemberships returns the agencies the calling user is a member of and
+// their role in each, ordered by agency ID.
+func listMyMemberships(config Config, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		results := make([]membershipResponse, 0, len(user.Memberships))
+		for _, agencyID := range slices.Sorted(maps.Keys(user.Memberships)) {
+			results = append(results, membershipResponse{
+				AgencyID: agencyID,
+				Role:     user.Memberships[agencyID],
+			})
+		}
+
+		if err := encode(w, r, http.StatusOK, listResponse[membershipResponse]{
+			Results: results,
+		}); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
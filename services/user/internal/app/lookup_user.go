@@ -0,0 +1,87 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/user/internal/models"
+)
+
+// lookupUser finds a user by email address using their lookup#<email> row.
+// The calling user must be a platform admin.
+func lookupUser(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			email       = r.PathValue("email")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		result, err := client.GetItem(r.Context(), &dynamodb.GetItemInput{
+			TableName: aws.String(config.UserTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("lookup#%s", email),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "lookup",
+				},
+			},
+		})
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get lookup record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if result.Item == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var lookup models.Lookup
+		if err := attributevalue.UnmarshalMap(result.Item, &lookup); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal lookup record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		found, err := getUser(r.Context(), config, client, lookup.UserID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get user record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if found == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, toUserResponse(*found)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
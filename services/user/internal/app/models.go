@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/user/internal/models"
+)
+
+//-----------------------------------------------------------------------------
+// USER
+//-----------------------------------------------------------------------------
+
+// e164Pattern matches a phone number in E.164 format, e.g. +13035550123.
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// userResponse represents a single user.
+type userResponse struct {
+	ID           string                   `json:"id"`
+	Email        string                   `json:"email"`
+	Name         string                   `json:"name"`
+	Phone        string                   `json:"phone,omitempty"`
+	Timezone     string                   `json:"timezone,omitempty"`
+	Status       identity.Status          `json:"status"`
+	Entitlements []identity.Entitlement   `json:"entitlements,omitempty"`
+	Memberships  map[string]identity.Role `json:"memberships,omitempty"`
+	Created      time.Time                `json:"created"`
+	Modified     time.Time                `json:"modified"`
+	CreatedBy    string                   `json:"createdBy"`
+	ModifiedBy   string                   `json:"modifiedBy"`
+}
+
+// toUserResponse converts a user record to a response.
+func toUserResponse(user models.User) userResponse {
+	return userResponse{
+		ID:           strings.TrimPrefix(user.PK, "user#"),
+		Email:        user.Email,
+		Name:         user.Name,
+		Phone:        user.Phone,
+		Timezone:     user.Timezone,
+		Status:       userStatus(user.Status),
+		Entitlements: user.Entitlements,
+		Memberships:  user.Memberships,
+		Created:      user.Created,
+		Modified:     user.Modified,
+		CreatedBy:    user.CreatedBy,
+		ModifiedBy:   user.ModifiedBy,
+	}
+}
+
+// userStatus returns the status of a user, users created before statuses were
+// recorded are active.
+func userStatus(status identity.Status) identity.Status {
+	if status == "" {
+		return identity.StatusActive
+	}
+	return status
+}
+
+// updateMeRequest represents a request to update the calling user's profile.
+// Fields that are nil are left unchanged, an empty phone or timezone clears it.
+type updateMeRequest struct {
+	Name     *string `json:"name"`
+	Phone    *string `json:"phone"`
+	Timezone *string `json:"timezone"`
+}
+
+// valid returns a map of validation problems for the request.
+func (r updateMeRequest) valid(ctx context.Context) map[string]string {
+	problems := make(map[string]string)
+
+	if r.Name != nil && *r.Name == "" {
+		problems["name"] = "name cannot be empty"
+	}
+
+	if r.Phone != nil && *r.Phone != "" && !e164Pattern.MatchString(*r.Phone) {
+		problems["phone"] = "phone must be in E.164 format, e.g. +13035550123"
+	}
+
+	if r.Timezone != nil && *r.Timezone != "" {
+		if _, err := time.LoadLocation(*r.Timezone); err != nil {
+			problems["timezone"] = "timezone must be an IANA time zone, e.g. America/Denver"
+		}
+	}
+
+	return problems
+}
+
+//-----------------------------------------------------------------------------
+// MEMBERSHIP
+//-----------------------------------------------------------------------------
+
+// membershipResponse represents the calling user's role in an agency.
+type membershipResponse struct {
+	AgencyID string        `json:"agencyId"`
+	Role     identity.Role `json:"role"`
+}
+
+//-----------------------------------------------------------------------------
+// ENDPOINT
+//-----------------------------------------------------------------------------
+
+// endpointResponse represents an endpoint owned by the calling user.
+type endpointResponse struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	EndpointType string    `json:"endpointType"`
+	Enabled      bool      `json:"enabled"`
+	Created      time.Time `json:"created"`
+	Modified     time.Time `json:"modified"`
+}
+
+//-----------------------------------------------------------------------------
+// LIST RESPONSE
+//-----------------------------------------------------------------------------
+
+// listResponse represents a list of items with pagination.
+type listResponse[T any] struct {
+	Results     []T    `json:"results"`
+	NextCursor  string `json:"nextCursor"`
+	HasNextPage bool   `json:"hasNextPage"`
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+)
+
+// readMe returns the calling user. The response is built from the user the
+// authorizer resolved for the request, only the profile fields it doesn't
+// carry are read from the user record.
+func readMe(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := userResponse{
+			ID:           user.ID,
+			Email:        user.Email,
+			Name:         user.Name,
+			Status:       userStatus(user.Status),
+			Entitlements: user.Entitlements,
+			Memberships:  user.Memberships,
+			Created:      user.Created,
+			Modified:     user.Modified,
+			CreatedBy:    user.CreatedBy,
+			ModifiedBy:   user.ModifiedBy,
+		}
+
+		record, err := getUser(r.Context(), config, client, user.ID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to get user record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if record != nil {
+			response.Phone = record.Phone
+			response.Timezone = record.Timezone
+		}
+
+		if err := encode(w, r, http.StatusOK, response); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
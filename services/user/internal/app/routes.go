@@ -0,0 +1,22 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+)
+
+func addRoutes(mux *http.ServeMux, config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) {
+	mux.Handle(fmt.Sprintf("GET /%s/me", config.Environment), readMe(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("PATCH /%s/me", config.Environment), updateMe(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("GET /%s/me/memberships", config.Environment), listMyMemberships(config, logger))
+	mux.Handle(fmt.Sprintf("GET /%s/me/endpoints", config.Environment), listMyEndpoints(config, logger, dynamoClient))
+
+	mux.Handle(fmt.Sprintf("GET /%s/lookup/{email}", config.Environment), lookupUser(config, logger, dynamoClient))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/deactivate", config.Environment), setUserStatus(config, logger, dynamoClient, publisher, identity.StatusInactive))
+	mux.Handle(fmt.Sprintf("POST /%s/{id}/reactivate", config.Environment), setUserStatus(config, logger, dynamoClient, publisher, identity.StatusActive))
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/user/internal/models"
+)
+
+// setUserStatus deactivates or reactivates a user. An inactive user is denied
+// by the gateway, their endpoints are suspended and their memberships are
+// marked inactive until they are reactivated. Users without a status are
+// active.
+// The calling user must be a platform admin.
+func setUserStatus(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher, status identity.Status) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+			userid      = r.PathValue("id")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if !slices.Contains(user.Entitlements, identity.EntitlementPlatformAdmin) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// Admins can't lock themselves out.
+		if userid == user.ID {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		condition := "attribute_exists(#pk) AND #status = :inactive"
+		if status == identity.StatusInactive {
+			condition = "attribute_exists(#pk) AND (attribute_not_exists(#status) OR #status <> :inactive)"
+		}
+
+		now := time.Now()
+
+		result, err := dynamoClient.UpdateItem(r.Context(), &dynamodb.UpdateItemInput{
+			TableName: aws.String(config.UserTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("user#%s", userid),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+			ConditionExpression: aws.String(condition),
+			UpdateExpression:    aws.String("SET #status = :status, #modified = :modified, #modifiedBy = :modifiedBy"),
+			ExpressionAttributeNames: map[string]string{
+				"#pk":         "pk",
+				"#status":     "status",
+				"#modified":   "modified",
+				"#modifiedBy": "modifiedBy",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":status":     &types.AttributeValueMemberS{Value: status},
+				":inactive":   &types.AttributeValueMemberS{Value: identity.StatusInactive},
+				":modified":   &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+				":modifiedBy": &types.AttributeValueMemberS{Value: user.ID},
+			},
+			ReturnValues:                        types.ReturnValueAllNew,
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		})
+
+		// Either the user doesn't exist or they already have the status.
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			if conditionFailed.Item == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			var existing models.User
+			if err := attributevalue.UnmarshalMap(conditionFailed.Item, &existing); err != nil {
+				logger.ErrorContext(r.Context(), "failed to unmarshal user record", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			// The status may have been written by a request that failed to
+			// publish its event, so the event is published again. It carries
+			// the time the user was last modified, which is no earlier than the
+			// status change, so it can't replace a later one.
+			if err := publishUserStatus(r, publisher, userid, status, existing.Modified, existing.ModifiedBy); err != nil {
+				logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to update user status", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var updated models.User
+		if err := attributevalue.UnmarshalMap(result.Attributes, &updated); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := publishUserStatus(r, publisher, userid, status, now, user.ID); err != nil {
+			logger.ErrorContext(r.Context(), "failed to publish event", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, toUserResponse(updated)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// publishUserStatus publishes user.deactivated or user.reactivated for the
+// status.
+func publishUserStatus(r *http.Request, publisher *events.Publisher, userID string, status identity.Status, modified time.Time, modifiedBy string) error {
+	eventType := events.TypeUserReactivated
+	if status == identity.StatusInactive {
+		eventType = events.TypeUserDeactivated
+	}
+
+	return publisher.Publish(r.Context(), eventType, events.UserDeactivated{
+		UserID:     userID,
+		Status:     status,
+		Modified:   modified,
+		ModifiedBy: modifiedBy,
+	})
+}
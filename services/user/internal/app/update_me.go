@@ -0,0 +1,137 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/identity"
+	"github.com/jsmithdenverdev/pager/services/user/internal/models"
+)
+
+// updateMe changes the calling user's name, phone or timezone. Fields omitted
+// from the request are left as they are.
+func updateMe(config Config, logger *slog.Logger, client *dynamodb.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			user        identity.User
+			userinfostr = r.Header.Get("x-pager-userinfo")
+		)
+
+		if err := json.Unmarshal([]byte(userinfostr), &user); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user info", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		req, problems, err := decodeValid[updateMeRequest](r)
+		if err != nil {
+			if len(problems) > 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				if err := json.NewEncoder(w).Encode(problems); err != nil {
+					logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var (
+			set    = []string{"#modified = :modified", "#modifiedBy = :modifiedBy"}
+			remove []string
+			names  = map[string]string{
+				"#pk":         "pk",
+				"#modified":   "modified",
+				"#modifiedBy": "modifiedBy",
+			}
+			values = map[string]types.AttributeValue{
+				":modified":   &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339Nano)},
+				":modifiedBy": &types.AttributeValueMemberS{Value: user.ID},
+			}
+		)
+
+		if req.Name != nil {
+			set = append(set, "#name = :name")
+			names["#name"] = "name"
+			values[":name"] = &types.AttributeValueMemberS{Value: *req.Name}
+		}
+
+		// An empty phone or timezone removes it from the profile.
+		for _, field := range []struct {
+			attribute string
+			value     *string
+		}{
+			{"phone", req.Phone},
+			{"timezone", req.Timezone},
+		} {
+			if field.value == nil {
+				continue
+			}
+			names["#"+field.attribute] = field.attribute
+			if *field.value == "" {
+				remove = append(remove, "#"+field.attribute)
+				continue
+			}
+			set = append(set, fmt.Sprintf("#%s = :%s", field.attribute, field.attribute))
+			values[":"+field.attribute] = &types.AttributeValueMemberS{Value: *field.value}
+		}
+
+		updateExpression := "SET " + strings.Join(set, ", ")
+		if len(remove) > 0 {
+			updateExpression += " REMOVE " + strings.Join(remove, ", ")
+		}
+
+		result, err := client.UpdateItem(r.Context(), &dynamodb.UpdateItemInput{
+			TableName: aws.String(config.UserTableName),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{
+					Value: fmt.Sprintf("user#%s", user.ID),
+				},
+				"sk": &types.AttributeValueMemberS{
+					Value: "meta",
+				},
+			},
+			ConditionExpression:       aws.String("attribute_exists(#pk)"),
+			UpdateExpression:          aws.String(updateExpression),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			ReturnValues:              types.ReturnValueAllNew,
+		})
+
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to update user", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var updated models.User
+		if err := attributevalue.UnmarshalMap(result.Attributes, &updated); err != nil {
+			logger.ErrorContext(r.Context(), "failed to unmarshal user record", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := encode(w, r, http.StatusOK, toUserResponse(updated)); err != nil {
+			logger.ErrorContext(r.Context(), "failed to encode response", slog.Any("error", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	})
+}
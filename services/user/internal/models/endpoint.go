@@ -0,0 +1,14 @@
+package models
+
+// Endpoint is a copy of an endpoint owned by a user, stored under
+// user#<id>/endpoint#<id>. The endpoint service owns endpoints, the copy is
+// kept up to date by endpoint events so a user's endpoints can be listed with
+// the rest of their profile. A deleted endpoint's copy is left behind as an
+// EntityTypeEndpointDeleted tombstone until it expires.
+type Endpoint struct {
+	Keys
+	Auditable
+	Name         string `dynamodbav:"name"`
+	EndpointType string `dynamodbav:"endpointType"`
+	Enabled      bool   `dynamodbav:"enabled"`
+}
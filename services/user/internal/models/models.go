@@ -9,6 +9,10 @@ type EntityType string
 const (
 	EntityTypeUser       EntityType = "USER"
 	EntityTypeUserLookup EntityType = "USER_LOOKUP"
+	EntityTypeEndpoint   EntityType = "ENDPOINT"
+	// EntityTypeEndpointDeleted marks the copy of a deleted endpoint until it
+	// expires, so an endpoint event delivered after the delete is ignored.
+	EntityTypeEndpointDeleted EntityType = "ENDPOINT_DELETED"
 )
 
 type Keys struct {
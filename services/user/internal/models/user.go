@@ -7,6 +7,9 @@ type User struct {
 	Auditable
 	Name         string                   `dynamodbav:"name"`
 	Email        string                   `dynamodbav:"email"`
+	Phone        string                   `dynamodbav:"phone,omitempty"`
+	Timezone     string                   `dynamodbav:"timezone,omitempty"`
+	Status       identity.Status          `dynamodbav:"status"`
 	Entitlements []identity.Entitlement   `dynamodbav:"entitlements"`
 	Memberships  map[string]identity.Role `dynamodbav:"memberships"`
 }
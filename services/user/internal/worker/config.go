@@ -1,15 +1,21 @@
 package worker
 
-import "log/slog"
+import (
+	"log/slog"
+	"time"
+)
 
 type Config struct {
-	LogLevel                    slog.Level `env:"LOG_LEVEL"`
-	Environment                 string     `env:"ENVIRONMENT"`
-	UserTableName               string     `env:"USER_TABLE_NAME"`
-	EventsTopicARN              string     `env:"EVENTS_TOPIC_ARN"`
-	EventRetryCount             int        `env:"EVENT_RETRY_COUNT"`
-	Auth0Domain                 string     `env:"AUTH0_DOMAIN"`
-	Auth0ManagementClientID     string     `env:"AUTH0_MANAGEMENT_CLIENT_ID"`
-	Auth0ManagementClientSecret string     `env:"AUTH0_MANAGEMENT_CLIENT_SECRET"`
-	Auth0Connection             string     `env:"AUTH0_CONNECTION"`
+	LogLevel                    slog.Level    `env:"LOG_LEVEL"`
+	Environment                 string        `env:"ENVIRONMENT"`
+	UserTableName               string        `env:"USER_TABLE_NAME"`
+	EventsTopicARN              string        `env:"EVENTS_TOPIC_ARN"`
+	EventRetryCount             int           `env:"EVENT_RETRY_COUNT"`
+	EventLedgerLease            time.Duration `env:"EVENT_LEDGER_LEASE" envDefault:"30s"`
+	EventLedgerTTL              time.Duration `env:"EVENT_LEDGER_TTL" envDefault:"336h"`
+	EndpointTombstoneTTL        time.Duration `env:"ENDPOINT_TOMBSTONE_TTL" envDefault:"336h"`
+	Auth0Domain                 string        `env:"AUTH0_DOMAIN"`
+	Auth0ManagementClientID     string        `env:"AUTH0_MANAGEMENT_CLIENT_ID"`
+	Auth0ManagementClientSecret string        `env:"AUTH0_MANAGEMENT_CLIENT_SECRET"`
+	Auth0Connection             string        `env:"AUTH0_CONNECTION"`
 }
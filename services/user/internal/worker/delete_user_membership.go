@@ -2,36 +2,27 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/services/user/internal/models"
 )
 
-func deleteUserMembership(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) func(context.Context, events.SNSEntity, int) error {
-	type message struct {
-		UserID   string `json:"userId"`
-		AgencyID string `json:"agencyId"`
-	}
-
-	logAndHandleError := eventProcessorErrorHandler(config, logger, snsClient, evtMembershipDeleteFailed)
-
-	return func(ctx context.Context, record events.SNSEntity, retryCount int) error {
-		var message message
-
-		if err := json.Unmarshal([]byte(record.Message), &message); err != nil {
+func deleteUserMembership(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.AgencyMembershipDeleted](envelope)
+		if err != nil {
 			// TODO
 			// If we can't unmarshal the request, we won't have the metdata needed to
 			// create a message to publish back as a failure.
 			// E.g., this should not be publishing to the sns topic.
-			return logAndHandleError(ctx, retryCount, "failed to delete user membership", message, err)
+			return fmt.Errorf("failed to delete user membership: %w", err)
 		}
 
 		userResult, err := dynamoClient.Query(ctx, &dynamodb.QueryInput{
@@ -52,24 +43,26 @@ func deleteUserMembership(config Config, logger *slog.Logger, dynamoClient *dyna
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete user membership", message, err)
+			return fmt.Errorf("failed to delete user membership: %w", err)
 		}
 
+		// There is no user to update; retrying will not change that.
 		if len(userResult.Items) == 0 {
-			return logAndHandleError(ctx, retryCount, "failed to delete user membership", message, err)
+			logger.WarnContext(ctx, "user not found", slog.String("userId", message.UserID))
+			return nil
 		}
 
 		var user models.User
 
 		if err := attributevalue.UnmarshalMap(userResult.Items[0], &user); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete user membership", message, err)
+			return fmt.Errorf("failed to delete user membership: %w", err)
 		}
 
 		delete(user.Memberships, message.AgencyID)
 
 		memberships, err := attributevalue.MarshalMap(user.Memberships)
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete user membership", message, err)
+			return fmt.Errorf("failed to delete user membership: %w", err)
 		}
 
 		_, err = dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
@@ -94,7 +87,7 @@ func deleteUserMembership(config Config, logger *slog.Logger, dynamoClient *dyna
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to delete user membership", message, err)
+			return fmt.Errorf("failed to delete user membership: %w", err)
 		}
 
 		// TODO: This has the possibility of causing in infinite loop of events
@@ -119,7 +112,7 @@ func deleteUserMembership(config Config, logger *slog.Logger, dynamoClient *dyna
 		// })
 
 		// if err != nil {
-		// 	return logAndHandleError(ctx, retryCount, "failed to delete user membership", message, err)
+		// 	return fmt.Errorf("failed to delete user membership: %w", err)
 		// }
 
 		// logger.DebugContext(ctx, "published event", slog.String("type", evtMembershipDeleted))
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDynamoDB records the requests made to it. Each request is answered by the
+// matching function if one is set, and with an empty output otherwise.
+type fakeDynamoDB struct {
+	updateItem func(params *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error)
+
+	updates []*dynamodb.UpdateItemInput
+}
+
+func (f *fakeDynamoDB) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.updates = append(f.updates, params)
+	if f.updateItem != nil {
+		return f.updateItem(params)
+	}
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+// discardSNS accepts and drops every event published to it.
+type discardSNS struct{}
+
+func (discardSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	return &sns.PublishOutput{}, nil
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newEnvelope returns the envelope of an event with the given payload.
+func newEnvelope(t *testing.T, eventType events.Type, payload any) events.Envelope {
+	t.Helper()
+
+	envelope, err := events.NewPublisher(discardSNS{}, "topic", "test").Envelope(context.Background(), eventType, payload)
+	require.NoError(t, err)
+	return envelope
+}
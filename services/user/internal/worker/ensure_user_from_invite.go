@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -12,31 +11,22 @@ import (
 
 	"github.com/auth0/go-auth0/authentication"
 	"github.com/auth0/go-auth0/authentication/database"
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
 	"github.com/google/uuid"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/pkg/identity"
 	"github.com/jsmithdenverdev/pager/services/user/internal/models"
 )
 
-func ensureUserFromInvite(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client, auth0Client *authentication.Authentication) func(context.Context, events.SNSEntity, int) error {
-	type message struct {
-		Email    string `json:"email"`
-		AgencyID string `json:"agencyId"`
-	}
-
-	logAndHandleError := eventProcessorErrorHandler(config, logger, snsClient, evtEnsureInviteFailed)
-
-	return func(ctx context.Context, record events.SNSEntity, retryCount int) error {
-		var message message
-
-		if err := json.Unmarshal([]byte(record.Message), &message); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+func ensureUserFromInvite(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher, auth0Client *authentication.Authentication) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.UserEnsureInvite](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to ensure user from invite: %w", err)
 		}
 
 		lookupResult, err := dynamoClient.Query(ctx, &dynamodb.QueryInput{
@@ -57,7 +47,7 @@ func ensureUserFromInvite(config Config, logger *slog.Logger, dynamoClient *dyna
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+			return fmt.Errorf("failed to ensure user from invite: %w", err)
 		}
 
 		// User doesn't exist, create them in auth0
@@ -74,7 +64,7 @@ func ensureUserFromInvite(config Config, logger *slog.Logger, dynamoClient *dyna
 				Password:   tempPassword,
 			})
 			if err != nil {
-				return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+				return fmt.Errorf("failed to ensure user from invite: %w", err)
 			}
 
 			// Force a password reset for the user
@@ -83,7 +73,7 @@ func ensureUserFromInvite(config Config, logger *slog.Logger, dynamoClient *dyna
 				Email:      message.Email,
 			})
 			if err != nil {
-				return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+				return fmt.Errorf("failed to ensure user from invite: %w", err)
 			}
 
 			now := time.Now()
@@ -104,7 +94,7 @@ func ensureUserFromInvite(config Config, logger *slog.Logger, dynamoClient *dyna
 
 			lookupAV, err := attributevalue.MarshalMap(lookup)
 			if err != nil {
-				return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+				return fmt.Errorf("failed to ensure user from invite: %w", err)
 			}
 
 			user := models.User{
@@ -120,12 +110,13 @@ func ensureUserFromInvite(config Config, logger *slog.Logger, dynamoClient *dyna
 				},
 				Name:        message.Email,
 				Email:       message.Email,
+				Status:      identity.StatusActive,
 				Memberships: map[string]identity.Role{},
 			}
 
 			userAV, err := attributevalue.MarshalMap(user)
 			if err != nil {
-				return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+				return fmt.Errorf("failed to ensure user from invite: %w", err)
 			}
 
 			if _, err := dynamoClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
@@ -144,23 +135,18 @@ func ensureUserFromInvite(config Config, logger *slog.Logger, dynamoClient *dyna
 					},
 				},
 			}); err != nil {
-				return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+				return fmt.Errorf("failed to ensure user from invite: %w", err)
 			}
 
-			if _, err := snsClient.Publish(ctx, &sns.PublishInput{
-				TopicArn: aws.String(config.EventsTopicARN),
-				Message:  aws.String(fmt.Sprintf(`{"email": "%s", "agencyId": "%s", "userId": "%s"}`, message.Email, message.AgencyID, lookup.UserID)),
-				MessageAttributes: map[string]snstypes.MessageAttributeValue{
-					"type": {
-						DataType:    aws.String("String"),
-						StringValue: aws.String(evtInviteTargetEnsured),
-					},
-				},
+			if err := publisher.Publish(ctx, events.TypeUserInviteTargetEnsured, events.UserInviteTargetEnsured{
+				Email:    message.Email,
+				AgencyID: message.AgencyID,
+				UserID:   lookup.UserID,
 			}); err != nil {
-				return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+				return fmt.Errorf("failed to ensure user from invite: %w", err)
 			}
 
-			logger.DebugContext(ctx, "published event", slog.String("type", evtInviteTargetEnsured))
+			logger.DebugContext(ctx, "published event", slog.String("type", string(events.TypeUserInviteTargetEnsured)))
 
 			return nil
 		}
@@ -168,7 +154,7 @@ func ensureUserFromInvite(config Config, logger *slog.Logger, dynamoClient *dyna
 		var lookup models.Lookup
 
 		if err := attributevalue.UnmarshalMap(lookupResult.Items[0], &lookup); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+			return fmt.Errorf("failed to ensure user from invite: %w", err)
 		}
 
 		userResult, err := dynamoClient.Query(ctx, &dynamodb.QueryInput{
@@ -189,33 +175,28 @@ func ensureUserFromInvite(config Config, logger *slog.Logger, dynamoClient *dyna
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+			return fmt.Errorf("failed to ensure user from invite: %w", err)
 		}
 
 		if len(userResult.Items) == 0 {
-			return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, errors.New("user doesn't exist (auth0 invite not implemented)"))
+			return errors.New("user doesn't exist (auth0 invite not implemented)")
 		}
 
 		var user models.User
 
 		if err := attributevalue.UnmarshalMap(userResult.Items[0], &user); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+			return fmt.Errorf("failed to ensure user from invite: %w", err)
 		}
 
-		if _, err := snsClient.Publish(ctx, &sns.PublishInput{
-			TopicArn: aws.String(config.EventsTopicARN),
-			Message:  aws.String(fmt.Sprintf(`{"email": "%s", "agencyId": "%s", "userId": "%s"}`, message.Email, message.AgencyID, lookup.UserID)),
-			MessageAttributes: map[string]snstypes.MessageAttributeValue{
-				"type": {
-					DataType:    aws.String("String"),
-					StringValue: aws.String(evtInviteTargetEnsured),
-				},
-			},
+		if err := publisher.Publish(ctx, events.TypeUserInviteTargetEnsured, events.UserInviteTargetEnsured{
+			Email:    message.Email,
+			AgencyID: message.AgencyID,
+			UserID:   lookup.UserID,
 		}); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to ensure user from invite", message, err)
+			return fmt.Errorf("failed to ensure user from invite: %w", err)
 		}
 
-		logger.DebugContext(ctx, "published event", slog.String("type", evtInviteTargetEnsured))
+		logger.DebugContext(ctx, "published event", slog.String("type", string(events.TypeUserInviteTargetEnsured)))
 
 		return nil
 	}
@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/user/internal/models"
+)
+
+// upsertUserEndpoint copies a created or updated endpoint onto its owner.
+// Endpoints created before the copy existed are added the first time they're
+// updated. Events for an endpoint that has been deleted are ignored, so a late
+// endpoint.updated can't bring its copy back.
+func upsertUserEndpoint(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.EndpointCreated](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to upsert user endpoint: %w", err)
+		}
+
+		now := time.Now().Format(time.RFC3339Nano)
+
+		if _, err := dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(config.UserTableName),
+			Key: map[string]dynamodbtypes.AttributeValue{
+				"pk": &dynamodbtypes.AttributeValueMemberS{
+					Value: fmt.Sprintf("user#%s", message.UserID),
+				},
+				"sk": &dynamodbtypes.AttributeValueMemberS{
+					Value: fmt.Sprintf("endpoint#%s", message.EndpointID),
+				},
+			},
+			UpdateExpression: aws.String("SET #type = :type, #name = :name, #endpointType = :endpointType, #enabled = :enabled, " +
+				"#created = if_not_exists(#created, :now), #createdBy = if_not_exists(#createdBy, :system), " +
+				"#modified = :now, #modifiedBy = :system"),
+			ConditionExpression: aws.String("attribute_not_exists(#type) OR #type <> :deleted"),
+			ExpressionAttributeNames: map[string]string{
+				"#type":         "type",
+				"#name":         "name",
+				"#endpointType": "endpointType",
+				"#enabled":      "enabled",
+				"#created":      "created",
+				"#createdBy":    "createdBy",
+				"#modified":     "modified",
+				"#modifiedBy":   "modifiedBy",
+			},
+			ExpressionAttributeValues: map[string]dynamodbtypes.AttributeValue{
+				":type":         &dynamodbtypes.AttributeValueMemberS{Value: string(models.EntityTypeEndpoint)},
+				":name":         &dynamodbtypes.AttributeValueMemberS{Value: message.Name},
+				":endpointType": &dynamodbtypes.AttributeValueMemberS{Value: message.EndpointType},
+				":enabled":      &dynamodbtypes.AttributeValueMemberBOOL{Value: message.Enabled},
+				":now":          &dynamodbtypes.AttributeValueMemberS{Value: now},
+				":system":       &dynamodbtypes.AttributeValueMemberS{Value: "system"},
+				":deleted":      &dynamodbtypes.AttributeValueMemberS{Value: string(models.EntityTypeEndpointDeleted)},
+			},
+		}); err != nil {
+			var conditionErr *dynamodbtypes.ConditionalCheckFailedException
+			if errors.As(err, &conditionErr) {
+				logger.InfoContext(ctx, "endpoint deleted, not upserting", slog.String("endpointId", message.EndpointID))
+				return nil
+			}
+			return fmt.Errorf("failed to upsert user endpoint: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// deleteUserEndpoint replaces the copy of a deleted endpoint with a tombstone
+// that expires after config.EndpointTombstoneTTL. The tombstone outlives any
+// create or update of the endpoint still being delivered, see
+// upsertUserEndpoint.
+func deleteUserEndpoint(config Config, logger *slog.Logger, dynamoClient dynamoDBAPI) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.EndpointDeleted](envelope)
+		if err != nil {
+			return fmt.Errorf("failed to delete user endpoint: %w", err)
+		}
+
+		now := time.Now()
+
+		if _, err := dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(config.UserTableName),
+			Key: map[string]dynamodbtypes.AttributeValue{
+				"pk": &dynamodbtypes.AttributeValueMemberS{
+					Value: fmt.Sprintf("user#%s", message.UserID),
+				},
+				"sk": &dynamodbtypes.AttributeValueMemberS{
+					Value: fmt.Sprintf("endpoint#%s", message.EndpointID),
+				},
+			},
+			UpdateExpression: aws.String("SET #type = :deleted, #modified = :now, #modifiedBy = :system, #ttl = :ttl " +
+				"REMOVE #name, #endpointType, #enabled"),
+			ExpressionAttributeNames: map[string]string{
+				"#type":         "type",
+				"#modified":     "modified",
+				"#modifiedBy":   "modifiedBy",
+				"#ttl":          "ttl",
+				"#name":         "name",
+				"#endpointType": "endpointType",
+				"#enabled":      "enabled",
+			},
+			ExpressionAttributeValues: map[string]dynamodbtypes.AttributeValue{
+				":deleted": &dynamodbtypes.AttributeValueMemberS{Value: string(models.EntityTypeEndpointDeleted)},
+				":now":     &dynamodbtypes.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+				":system":  &dynamodbtypes.AttributeValueMemberS{Value: "system"},
+				":ttl":     &dynamodbtypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(config.EndpointTombstoneTTL).Unix(), 10)},
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to delete user endpoint: %w", err)
+		}
+
+		return nil
+	}
+}
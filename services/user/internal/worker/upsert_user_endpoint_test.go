@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jsmithdenverdev/pager/pkg/events"
+	"github.com/jsmithdenverdev/pager/services/user/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var endpointConfig = Config{UserTableName: "users", EndpointTombstoneTTL: time.Hour}
+
+func TestUpsertUserEndpoint(t *testing.T) {
+	client := new(fakeDynamoDB)
+
+	require.NoError(t, upsertUserEndpoint(endpointConfig, discardLogger, client)(
+		context.Background(),
+		newEnvelope(t, events.TypeEndpointUpdated, events.EndpointUpdated{
+			EndpointID:   "endpoint-1",
+			UserID:       "user-1",
+			Name:         "Phone",
+			EndpointType: "SMS",
+			Enabled:      true,
+		})))
+
+	require.Len(t, client.updates, 1)
+	update := client.updates[0]
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "user#user-1"}, update.Key["pk"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "endpoint#endpoint-1"}, update.Key["sk"])
+	assert.Equal(t, "attribute_not_exists(#type) OR #type <> :deleted", aws.ToString(update.ConditionExpression))
+	assert.Equal(t, &types.AttributeValueMemberS{Value: string(models.EntityTypeEndpoint)}, update.ExpressionAttributeValues[":type"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "Phone"}, update.ExpressionAttributeValues[":name"])
+	assert.Equal(t, &types.AttributeValueMemberBOOL{Value: true}, update.ExpressionAttributeValues[":enabled"])
+}
+
+func TestDeleteUserEndpoint(t *testing.T) {
+	client := new(fakeDynamoDB)
+
+	require.NoError(t, deleteUserEndpoint(endpointConfig, discardLogger, client)(
+		context.Background(),
+		newEnvelope(t, events.TypeEndpointDeleted, events.EndpointDeleted{
+			EndpointID: "endpoint-1",
+			UserID:     "user-1",
+		})))
+
+	// The copy is replaced with a tombstone that expires.
+	require.Len(t, client.updates, 1)
+	update := client.updates[0]
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "endpoint#endpoint-1"}, update.Key["sk"])
+	assert.Contains(t, aws.ToString(update.UpdateExpression), "REMOVE #name, #endpointType, #enabled")
+	assert.Equal(t, &types.AttributeValueMemberS{Value: string(models.EntityTypeEndpointDeleted)}, update.ExpressionAttributeValues[":deleted"])
+
+	ttl, err := strconv.ParseInt(update.ExpressionAttributeValues[":ttl"].(*types.AttributeValueMemberN).Value, 10, 64)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), time.Unix(ttl, 0), time.Minute)
+}
+
+func TestUpsertUserEndpointAfterDelete(t *testing.T) {
+	// rows holds the type of each copy, an update with a condition fails when
+	// the copy is a tombstone.
+	rows := make(map[string]string)
+	client := &fakeDynamoDB{
+		updateItem: func(params *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+			sk := params.Key["sk"].(*types.AttributeValueMemberS).Value
+			if params.ConditionExpression != nil && rows[sk] == string(models.EntityTypeEndpointDeleted) {
+				return nil, &types.ConditionalCheckFailedException{}
+			}
+			if deleted, ok := params.ExpressionAttributeValues[":deleted"]; ok && params.ConditionExpression == nil {
+				rows[sk] = deleted.(*types.AttributeValueMemberS).Value
+				return &dynamodb.UpdateItemOutput{}, nil
+			}
+			rows[sk] = params.ExpressionAttributeValues[":type"].(*types.AttributeValueMemberS).Value
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	// endpoint.deleted is delivered before the endpoint.updated published
+	// ahead of it.
+	require.NoError(t, deleteUserEndpoint(endpointConfig, discardLogger, client)(
+		context.Background(),
+		newEnvelope(t, events.TypeEndpointDeleted, events.EndpointDeleted{
+			EndpointID: "endpoint-1",
+			UserID:     "user-1",
+		})))
+	require.NoError(t, upsertUserEndpoint(endpointConfig, discardLogger, client)(
+		context.Background(),
+		newEnvelope(t, events.TypeEndpointUpdated, events.EndpointUpdated{
+			EndpointID: "endpoint-1",
+			UserID:     "user-1",
+			Name:       "Phone",
+		})))
+
+	assert.Len(t, client.updates, 2)
+	assert.Equal(t, string(models.EntityTypeEndpointDeleted), rows["endpoint#endpoint-1"])
+}
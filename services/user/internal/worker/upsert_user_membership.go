@@ -2,39 +2,28 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
-	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/jsmithdenverdev/pager/pkg/consumer"
+	"github.com/jsmithdenverdev/pager/pkg/events"
 	"github.com/jsmithdenverdev/pager/pkg/identity"
 	"github.com/jsmithdenverdev/pager/services/user/internal/models"
 )
 
-func upsertUserMembership(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, snsClient *sns.Client) func(context.Context, events.SNSEntity, int) error {
-	type message struct {
-		UserID   string        `json:"userId"`
-		AgencyID string        `json:"agencyId"`
-		Role     identity.Role `json:"role"`
-	}
-
-	logAndHandleError := eventProcessorErrorHandler(config, logger, snsClient, evtMembershipUpsertFailed)
-
-	return func(ctx context.Context, record events.SNSEntity, retryCount int) error {
-		var message message
-
-		if err := json.Unmarshal([]byte(record.Message), &message); err != nil {
+func upsertUserMembership(config Config, logger *slog.Logger, dynamoClient *dynamodb.Client, publisher *events.Publisher) consumer.Handler {
+	return func(ctx context.Context, envelope events.Envelope) error {
+		message, err := events.DecodePayload[events.AgencyMembershipCreated](envelope)
+		if err != nil {
 			// TODO
 			// If we can't unmarshal the request, we won't have the metdata needed to
 			// create a message to publish back as a failure.
 			// E.g., this should not be publishing to the sns topic.
-			return logAndHandleError(ctx, retryCount, "failed to upsert user membership", message, err)
+			return fmt.Errorf("failed to upsert user membership: %w", err)
 		}
 
 		userResult, err := dynamoClient.Query(ctx, &dynamodb.QueryInput{
@@ -55,17 +44,19 @@ func upsertUserMembership(config Config, logger *slog.Logger, dynamoClient *dyna
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert user membership", message, err)
+			return fmt.Errorf("failed to upsert user membership: %w", err)
 		}
 
+		// There is no user to update; retrying will not change that.
 		if len(userResult.Items) == 0 {
-			return logAndHandleError(ctx, retryCount, "failed to upsert user membership", message, err)
+			logger.WarnContext(ctx, "user not found", slog.String("userId", message.UserID))
+			return nil
 		}
 
 		var user models.User
 
 		if err := attributevalue.UnmarshalMap(userResult.Items[0], &user); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert user membership", message, err)
+			return fmt.Errorf("failed to upsert user membership: %w", err)
 		}
 
 		if user.Memberships == nil {
@@ -75,7 +66,7 @@ func upsertUserMembership(config Config, logger *slog.Logger, dynamoClient *dyna
 
 		memberships, err := attributevalue.MarshalMap(user.Memberships)
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert user membership", message, err)
+			return fmt.Errorf("failed to upsert user membership: %w", err)
 		}
 
 		_, err = dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
@@ -100,23 +91,17 @@ func upsertUserMembership(config Config, logger *slog.Logger, dynamoClient *dyna
 		})
 
 		if err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert user membership", message, err)
+			return fmt.Errorf("failed to upsert user membership: %w", err)
 		}
 
-		if _, err := snsClient.Publish(ctx, &sns.PublishInput{
-			TopicArn: aws.String(config.EventsTopicARN),
-			Message:  aws.String(fmt.Sprintf(`{"userId": "%s", "agencyId": "%s"}`, message.UserID, message.AgencyID)),
-			MessageAttributes: map[string]snstypes.MessageAttributeValue{
-				"type": {
-					DataType:    aws.String("String"),
-					StringValue: aws.String(evtMembershipUpserted),
-				},
-			},
+		if err := publisher.Publish(ctx, events.TypeUserMembershipUpserted, events.UserMembershipUpserted{
+			UserID:   message.UserID,
+			AgencyID: message.AgencyID,
 		}); err != nil {
-			return logAndHandleError(ctx, retryCount, "failed to upsert user membership", message, err)
+			return fmt.Errorf("failed to upsert user membership: %w", err)
 		}
 
-		logger.DebugContext(ctx, "published event", slog.String("type", evtMembershipUpserted))
+		logger.DebugContext(ctx, "published event", slog.String("type", string(events.TypeUserMembershipUpserted)))
 
 		return nil
 	}